@@ -1,19 +1,30 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"nine-dubz/internal/analytics"
+	"nine-dubz/internal/category"
+	"nine-dubz/internal/chapter"
 	"nine-dubz/internal/comment"
 	"nine-dubz/internal/file"
 	"nine-dubz/internal/googleoauth"
+	"nine-dubz/internal/history"
 	"nine-dubz/internal/mail"
 	"nine-dubz/internal/movie"
+	"nine-dubz/internal/notification"
+	"nine-dubz/internal/playlist"
 	"nine-dubz/internal/public"
+	"nine-dubz/internal/reaction"
 	"nine-dubz/internal/response"
 	"nine-dubz/internal/role"
+	"nine-dubz/internal/search"
 	"nine-dubz/internal/seo"
 	"nine-dubz/internal/subscription"
+	"nine-dubz/internal/tag"
 	"nine-dubz/internal/token"
 	"nine-dubz/internal/user"
 	"nine-dubz/internal/video"
@@ -21,7 +32,11 @@ import (
 	"nine-dubz/pkg/etag"
 	"nine-dubz/pkg/language"
 	"nine-dubz/pkg/tokenauthorize"
+	"nine-dubz/pkg/userip"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/alitto/pond"
@@ -48,36 +63,58 @@ func (app *App) Start() {
 	pool := pond.New(4, 300)
 	defer pool.StopAndWait()
 
+	// JWT Token, also signs links in emails
+	ta := newTokenAuthorize()
+
 	// Use cases
-	muc := mail.New()
+	muc := mail.New(app.DB)
 	fuc := file.New(app.DB)
 	tuc := token.New(app.DB)
 	ruc := role.New(app.DB)
 	vuc := view.New(app.DB)
 	viduc := video.New(app.DB, fuc)
 	uuc := user.New(app.DB, tuc, ruc, fuc, muc)
-	subuc := subscription.New(app.DB)
-	movuc := movie.New(app.DB, pool, viduc, fuc, vuc, subuc)
+	nuc := notification.New(app.DB, muc, ta)
+	subuc := subscription.New(app.DB, nuc)
+	chuc := chapter.New(app.DB)
+	catuc := category.New(app.DB)
+	taguc := tag.New(app.DB)
+	reacuc := reaction.New(app.DB)
+	huc := history.New(app.DB)
+	auc := analytics.New(app.DB)
+	movuc := movie.New(app.DB, pool, viduc, fuc, vuc, subuc, chuc, catuc, taguc, reacuc, huc, auc, nuc, uuc)
 	goauc := googleoauth.New(app.DB, uuc, fuc)
-	cuc := comment.New(app.DB, movuc, uuc)
+	cuc := comment.New(app.DB, movuc, uuc, nuc)
+	pluc := playlist.New(app.DB, movuc)
+	movuc.PlaylistUseCase = pluc
 	seouc := seo.New(movuc)
+	searchuc := search.New(app.DB, movuc, uuc, cuc, vuc)
 
-	// JWT Token
-	tokenSecretKey, ok := os.LookupEnv("TOKEN_SECRET_KEY")
-	if !ok {
-		log.Println("TOKEN_SECRET_KEY environment variable not set")
+	// Client IPs are taken from forwarding headers only behind these proxies
+	trustedProxies := userip.DefaultTrustedProxies
+	if trustedProxiesEnv, ok := os.LookupEnv("TRUSTED_PROXIES"); ok {
+		trustedProxies = strings.Split(trustedProxiesEnv, ",")
+	}
+	uip, err := userip.New(trustedProxies)
+	if err != nil {
+		log.Fatalln(err)
 	}
-	ta := tokenauthorize.New(tokenSecretKey, "nine-dubz")
 
 	// Http handlers
 	ph := public.NewHandler(seouc)
 	uh := user.NewHandler(uuc, tuc, ta)
-	fh := file.NewHandler(fuc)
-	mh := movie.NewHandler(movuc, uh, fuc, ta, tuc)
+	fh := file.NewHandler(fuc, movuc)
 	goah := googleoauth.NewHandler(goauc, uh, tuc, ta)
 	ch := comment.NewHandler(cuc, uh)
 	seoh := seo.NewHandler(seouc)
 	subh := subscription.NewHandler(subuc, uh)
+	cath := category.NewHandler(catuc, uh)
+	plh := playlist.NewHandler(pluc, uh)
+	hh := history.NewHandler(huc, uh)
+	mh := movie.NewHandler(movuc, uh, fuc, ta, tuc, uip, hh)
+	ah := analytics.NewHandler(auc, uh)
+	nh := notification.NewHandler(nuc, uh, fuc)
+	searchh := search.NewHandler(searchuc)
 
 	//app.Router.Use(middleware.Logger)
 	app.Router.Use(
@@ -119,7 +156,8 @@ func (app *App) Start() {
 			response.RenderError(w, r, http.StatusNotFound, "not found")
 		})
 
-		fh.Routes(r)
+		r.With(uh.TryToGetUserId).Group(fh.Routes)
+		mh.HeartbeatRoutes(r)
 
 		r.
 			With(httprate.Limit(
@@ -135,6 +173,11 @@ func (app *App) Start() {
 			ch.Routes(r)
 			seoh.Routes(r)
 			subh.Routes(r)
+			cath.Routes(r)
+			plh.Routes(r)
+			ah.Routes(r)
+			nh.Routes(r)
+			searchh.Routes(r)
 		})
 	})
 
@@ -151,9 +194,89 @@ func (app *App) Start() {
 
 	// If server were crashed, try to re-post-process them
 	go movuc.RetryVideoPostProcess()
+	go movuc.RunPublishScheduler(time.Minute)
+	go movuc.RunTrendingUpdater(10 * time.Minute)
+	go auc.RunRollup(5 * time.Minute)
+	go searchuc.RunReindex(5 * time.Minute)
+	go nuc.RunEmails(time.Minute)
+	go muc.RunOutbox(time.Minute)
+	go vuc.RunPendingCleanup(time.Hour)
 
-	err := http.ListenAndServe(appIp+":"+appPort, app.Router)
-	if err != nil {
+	server := &http.Server{
+		Addr:    appIp + ":" + appPort,
+		Handler: app.Router,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Println("server: shutdown:", err)
+		}
+	}()
+
+	err = server.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		log.Println("server:", err)
 		return
 	}
+	<-shutdown
+
+	// Watch time and retention are counted in memory between rollups
+	if err = auc.Flush(); err != nil {
+		log.Println("analytics: flush:", err)
+	}
+}
+
+func newTokenAuthorize() *tokenauthorize.TokenAuthorize {
+	tokenSecretKey, ok := os.LookupEnv("TOKEN_SECRET_KEY")
+	if !ok {
+		log.Println("TOKEN_SECRET_KEY environment variable not set")
+	}
+
+	return tokenauthorize.New(tokenSecretKey, "nine-dubz")
+}
+
+// newBackfillMovieUseCase builds the movie use case for one-time jobs run
+// instead of the server
+func (app *App) newBackfillMovieUseCase(pool *pond.WorkerPool) *movie.UseCase {
+	ta := newTokenAuthorize()
+	muc := mail.New(app.DB)
+	fuc := file.New(app.DB)
+	tuc := token.New(app.DB)
+	ruc := role.New(app.DB)
+	vuc := view.New(app.DB)
+	viduc := video.New(app.DB, fuc)
+	uuc := user.New(app.DB, tuc, ruc, fuc, muc)
+	nuc := notification.New(app.DB, muc, ta)
+	subuc := subscription.New(app.DB, nuc)
+	chuc := chapter.New(app.DB)
+	catuc := category.New(app.DB)
+	taguc := tag.New(app.DB)
+	reacuc := reaction.New(app.DB)
+	huc := history.New(app.DB)
+	auc := analytics.New(app.DB)
+
+	return movie.New(app.DB, pool, viduc, fuc, vuc, subuc, chuc, catuc, taguc, reacuc, huc, auc, nuc, uuc)
+}
+
+func (app *App) BackfillAnimatedPreviews() {
+	pool := pond.New(1, 0)
+	defer pool.StopAndWait()
+
+	app.newBackfillMovieUseCase(pool).BackfillAnimatedPreviews()
+}
+
+func (app *App) BackfillDurations() {
+	pool := pond.New(1, 0)
+	defer pool.StopAndWait()
+
+	app.newBackfillMovieUseCase(pool).BackfillDurations()
 }
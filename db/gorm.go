@@ -5,13 +5,22 @@ import (
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"nine-dubz/internal/analytics"
 	"nine-dubz/internal/apimethod"
+	"nine-dubz/internal/category"
+	"nine-dubz/internal/chapter"
 	"nine-dubz/internal/comment"
 	"nine-dubz/internal/file"
 	"nine-dubz/internal/googleoauth"
+	"nine-dubz/internal/history"
+	"nine-dubz/internal/mail"
 	"nine-dubz/internal/movie"
+	"nine-dubz/internal/notification"
+	"nine-dubz/internal/playlist"
+	"nine-dubz/internal/reaction"
 	"nine-dubz/internal/role"
 	"nine-dubz/internal/subscription"
+	"nine-dubz/internal/tag"
 	"nine-dubz/internal/token"
 	"nine-dubz/internal/user"
 	"nine-dubz/internal/video"
@@ -48,6 +57,21 @@ func NewGormDb() *gorm.DB {
 		panic("Failed to connect database")
 	}
 
+	var count int64
+
+	// Movies reference categories, so they must exist before migrating movies
+	db.AutoMigrate(&category.Category{})
+	db.Model(&category.Category{}).Count(&count)
+	if count == 0 {
+		db.Create(&category.DefaultCategories)
+	}
+
+	// Counters were added to existing movies, fill them once after migration
+	hasMovieCounters := db.Migrator().HasColumn(&movie.Movie{}, "ViewsCount")
+	// Movies published before the publish time was kept would notify the
+	// subscribers again when republished, it's set once from the creation
+	hasPublishedAt := db.Migrator().HasColumn(&movie.Movie{}, "PublishedAt")
+
 	db.AutoMigrate(
 		&file.File{},
 		&role.Role{},
@@ -58,11 +82,36 @@ func NewGormDb() *gorm.DB {
 		&video.Video{},
 		&comment.Comment{},
 		&view.View{},
+		&view.Pending{},
+		&reaction.Reaction{},
+		&tag.Tag{},
 		&movie.Movie{},
 		&subscription.Subscription{},
+		&chapter.Chapter{},
+		&playlist.Playlist{},
+		&playlist.Item{},
+		&history.Entry{},
+		&history.Settings{},
+		&analytics.MovieDaily{},
+		&analytics.ChannelDaily{},
+		&analytics.Retention{},
+		&analytics.Source{},
+		&notification.Notification{},
+		&notification.Preference{},
+		&notification.EmailSettings{},
+		&mail.Message{},
 	)
 
-	var count int64
+	if !hasMovieCounters {
+		db.Exec(`UPDATE movies SET
+			views_count = (SELECT COUNT(*) FROM views WHERE views.movie_id = movies.id AND views.deleted_at IS NULL),
+			comments_count = (SELECT COUNT(*) FROM comments WHERE comments.movie_id = movies.id AND comments.deleted_at IS NULL)`)
+	}
+
+	if !hasPublishedAt {
+		db.Exec("UPDATE movies SET published_at = created_at WHERE is_published = ? AND published_at IS NULL", true)
+	}
+
 	db.Model(&role.Role{}).Where("code = ?", "all").Count(&count)
 	if count == 0 {
 		db.Create(&role.Role{Code: "all", Name: "all"})
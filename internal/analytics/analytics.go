@@ -0,0 +1,357 @@
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"nine-dubz/internal/view"
+	"sync"
+	"time"
+)
+
+const (
+	// Retention is counted in buckets of this many seconds, the same step
+	// as the timeline thumbnails
+	RetentionBucket = 10
+
+	// Positions further apart than this between two heartbeats are a seek,
+	// the movie in between is not watched
+	maxHeartbeatGap = 60
+	// A viewer silent for this long starts a new session
+	sessionTTL  = 30 * time.Minute
+	maxSessions = 100000
+
+	defaultDays = 28
+	maxDays     = 366
+)
+
+type UseCase struct {
+	AnalyticsInteractor Interactor
+	Sessions            map[string]*session
+	WatchTime           map[dailyKey]int64
+	Retention           map[retentionKey]int64
+	Mutex               *sync.Mutex
+}
+
+func New(db *gorm.DB) *UseCase {
+	return &UseCase{
+		AnalyticsInteractor: &Repository{
+			DB: db,
+		},
+		Sessions:  make(map[string]*session),
+		WatchTime: make(map[dailyKey]int64),
+		Retention: make(map[retentionKey]int64),
+		Mutex:     &sync.Mutex{},
+	}
+}
+
+func today() time.Time {
+	now := time.Now()
+
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// Heartbeat adds the watch time since the viewer's previous heartbeat and
+// marks the retention buckets watched in between. Counts are kept in
+// memory until the next Flush.
+func (uc *UseCase) Heartbeat(movieId, channelId uint, userId *uint, client view.Client, position, watched, duration int) {
+	if view.IsBot(client.UserAgent) || watched < 0 {
+		return
+	}
+	if duration > 0 {
+		position = min(position, duration)
+	}
+	position = max(position, 0)
+
+	now := time.Now()
+	date := today()
+	key := fmt.Sprintf("%d:%s", movieId, view.ClientKey(userId, client))
+
+	uc.Mutex.Lock()
+	defer uc.Mutex.Unlock()
+
+	var watchTime int
+	current, ok := uc.Sessions[key]
+	if !ok || watched < current.watched || now.Sub(current.lastSeen) > sessionTTL {
+		if !ok && len(uc.Sessions) >= maxSessions {
+			return
+		}
+
+		// A session that starts near the beginning watched it all
+		current = &session{position: -1, buckets: make(map[int]bool)}
+		if position <= maxHeartbeatGap {
+			current.position = 0
+		}
+		uc.Sessions[key] = current
+		watchTime = min(watched, maxHeartbeatGap)
+	} else {
+		watchTime = min(watched-current.watched, int(now.Sub(current.lastSeen).Seconds())+RetentionBucket)
+	}
+
+	if watchTime > 0 {
+		uc.WatchTime[dailyKey{date: date, movieId: movieId, channelId: channelId}] += int64(watchTime)
+	}
+
+	fromBucket := position / RetentionBucket
+	if current.position >= 0 && position >= current.position && position-current.position <= maxHeartbeatGap {
+		fromBucket = current.position / RetentionBucket
+	}
+	for bucket := fromBucket; bucket <= position/RetentionBucket; bucket++ {
+		if !current.buckets[bucket] {
+			current.buckets[bucket] = true
+			uc.Retention[retentionKey{date: date, movieId: movieId, bucket: bucket}]++
+		}
+	}
+
+	current.position = position
+	current.watched = watched
+	current.lastSeen = now
+}
+
+func (uc *UseCase) RunRollup(interval time.Duration) {
+	if err := uc.Backfill(); err != nil {
+		log.Println("analytics: backfill:", err)
+	}
+	uc.Rollup()
+
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		uc.Rollup()
+	}
+}
+
+// Rollup writes the heartbeat counts and recounts views of today and
+// yesterday, views of yesterday can still come in around midnight.
+func (uc *UseCase) Rollup() {
+	if err := uc.Flush(); err != nil {
+		log.Println("analytics: flush:", err)
+	}
+
+	if err := uc.AnalyticsInteractor.RollupViews(today().AddDate(0, 0, -1)); err != nil {
+		log.Println("analytics: rollup views:", err)
+	}
+}
+
+// Backfill rolls up all views recorded before the daily tables were, it's
+// done once when there are no rollups older than the ones Rollup makes.
+func (uc *UseCase) Backfill() error {
+	hasRollup, err := uc.AnalyticsInteractor.HasViewsRollupBefore(today().AddDate(0, 0, -1))
+	if err != nil || hasRollup {
+		return err
+	}
+
+	return uc.AnalyticsInteractor.RollupViews(time.Unix(0, 0))
+}
+
+// Flush writes the watch time and retention counted since the last flush
+// and forgets sessions that went silent.
+func (uc *UseCase) Flush() error {
+	uc.Mutex.Lock()
+	watchTime, retention := uc.WatchTime, uc.Retention
+	uc.WatchTime, uc.Retention = make(map[dailyKey]int64), make(map[retentionKey]int64)
+	for key, current := range uc.Sessions {
+		if time.Since(current.lastSeen) > sessionTTL {
+			delete(uc.Sessions, key)
+		}
+	}
+	uc.Mutex.Unlock()
+
+	if len(watchTime) > 0 {
+		var rows []MovieDaily
+		for key, seconds := range watchTime {
+			rows = append(rows, MovieDaily{Date: key.date, MovieId: key.movieId, ChannelId: key.channelId, WatchTime: seconds})
+		}
+		if err := uc.AnalyticsInteractor.AddWatchTime(rows); err != nil {
+			uc.restore(watchTime, retention)
+			return err
+		}
+	}
+
+	if len(retention) > 0 {
+		var rows []Retention
+		for key, viewers := range retention {
+			rows = append(rows, Retention{Date: key.date, MovieId: key.movieId, Bucket: key.bucket, Viewers: viewers})
+		}
+		if err := uc.AnalyticsInteractor.AddRetention(rows); err != nil {
+			uc.restore(nil, retention)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restore puts back the counts a failed flush didn't write, the next flush
+// writes them along with the new ones
+func (uc *UseCase) restore(watchTime map[dailyKey]int64, retention map[retentionKey]int64) {
+	uc.Mutex.Lock()
+	defer uc.Mutex.Unlock()
+
+	for key, seconds := range watchTime {
+		uc.WatchTime[key] += seconds
+	}
+	for key, viewers := range retention {
+		uc.Retention[key] += viewers
+	}
+}
+
+// NewRange checks the requested dates, by default it's the last 28 days
+func NewRange(from, to string) (time.Time, time.Time, error) {
+	toDate := today()
+	if to != "" {
+		var err error
+		toDate, err = time.ParseInLocation(time.DateOnly, to, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid date")
+		}
+	}
+
+	fromDate := toDate.AddDate(0, 0, 1-defaultDays)
+	if from != "" {
+		var err error
+		fromDate, err = time.ParseInLocation(time.DateOnly, from, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid date")
+		}
+	}
+
+	if fromDate.After(toDate) || fromDate.AddDate(0, 0, maxDays).Before(toDate) {
+		return time.Time{}, time.Time{}, errors.New("invalid date range")
+	}
+
+	return fromDate, toDate, nil
+}
+
+// GetMovie returns statistics of the movie to its owner
+func (uc *UseCase) GetMovie(userId uint, code string, from, to time.Time) (*GetResponse, error) {
+	movieId, channelId, err := uc.AnalyticsInteractor.GetMovieOwner(code)
+	if err != nil {
+		return nil, err
+	}
+	if channelId != userId {
+		return nil, errors.New("not allowed")
+	}
+
+	fromDate, toDate := from.Format(time.DateOnly), to.Format(time.DateOnly)
+	rows, err := uc.AnalyticsInteractor.GetMovieSeries(movieId, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make(map[string]*Point)
+	for _, row := range rows {
+		points[row.Date.Format(time.DateOnly)] = &Point{
+			Views:         row.Views,
+			UniqueViewers: row.UniqueViewers,
+			WatchTime:     row.WatchTime,
+		}
+	}
+
+	response, err := uc.newResponse(points, "movie_id", movieId, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	retention, err := uc.AnalyticsInteractor.GetRetention(movieId, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+	if len(retention) > 0 {
+		var started int64
+		for _, row := range retention {
+			started = max(started, row.Viewers)
+		}
+		for _, row := range retention {
+			response.Retention = append(response.Retention, &RetentionPoint{
+				Start:   row.Bucket * RetentionBucket,
+				Viewers: row.Viewers,
+				Share:   float64(row.Viewers) / float64(started),
+			})
+		}
+	}
+
+	return response, nil
+}
+
+// GetChannel returns statistics of all movies of the channel
+func (uc *UseCase) GetChannel(channelId uint, from, to time.Time) (*GetResponse, error) {
+	fromDate, toDate := from.Format(time.DateOnly), to.Format(time.DateOnly)
+	rows, err := uc.AnalyticsInteractor.GetChannelSeries(channelId, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make(map[string]*Point)
+	for _, row := range rows {
+		points[row.Date.Format(time.DateOnly)] = &Point{
+			Views:         row.Views,
+			UniqueViewers: row.UniqueViewers,
+		}
+	}
+
+	watchTime, err := uc.AnalyticsInteractor.GetChannelWatchTime(channelId, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range watchTime {
+		date := row.Date.Format(time.DateOnly)
+		if _, ok := points[date]; !ok {
+			points[date] = &Point{}
+		}
+		points[date].WatchTime = row.WatchTime
+	}
+
+	return uc.newResponse(points, "channel_id", channelId, from, to)
+}
+
+// newResponse puts the daily points in order with empty days in between
+// and adds up the totals and sources.
+func (uc *UseCase) newResponse(points map[string]*Point, column string, id uint, from, to time.Time) (*GetResponse, error) {
+	response := &GetResponse{
+		From:   from.Format(time.DateOnly),
+		To:     to.Format(time.DateOnly),
+		Series: make([]*Point, 0),
+		Totals: &Point{},
+	}
+
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		point, ok := points[date.Format(time.DateOnly)]
+		if !ok {
+			point = &Point{}
+		}
+		point.Date = date.Format(time.DateOnly)
+		point.AverageViewDuration = averageViewDuration(point)
+		response.Series = append(response.Series, point)
+
+		response.Totals.Views += point.Views
+		response.Totals.WatchTime += point.WatchTime
+	}
+	response.Totals.AverageViewDuration = averageViewDuration(response.Totals)
+
+	// A viewer coming back on several days is unique once in the totals
+	uniqueViewers, err := uc.AnalyticsInteractor.GetUniqueViewers(column, id, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	response.Totals.UniqueViewers = uniqueViewers
+
+	sources, err := uc.AnalyticsInteractor.GetSources(column, id, response.From, response.To)
+	if err != nil {
+		return nil, err
+	}
+	response.Sources = make([]*SourceCount, 0)
+	for key := range sources {
+		response.Sources = append(response.Sources, &sources[key])
+	}
+
+	return response, nil
+}
+
+func averageViewDuration(point *Point) float64 {
+	if point.Views == 0 {
+		return 0
+	}
+
+	return float64(point.WatchTime) / float64(point.Views)
+}
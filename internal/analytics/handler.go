@@ -0,0 +1,58 @@
+package analytics
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+	"nine-dubz/internal/response"
+	"nine-dubz/internal/user"
+)
+
+type Handler struct {
+	AnalyticsUseCase *UseCase
+	UserHandler      *user.Handler
+}
+
+func NewHandler(uc *UseCase, uh *user.Handler) *Handler {
+	return &Handler{
+		AnalyticsUseCase: uc,
+		UserHandler:      uh,
+	}
+}
+
+func (h *Handler) GetChannelHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	from, to, err := NewRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Invalid date range")
+		return
+	}
+
+	analyticsResponse, err := h.AnalyticsUseCase.GetChannel(userId, from, to)
+	if err != nil {
+		response.RenderError(w, r, http.StatusInternalServerError, "Can't get analytics")
+		return
+	}
+
+	render.JSON(w, r, analyticsResponse)
+}
+
+func (h *Handler) GetMovieHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+	movieCode := chi.URLParam(r, "movieCode")
+
+	from, to, err := NewRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Invalid date range")
+		return
+	}
+
+	analyticsResponse, err := h.AnalyticsUseCase.GetMovie(userId, movieCode, from, to)
+	if err != nil {
+		response.RenderError(w, r, http.StatusNotFound, "Movie not found")
+		return
+	}
+
+	render.JSON(w, r, analyticsResponse)
+}
@@ -0,0 +1,17 @@
+package analytics
+
+import "time"
+
+type Interactor interface {
+	RollupViews(since time.Time) error
+	HasViewsRollupBefore(date time.Time) (bool, error)
+	AddWatchTime(rows []MovieDaily) error
+	AddRetention(rows []Retention) error
+	GetMovieOwner(code string) (uint, uint, error)
+	GetMovieSeries(movieId uint, from, to string) ([]MovieDaily, error)
+	GetChannelSeries(channelId uint, from, to string) ([]ChannelDaily, error)
+	GetChannelWatchTime(channelId uint, from, to string) ([]MovieDaily, error)
+	GetSources(column string, id uint, from, to string) ([]SourceCount, error)
+	GetUniqueViewers(column string, id uint, from, to time.Time) (int64, error)
+	GetRetention(movieId uint, from, to string) ([]Retention, error)
+}
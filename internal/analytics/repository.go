@@ -0,0 +1,176 @@
+package analytics
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// Users are told apart by ID, guests by IP
+const viewerExpr = "COALESCE(CONCAT('u', views.user_id), views.ip)"
+
+type Repository struct {
+	DB *gorm.DB
+}
+
+// RollupViews recounts views, unique viewers and sources of every day
+// since the given time from the views table.
+func (r *Repository) RollupViews(since time.Time) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO analytics_movie_daily (date, movie_id, channel_id, views, unique_viewers, watch_time)
+			SELECT DATE(views.created_at), views.movie_id, movies.user_id, COUNT(*), COUNT(DISTINCT `+viewerExpr+`), 0
+			FROM views JOIN movies ON movies.id = views.movie_id
+			WHERE views.created_at >= ? AND views.deleted_at IS NULL
+			GROUP BY DATE(views.created_at), views.movie_id, movies.user_id
+			ON DUPLICATE KEY UPDATE views = VALUES(views), unique_viewers = VALUES(unique_viewers)`,
+			since,
+		).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec(`INSERT INTO analytics_channel_daily (date, channel_id, views, unique_viewers)
+			SELECT DATE(views.created_at), movies.user_id, COUNT(*), COUNT(DISTINCT `+viewerExpr+`)
+			FROM views JOIN movies ON movies.id = views.movie_id
+			WHERE views.created_at >= ? AND views.deleted_at IS NULL
+			GROUP BY DATE(views.created_at), movies.user_id
+			ON DUPLICATE KEY UPDATE views = VALUES(views), unique_viewers = VALUES(unique_viewers)`,
+			since,
+		).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec(`INSERT INTO analytics_sources (date, movie_id, source, channel_id, views)
+			SELECT DATE(views.created_at), views.movie_id, views.source, movies.user_id, COUNT(*)
+			FROM views JOIN movies ON movies.id = views.movie_id
+			WHERE views.created_at >= ? AND views.deleted_at IS NULL
+			GROUP BY DATE(views.created_at), views.movie_id, views.source, movies.user_id
+			ON DUPLICATE KEY UPDATE views = VALUES(views)`,
+			since,
+		).Error
+	})
+}
+
+// HasViewsRollupBefore reports whether views of any day before the date
+// were rolled up
+func (r *Repository) HasViewsRollupBefore(date time.Time) (bool, error) {
+	var count int64
+	result := r.DB.Model(&ChannelDaily{}).Where("date < ?", date).Limit(1).Count(&count)
+
+	return count > 0, result.Error
+}
+
+func (r *Repository) AddWatchTime(rows []MovieDaily) error {
+	return r.DB.
+		Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{"watch_time": gorm.Expr("watch_time + VALUES(watch_time)")}),
+		}).
+		Create(&rows).
+		Error
+}
+
+func (r *Repository) AddRetention(rows []Retention) error {
+	return r.DB.
+		Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{"viewers": gorm.Expr("viewers + VALUES(viewers)")}),
+		}).
+		Create(&rows).
+		Error
+}
+
+// GetMovieOwner returns the movie ID and the ID of its channel
+func (r *Repository) GetMovieOwner(code string) (uint, uint, error) {
+	var movie struct {
+		ID     uint
+		UserId uint
+	}
+	result := r.DB.
+		Table("movies").
+		Select("id, user_id").
+		Where("code = ? AND deleted_at IS NULL", code).
+		Take(&movie)
+
+	return movie.ID, movie.UserId, result.Error
+}
+
+func (r *Repository) GetMovieSeries(movieId uint, from, to string) ([]MovieDaily, error) {
+	var rows []MovieDaily
+	result := r.DB.
+		Where("movie_id = ? AND date BETWEEN ? AND ?", movieId, from, to).
+		Order("date").
+		Find(&rows)
+
+	return rows, result.Error
+}
+
+func (r *Repository) GetChannelSeries(channelId uint, from, to string) ([]ChannelDaily, error) {
+	var rows []ChannelDaily
+	result := r.DB.
+		Where("channel_id = ? AND date BETWEEN ? AND ?", channelId, from, to).
+		Order("date").
+		Find(&rows)
+
+	return rows, result.Error
+}
+
+// GetChannelWatchTime adds up watch time of the channel movies by day
+func (r *Repository) GetChannelWatchTime(channelId uint, from, to string) ([]MovieDaily, error) {
+	var rows []MovieDaily
+	result := r.DB.
+		Model(&MovieDaily{}).
+		Select("date, SUM(watch_time) AS watch_time").
+		Where("channel_id = ? AND date BETWEEN ? AND ?", channelId, from, to).
+		Group("date").
+		Find(&rows)
+
+	return rows, result.Error
+}
+
+// GetSources adds up views by source of a movie or a channel, column is
+// movie_id or channel_id
+func (r *Repository) GetSources(column string, id uint, from, to string) ([]SourceCount, error) {
+	var rows []SourceCount
+	result := r.DB.
+		Model(&Source{}).
+		Select("source, SUM(views) AS views").
+		Where(column+" = ? AND date BETWEEN ? AND ?", id, from, to).
+		Group("source").
+		Order("views desc").
+		Scan(&rows)
+
+	return rows, result.Error
+}
+
+// GetUniqueViewers counts viewers of a movie or a channel from the views
+// table, daily counts can't be added up as viewers come back on other days.
+// column is movie_id or channel_id, to is excluded.
+func (r *Repository) GetUniqueViewers(column string, id uint, from, to time.Time) (int64, error) {
+	viewsColumn := "views.movie_id"
+	if column == "channel_id" {
+		viewsColumn = "movies.user_id"
+	}
+
+	var count int64
+	result := r.DB.
+		Table("views").
+		Select("COUNT(DISTINCT "+viewerExpr+")").
+		Joins("JOIN movies ON movies.id = views.movie_id").
+		Where(viewsColumn+" = ? AND views.created_at >= ? AND views.created_at < ? AND views.deleted_at IS NULL", id, from, to).
+		Scan(&count)
+
+	return count, result.Error
+}
+
+func (r *Repository) GetRetention(movieId uint, from, to string) ([]Retention, error) {
+	var rows []Retention
+	result := r.DB.
+		Model(&Retention{}).
+		Select("bucket, SUM(viewers) AS viewers").
+		Where("movie_id = ? AND date BETWEEN ? AND ?", movieId, from, to).
+		Group("bucket").
+		Order("bucket").
+		Find(&rows)
+
+	return rows, result.Error
+}
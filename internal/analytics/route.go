@@ -0,0 +1,14 @@
+package analytics
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) Routes(r chi.Router) {
+	r.
+		With(h.UserHandler.IsAuthorized).
+		Route("/analytics", func(r chi.Router) {
+			r.Get("/channel", h.GetChannelHandler)
+			r.Get("/movie/{movieCode}", h.GetMovieHandler)
+		})
+}
@@ -0,0 +1,108 @@
+package analytics
+
+import "time"
+
+// MovieDaily is a day of a movie's statistics. Views and unique viewers
+// are rolled up from views, watch time is added up from the player's
+// heartbeats.
+type MovieDaily struct {
+	Date          time.Time `gorm:"type:date;primaryKey"`
+	MovieId       uint      `gorm:"primaryKey;autoIncrement:false"`
+	ChannelId     uint      `gorm:"not null;index"`
+	Views         int64     `gorm:"not null;default:0"`
+	UniqueViewers int64     `gorm:"not null;default:0"`
+	WatchTime     int64     `gorm:"not null;default:0"`
+}
+
+func (MovieDaily) TableName() string {
+	return "analytics_movie_daily"
+}
+
+// ChannelDaily is a day of a channel's views, viewers of several movies
+// count once.
+type ChannelDaily struct {
+	Date          time.Time `gorm:"type:date;primaryKey"`
+	ChannelId     uint      `gorm:"primaryKey;autoIncrement:false"`
+	Views         int64     `gorm:"not null;default:0"`
+	UniqueViewers int64     `gorm:"not null;default:0"`
+}
+
+func (ChannelDaily) TableName() string {
+	return "analytics_channel_daily"
+}
+
+// Retention counts viewers who reached each bucket of a movie in a day
+type Retention struct {
+	Date    time.Time `gorm:"type:date;primaryKey"`
+	MovieId uint      `gorm:"primaryKey;autoIncrement:false"`
+	Bucket  int       `gorm:"primaryKey;autoIncrement:false"`
+	Viewers int64     `gorm:"not null;default:0"`
+}
+
+func (Retention) TableName() string {
+	return "analytics_retention"
+}
+
+// Source counts a movie's views in a day by where viewers came from
+type Source struct {
+	Date      time.Time `gorm:"type:date;primaryKey"`
+	MovieId   uint      `gorm:"primaryKey;autoIncrement:false"`
+	Source    string    `gorm:"size:20;primaryKey"`
+	ChannelId uint      `gorm:"not null;index"`
+	Views     int64     `gorm:"not null;default:0"`
+}
+
+func (Source) TableName() string {
+	return "analytics_sources"
+}
+
+type Point struct {
+	Date                string  `json:"date"`
+	Views               int64   `json:"views"`
+	UniqueViewers       int64   `json:"uniqueViewers"`
+	WatchTime           int64   `json:"watchTime"`
+	AverageViewDuration float64 `json:"averageViewDuration"`
+}
+
+type SourceCount struct {
+	Source string `json:"source"`
+	Views  int64  `json:"views"`
+}
+
+// RetentionPoint is the share of viewers still watching at Start seconds
+type RetentionPoint struct {
+	Start   int     `json:"start"`
+	Viewers int64   `json:"viewers"`
+	Share   float64 `json:"share"`
+}
+
+// GetResponse is the statistics of a date range: a point per day, the
+// range totals, views by source and, for movies, the retention curve.
+// Unique viewers in totals are the sum of daily unique viewers.
+type GetResponse struct {
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Series    []*Point          `json:"series"`
+	Totals    *Point            `json:"totals"`
+	Sources   []*SourceCount    `json:"sources"`
+	Retention []*RetentionPoint `json:"retention,omitempty"`
+}
+
+type session struct {
+	position int
+	watched  int
+	lastSeen time.Time
+	buckets  map[int]bool
+}
+
+type dailyKey struct {
+	date      time.Time
+	movieId   uint
+	channelId uint
+}
+
+type retentionKey struct {
+	date    time.Time
+	movieId uint
+	bucket  int
+}
@@ -0,0 +1,165 @@
+package category
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"nine-dubz/pkg/language"
+	"regexp"
+	"unicode/utf8"
+)
+
+var nameRegexp = regexp.MustCompile(`^[A-Z0-9_]{1,100}$`)
+
+type UseCase struct {
+	CategoryInteractor Interactor
+}
+
+func New(db *gorm.DB) *UseCase {
+	return &UseCase{
+		CategoryInteractor: &Repository{
+			DB: db,
+		},
+	}
+}
+
+func (uc *UseCase) Add(categoryAddRequest *AddRequest) (*GetForAdminResponse, error) {
+	if err := Validate(categoryAddRequest.Name, categoryAddRequest.Icon); err != nil {
+		return nil, err
+	}
+
+	category := NewAddRequest(categoryAddRequest)
+	if err := uc.CategoryInteractor.Create(category); err != nil {
+		return nil, err
+	}
+
+	return NewGetForAdminResponse(category, category.Name), nil
+}
+
+func (uc *UseCase) Update(categoryUpdateRequest *UpdateRequest) error {
+	category := &Category{}
+	var selectQuery []string
+
+	if categoryUpdateRequest.Name != nil {
+		category.Name = *categoryUpdateRequest.Name
+		selectQuery = append(selectQuery, "Name")
+	}
+	if categoryUpdateRequest.Icon != nil {
+		category.Icon = *categoryUpdateRequest.Icon
+		selectQuery = append(selectQuery, "Icon")
+	}
+	if categoryUpdateRequest.Sort != nil {
+		category.Sort = *categoryUpdateRequest.Sort
+		selectQuery = append(selectQuery, "Sort")
+	}
+	if categoryUpdateRequest.Enabled != nil {
+		category.Enabled = *categoryUpdateRequest.Enabled
+		selectQuery = append(selectQuery, "Enabled")
+	}
+
+	if len(selectQuery) == 0 {
+		return errors.New("nothing to update")
+	}
+
+	if categoryUpdateRequest.Name != nil || categoryUpdateRequest.Icon != nil {
+		current, err := uc.CategoryInteractor.Get(categoryUpdateRequest.ID)
+		if err != nil {
+			return err
+		}
+		if categoryUpdateRequest.Name == nil {
+			category.Name = current.Name
+		}
+		if categoryUpdateRequest.Icon == nil {
+			category.Icon = current.Icon
+		}
+
+		if err = Validate(category.Name, category.Icon); err != nil {
+			return err
+		}
+	}
+
+	rowsAffected, err := uc.CategoryInteractor.UpdatesSelectWhere(
+		category,
+		selectQuery,
+		map[string]interface{}{"id": categoryUpdateRequest.ID},
+	)
+	if err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return errors.New("category not found")
+	}
+
+	return nil
+}
+
+func (uc *UseCase) Delete(id uint) error {
+	rowsAffected, err := uc.CategoryInteractor.Delete(id)
+	if errors.Is(err, gorm.ErrForeignKeyViolated) {
+		return errors.New("category has movies, disable it instead")
+	} else if err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return errors.New("category not found")
+	}
+
+	return nil
+}
+
+// IsEnabled reports whether movies can be assigned to the category.
+func (uc *UseCase) IsEnabled(id uint) bool {
+	category, err := uc.CategoryInteractor.Get(id)
+	if err != nil {
+		return false
+	}
+
+	return category.Enabled
+}
+
+func (uc *UseCase) GetMultiple(languageCode string) ([]*GetResponse, error) {
+	categories, err := uc.CategoryInteractor.GetWhereMultiple(map[string]interface{}{"enabled": true})
+	if err != nil {
+		return nil, err
+	}
+
+	var categoriesPayload []*GetResponse
+	for _, category := range categories {
+		categoriesPayload = append(categoriesPayload, NewGetResponse(&category, GetTitle(&category, languageCode)))
+	}
+
+	return categoriesPayload, nil
+}
+
+func (uc *UseCase) GetMultipleForAdmin(languageCode string) ([]*GetForAdminResponse, error) {
+	categories, err := uc.CategoryInteractor.GetWhereMultiple(map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	var categoriesPayload []*GetForAdminResponse
+	for _, category := range categories {
+		categoriesPayload = append(categoriesPayload, NewGetForAdminResponse(&category, GetTitle(&category, languageCode)))
+	}
+
+	return categoriesPayload, nil
+}
+
+// GetTitle resolves the category name through the language files,
+// falling back to the name itself if the message is missing.
+func GetTitle(category *Category, languageCode string) string {
+	title, err := language.GetMessage(category.Name, languageCode)
+	if err != nil {
+		return category.Name
+	}
+
+	return title
+}
+
+func Validate(name, icon string) error {
+	if !nameRegexp.MatchString(name) {
+		return errors.New("category name must be a language message code, e.g. CATEGORY_GAMES")
+	}
+	if utf8.RuneCountInString(icon) > 255 {
+		return errors.New("category icon too long")
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package category
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+	"nine-dubz/internal/response"
+	"nine-dubz/internal/user"
+	"nine-dubz/pkg/language"
+	"strconv"
+)
+
+type Handler struct {
+	CategoryUseCase *UseCase
+	UserHandler     *user.Handler
+}
+
+func NewHandler(uc *UseCase, uh *user.Handler) *Handler {
+	return &Handler{
+		CategoryUseCase: uc,
+		UserHandler:     uh,
+	}
+}
+
+func (h *Handler) GetMultipleHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.CategoryUseCase.GetMultiple(language.GetLanguageCode(r))
+	if err != nil {
+		response.RenderError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if len(categories) > 0 {
+		render.JSON(w, r, categories)
+	} else {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, make([]struct{}, 0))
+	}
+}
+
+func (h *Handler) GetMultipleForAdminHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.CategoryUseCase.GetMultipleForAdmin(language.GetLanguageCode(r))
+	if err != nil {
+		response.RenderError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if len(categories) > 0 {
+		render.JSON(w, r, categories)
+	} else {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, make([]struct{}, 0))
+	}
+}
+
+func (h *Handler) AddHandler(w http.ResponseWriter, r *http.Request) {
+	categoryAddRequest := &AddRequest{}
+	if err := json.NewDecoder(r.Body).Decode(categoryAddRequest); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't parse fields")
+		return
+	}
+
+	category, err := h.CategoryUseCase.Add(categoryAddRequest)
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't add category: "+err.Error())
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, category)
+}
+
+func (h *Handler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	categoryId, err := strconv.ParseUint(chi.URLParam(r, "categoryId"), 10, 32)
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Invalid category id")
+		return
+	}
+
+	categoryUpdateRequest := &UpdateRequest{}
+	if err = json.NewDecoder(r.Body).Decode(categoryUpdateRequest); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't parse fields")
+		return
+	}
+	categoryUpdateRequest.ID = uint(categoryId)
+
+	if err = h.CategoryUseCase.Update(categoryUpdateRequest); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't update category: "+err.Error())
+		return
+	}
+
+	response.RenderSuccess(w, r, http.StatusOK, "")
+}
+
+func (h *Handler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	categoryId, err := strconv.ParseUint(chi.URLParam(r, "categoryId"), 10, 32)
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Invalid category id")
+		return
+	}
+
+	if err = h.CategoryUseCase.Delete(uint(categoryId)); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't delete category: "+err.Error())
+		return
+	}
+
+	response.RenderSuccess(w, r, http.StatusOK, "")
+}
@@ -0,0 +1,9 @@
+package category
+
+type Interactor interface {
+	Create(category *Category) error
+	UpdatesSelectWhere(category *Category, selectQuery, where interface{}) (int64, error)
+	Delete(id uint) (int64, error)
+	Get(id uint) (*Category, error)
+	GetWhereMultiple(where interface{}) ([]Category, error)
+}
@@ -0,0 +1,37 @@
+package category
+
+import "gorm.io/gorm"
+
+type Repository struct {
+	DB *gorm.DB
+}
+
+func (r *Repository) Create(category *Category) error {
+	return r.DB.Create(category).Error
+}
+
+func (r *Repository) UpdatesSelectWhere(category *Category, selectQuery, where interface{}) (int64, error) {
+	result := r.DB.Model(&Category{}).Select(selectQuery).Where(where).Updates(category)
+
+	return result.RowsAffected, result.Error
+}
+
+func (r *Repository) Delete(id uint) (int64, error) {
+	result := r.DB.Delete(&Category{ID: id})
+
+	return result.RowsAffected, result.Error
+}
+
+func (r *Repository) Get(id uint) (*Category, error) {
+	category := &Category{}
+	result := r.DB.First(category, id)
+
+	return category, result.Error
+}
+
+func (r *Repository) GetWhereMultiple(where interface{}) ([]Category, error) {
+	var categories []Category
+	result := r.DB.Where(where).Order("sort asc, id asc").Find(&categories)
+
+	return categories, result.Error
+}
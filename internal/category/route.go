@@ -0,0 +1,23 @@
+package category
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) Routes(r chi.Router) {
+	r.Route("/category", func(r chi.Router) {
+		r.Get("/", h.GetMultipleHandler)
+
+		r.
+			With(h.UserHandler.IsAuthorized).
+			With(h.UserHandler.UserPermission).
+			Route("/admin", func(r chi.Router) {
+				r.Get("/", h.GetMultipleForAdminHandler)
+				r.Post("/", h.AddHandler)
+				r.Route("/{categoryId}", func(r chi.Router) {
+					r.Post("/", h.UpdateHandler)
+					r.Delete("/", h.DeleteHandler)
+				})
+			})
+	})
+}
@@ -1,66 +1,120 @@
 package category
 
-import (
-	"database/sql/driver"
-	"fmt"
-	"reflect"
-)
+import "time"
 
+// Category name is a language message code, e.g. CATEGORY_GAMES.
 type Category struct {
-	ID   uint   `json:"id"`
-	Name string `json:"name"`
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+	Name      string    `json:"name" gorm:"size:100;not null"`
+	Icon      string    `json:"icon" gorm:"size:255"`
+	Sort      int       `json:"sort" gorm:"index"`
+	Enabled   bool      `json:"enabled"`
 }
 
-func (cat *Category) Scan(value interface{}) error {
-	var catId interface{}
+// DefaultCategories are created on the first start, their IDs match the
+// values movies were saved with before categories were moved to the database.
+var DefaultCategories = []Category{
+	{
+		ID:      1,
+		Name:    "CATEGORY_GAMES",
+		Sort:    100,
+		Enabled: true,
+	},
+	{
+		ID:      2,
+		Name:    "CATEGORY_BLOG",
+		Sort:    200,
+		Enabled: true,
+	},
+	{
+		ID:      3,
+		Name:    "CATEGORY_MUSIC",
+		Sort:    300,
+		Enabled: true,
+	},
+	{
+		ID:      4,
+		Name:    "CATEGORY_HUMOR",
+		Sort:    400,
+		Enabled: true,
+	},
+	{
+		ID:      5,
+		Name:    "CATEGORY_EDUCATION",
+		Sort:    500,
+		Enabled: true,
+	},
+}
 
-	switch x := value.(type) {
-	case int64, uint64:
-		catId = x
-		break
-	default:
-		return fmt.Errorf("can't scan category of type %v with id %v", reflect.TypeOf(value), value)
+type AddRequest struct {
+	Name    string `json:"name"`
+	Icon    string `json:"icon"`
+	Sort    *int   `json:"sort"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// NewAddRequest fills the omitted fields, the columns have no defaults for
+// GORM to skip zero values for
+func NewAddRequest(category *AddRequest) *Category {
+	sort := 100
+	if category.Sort != nil {
+		sort = *category.Sort
+	}
+	enabled := true
+	if category.Enabled != nil {
+		enabled = *category.Enabled
 	}
 
-	for _, category := range Categories {
-		if category.ID == catId {
-			*cat = category
-			break
-		}
+	return &Category{
+		Name:    category.Name,
+		Icon:    category.Icon,
+		Sort:    sort,
+		Enabled: enabled,
 	}
+}
 
-	return nil
+type UpdateRequest struct {
+	ID      uint    `json:"-"`
+	Name    *string `json:"name"`
+	Icon    *string `json:"icon"`
+	Sort    *int    `json:"sort"`
+	Enabled *bool   `json:"enabled"`
 }
 
-func (cat Category) Value() (driver.Value, error) {
-	for _, category := range Categories {
-		if cat.ID == category.ID {
-			return int64(cat.ID), nil
-		}
+type GetResponse struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Icon  string `json:"icon"`
+}
+
+func NewGetResponse(category *Category, title string) *GetResponse {
+	return &GetResponse{
+		ID:    category.ID,
+		Name:  category.Name,
+		Title: title,
+		Icon:  category.Icon,
 	}
+}
 
-	return nil, fmt.Errorf("category with id %d not found", cat.ID)
+type GetForAdminResponse struct {
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	Title   string `json:"title"`
+	Icon    string `json:"icon"`
+	Sort    int    `json:"sort"`
+	Enabled bool   `json:"enabled"`
 }
 
-var Categories = []Category{
-	{
-		ID:   1,
-		Name: "CATEGORY_GAMES",
-	},
-	{
-		ID:   2,
-		Name: "CATEGORY_BLOG",
-	},
-	{
-		ID:   3,
-		Name: "CATEGORY_MUSIC",
-	},
-	{
-		ID:   4,
-		Name: "CATEGORY_HUMOR",
-	},
-	{
-		ID:   5,
-		Name: "CATEGORY_EDUCATION",
-	},
+func NewGetForAdminResponse(category *Category, title string) *GetForAdminResponse {
+	return &GetForAdminResponse{
+		ID:      category.ID,
+		Name:    category.Name,
+		Title:   title,
+		Icon:    category.Icon,
+		Sort:    category.Sort,
+		Enabled: category.Enabled,
+	}
 }
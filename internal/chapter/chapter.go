@@ -0,0 +1,127 @@
+package chapter
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+type UseCase struct {
+	ChapterInteractor Interactor
+}
+
+func New(db *gorm.DB) *UseCase {
+	return &UseCase{
+		ChapterInteractor: &Repository{
+			DB: db,
+		},
+	}
+}
+
+func (uc *UseCase) Set(movieId uint, duration int, chaptersRequest []SetRequest) error {
+	if err := Validate(chaptersRequest, duration); err != nil {
+		return err
+	}
+
+	var chapters []Chapter
+	for _, chapterRequest := range chaptersRequest {
+		chapters = append(chapters, *NewSetRequest(movieId, &chapterRequest))
+	}
+
+	return uc.ChapterInteractor.Replace(movieId, chapters)
+}
+
+func (uc *UseCase) GetMultiple(movieId uint, duration int) ([]*GetResponse, error) {
+	chapters, err := uc.ChapterInteractor.GetMultiple(movieId)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewGetResponseMultiple(chapters, duration), nil
+}
+
+// Cut drops the chapters starting past the new end of a shortened movie
+func (uc *UseCase) Cut(movieId uint, duration int) error {
+	if duration <= 0 {
+		return nil
+	}
+
+	return uc.ChapterInteractor.DeleteFrom(movieId, duration)
+}
+
+// Validate sorts the chapters and checks them against the movie duration,
+// a zero duration isn't known yet and isn't checked
+func Validate(chapters []SetRequest, duration int) error {
+	if len(chapters) > 100 {
+		return errors.New("too many chapters")
+	}
+
+	sort.Slice(chapters, func(i, j int) bool {
+		return chapters[i].Start < chapters[j].Start
+	})
+
+	for i, chapter := range chapters {
+		chapters[i].Title = strings.TrimSpace(chapter.Title)
+		if utf8.RuneCountInString(chapters[i].Title) == 0 {
+			return errors.New("chapter title is required")
+		}
+		if utf8.RuneCountInString(chapters[i].Title) > 100 {
+			return errors.New("chapter title too long")
+		}
+		if strings.ContainsAny(chapters[i].Title, "\r\n") {
+			return errors.New("chapter title can't have line breaks")
+		}
+		if chapter.Start < 0 {
+			return errors.New("invalid chapter start")
+		}
+		if duration > 0 && chapter.Start >= duration {
+			return errors.New("chapter starts after the end of the movie")
+		}
+		if i > 0 && chapter.Start == chapters[i-1].Start {
+			return errors.New("chapters with the same start")
+		}
+	}
+
+	return nil
+}
+
+func ParseFromText(text string) []SetRequest {
+	r := regexp.MustCompile(`^\s*(?:(\d{1,2}):)?(\d{1,2}):(\d{2})\s+(.+)$`)
+
+	var chapters []SetRequest
+	for _, line := range strings.Split(text, "\n") {
+		matches := r.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if matches == nil {
+			continue
+		}
+
+		hours, _ := strconv.Atoi(matches[1])
+		minutes, _ := strconv.Atoi(matches[2])
+		seconds, _ := strconv.Atoi(matches[3])
+		if seconds > 59 || (matches[1] != "" && minutes > 59) {
+			continue
+		}
+
+		chapters = append(chapters, SetRequest{
+			Title: strings.TrimSpace(matches[4]),
+			Start: hours*3600 + minutes*60 + seconds,
+		})
+	}
+
+	// At least two chapters, the first one at 00:00, in ascending order
+	if len(chapters) < 2 || chapters[0].Start != 0 {
+		return nil
+	}
+	for i := 1; i < len(chapters); i++ {
+		if chapters[i].Start <= chapters[i-1].Start {
+			return nil
+		}
+	}
+
+	return chapters
+}
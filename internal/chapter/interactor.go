@@ -0,0 +1,7 @@
+package chapter
+
+type Interactor interface {
+	Replace(movieId uint, chapters []Chapter) error
+	GetMultiple(movieId uint) ([]Chapter, error)
+	DeleteFrom(movieId uint, start int) error
+}
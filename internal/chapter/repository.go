@@ -0,0 +1,32 @@
+package chapter
+
+import "gorm.io/gorm"
+
+type Repository struct {
+	DB *gorm.DB
+}
+
+func (r *Repository) Replace(movieId uint, chapters []Chapter) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&Chapter{}, "movie_id = ?", movieId).Error; err != nil {
+			return err
+		}
+
+		if len(chapters) == 0 {
+			return nil
+		}
+
+		return tx.Create(&chapters).Error
+	})
+}
+
+func (r *Repository) GetMultiple(movieId uint) ([]Chapter, error) {
+	var chapters []Chapter
+	result := r.DB.Where("movie_id = ?", movieId).Order("start asc").Find(&chapters)
+
+	return chapters, result.Error
+}
+
+func (r *Repository) DeleteFrom(movieId uint, start int) error {
+	return r.DB.Unscoped().Delete(&Chapter{}, "movie_id = ? AND start >= ?", movieId, start).Error
+}
@@ -0,0 +1,50 @@
+package chapter
+
+import "gorm.io/gorm"
+
+type Chapter struct {
+	gorm.Model
+	MovieID uint   `gorm:"not null;index"`
+	Title   string `gorm:"not null"`
+	Start   int    `gorm:"not null"`
+}
+
+type SetRequest struct {
+	Title string `json:"title"`
+	Start int    `json:"start"`
+}
+
+func NewSetRequest(movieId uint, chapter *SetRequest) *Chapter {
+	return &Chapter{
+		MovieID: movieId,
+		Title:   chapter.Title,
+		Start:   chapter.Start,
+	}
+}
+
+type GetResponse struct {
+	Title string `json:"title"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+func NewGetResponseMultiple(chapters []Chapter, duration int) []*GetResponse {
+	response := make([]*GetResponse, 0)
+	for i, chapter := range chapters {
+		end := duration
+		if i+1 < len(chapters) {
+			end = chapters[i+1].Start
+		}
+		if end <= chapter.Start {
+			end = chapter.Start + 1
+		}
+
+		response = append(response, &GetResponse{
+			Title: chapter.Title,
+			Start: chapter.Start,
+			End:   end,
+		})
+	}
+
+	return response
+}
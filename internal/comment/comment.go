@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"log"
 	"nine-dubz/internal/movie"
+	"nine-dubz/internal/notification"
 	"nine-dubz/internal/pagination"
 	"nine-dubz/internal/sorting"
 	"nine-dubz/internal/user"
@@ -14,19 +16,29 @@ import (
 	"unicode/utf8"
 )
 
+// Replies are shown in the order they were written
+var subCommentsSortKey = pagination.Key{SortBy: "created_at", Column: "comments.created_at", IdColumn: "comments.id"}
+
+var mentionRegexp = regexp.MustCompile(`<@id:(\d*)>`)
+
+// Mentioned users notified by one comment
+const maxMentionNotifications = 10
+
 type UseCase struct {
-	CommentInteractor Interactor
-	MovieUseCase      *movie.UseCase
-	UserUseCase       *user.UseCase
+	CommentInteractor   Interactor
+	MovieUseCase        *movie.UseCase
+	UserUseCase         *user.UseCase
+	NotificationUseCase *notification.UseCase
 }
 
-func New(db *gorm.DB, muc *movie.UseCase, uuc *user.UseCase) *UseCase {
+func New(db *gorm.DB, muc *movie.UseCase, uuc *user.UseCase, nuc *notification.UseCase) *UseCase {
 	return &UseCase{
 		CommentInteractor: &Repository{
 			DB: db,
 		},
-		MovieUseCase: muc,
-		UserUseCase:  uuc,
+		MovieUseCase:        muc,
+		UserUseCase:         uuc,
+		NotificationUseCase: nuc,
 	}
 }
 
@@ -55,10 +67,11 @@ func (uc *UseCase) Add(userId uint, movieCode, text string, options ...uint) (*A
 		UserID:  userId,
 	}
 
+	var parentUserId uint
 	if parentCommentId > 0 {
 		parentComments, err := uc.CommentInteractor.GetDistinctMultiple(
 			map[string]interface{}{"id": parentCommentId},
-			[]string{"parent_id"},
+			[]string{"parent_id", "user_id"},
 		)
 		if err != nil {
 			return nil, err
@@ -66,6 +79,7 @@ func (uc *UseCase) Add(userId uint, movieCode, text string, options ...uint) (*A
 			if parentComments[0].ParentID != nil {
 				return nil, errors.New("parent comment already exists")
 			}
+			parentUserId = parentComments[0].UserID
 		}
 
 		comment.ParentID = &parentCommentId
@@ -90,30 +104,66 @@ func (uc *UseCase) Add(userId uint, movieCode, text string, options ...uint) (*A
 
 	err = uc.Format(&comments)
 
+	uc.Notify(&comments[0], parentUserId, movieCode)
+
 	return NewAddResponse(&comments[0]), nil
 }
 
-func (uc *UseCase) GetMultipleSubComments(userId *uint, movieCode string, parentId uint, pagination *pagination.Pagination) (*[]GetSubCommentResponse, error) {
-	if pagination.Limit > 10 || pagination.Limit == -1 {
-		pagination.Limit = 10
+// Notify tells the author of the parent comment about the reply and the
+// mentioned users about the mention. A mentioned parent author only gets
+// the reply, users who can't open the movie are not told about it.
+func (uc *UseCase) Notify(comment *Comment, parentUserId uint, movieCode string) {
+	template := notification.Notification{
+		ActorId:   &comment.UserID,
+		MovieId:   &comment.MovieID,
+		CommentId: &comment.ID,
+	}
+
+	if parentUserId > 0 {
+		template.Type = notification.TypeReply
+		if err := uc.NotificationUseCase.Notify(template, []uint{parentUserId}); err != nil {
+			log.Println("comment: notify reply:", err)
+		}
 	}
 
+	var mentionedIds []uint
+	for _, mention := range comment.Mentions {
+		if len(mentionedIds) == maxMentionNotifications {
+			break
+		}
+		if mention.UserID != parentUserId && uc.MovieUseCase.CheckMovieAccess(&mention.UserID, movieCode) {
+			mentionedIds = append(mentionedIds, mention.UserID)
+		}
+	}
+	if len(mentionedIds) > 0 {
+		template.Type = notification.TypeMention
+		if err := uc.NotificationUseCase.Notify(template, mentionedIds); err != nil {
+			log.Println("comment: notify mention:", err)
+		}
+	}
+}
+
+func (uc *UseCase) GetMultipleSubComments(userId *uint, movieCode string, parentId uint, pagination *pagination.Pagination) (*[]GetSubCommentResponse, error) {
 	movieResponse, err := uc.MovieUseCase.Get(userId, movieCode)
 	if err != nil {
 		return nil, err
 	}
 
-	comments, err := uc.CommentInteractor.GetMultiple(
-		map[string]interface{}{
-			"movie_id":  movieResponse.ID,
-			"parent_id": parentId,
-		},
-		"created_at asc",
-		pagination,
-	)
+	where := map[string]interface{}{
+		"movie_id":  movieResponse.ID,
+		"parent_id": parentId,
+	}
+	comments, err := uc.CommentInteractor.GetMultiple(where, pagination.OrderBy(subCommentsSortKey), pagination)
 	if err != nil {
 		return nil, err
 	}
+	comments = comments[:pagination.Trim(comments, func(i int) (interface{}, uint) {
+		return comments[i].CreatedAt, comments[i].ID
+	})]
+
+	if err = uc.SetTotal(where, pagination); err != nil {
+		return nil, err
+	}
 
 	err = uc.Format(&comments)
 	if err != nil {
@@ -124,10 +174,6 @@ func (uc *UseCase) GetMultipleSubComments(userId *uint, movieCode string, parent
 }
 
 func (uc *UseCase) GetMultiple(userId *uint, movieCode string, pagination *pagination.Pagination, sort *sorting.Sort) (*[]GetResponse, error) {
-	if pagination.Limit > 20 || pagination.Limit == -1 {
-		pagination.Limit = 20
-	}
-
 	if !slices.Contains([]string{"created_at"}, sort.SortBy) {
 		sort.SortBy = "created_at"
 		sort.SortVal = "desc"
@@ -138,17 +184,21 @@ func (uc *UseCase) GetMultiple(userId *uint, movieCode string, pagination *pagin
 		return nil, err
 	}
 
-	comments, err := uc.CommentInteractor.GetMultiple(
-		map[string]interface{}{
-			"movie_id":  movieResponse.ID,
-			"parent_id": nil,
-		},
-		fmt.Sprintf("%s %s", sort.SortBy, sort.SortVal),
-		pagination,
-	)
+	where := map[string]interface{}{
+		"movie_id":  movieResponse.ID,
+		"parent_id": nil,
+	}
+	comments, err := uc.CommentInteractor.GetMultiple(where, pagination.OrderBy(NewSortKey(sort)), pagination)
 	if err != nil {
 		return nil, err
 	}
+	comments = comments[:pagination.Trim(comments, func(i int) (interface{}, uint) {
+		return comments[i].CreatedAt, comments[i].ID
+	})]
+
+	if err = uc.SetTotal(where, pagination); err != nil {
+		return nil, err
+	}
 
 	if len(comments) == 0 {
 		return nil, err
@@ -188,8 +238,49 @@ func (uc *UseCase) GetMultiple(userId *uint, movieCode string, pagination *pagin
 	return NewGetMultipleResponse(&comments), nil
 }
 
+// SetTotal counts the list items when the client asked for the total.
+func (uc *UseCase) SetTotal(where interface{}, pagination *pagination.Pagination) error {
+	if !pagination.WithTotal {
+		return nil
+	}
+
+	total, err := uc.CommentInteractor.Count(where)
+	if err != nil {
+		return err
+	}
+	pagination.Total = &total
+
+	return nil
+}
+
+func NewSortKey(sort *sorting.Sort) pagination.Key {
+	return pagination.Key{
+		SortBy:   sort.SortBy,
+		Column:   "comments." + sort.SortBy,
+		IdColumn: "comments.id",
+		Desc:     sort.SortVal == "desc",
+	}
+}
+
+func (uc *UseCase) GetMultipleByIds(ids []uint) ([]Comment, error) {
+	comments, err := uc.CommentInteractor.GetMultiple(
+		map[string]interface{}{"id": ids},
+		"created_at desc",
+		&pagination.Pagination{Limit: len(ids), Offset: -1},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.Format(&comments)
+	if err != nil {
+		return nil, errors.New("comment: error while formatting comments")
+	}
+
+	return comments, nil
+}
+
 func (uc *UseCase) Format(comments *[]Comment) error {
-	r := regexp.MustCompile(`<@id:(\d*)>`)
 	var userIds []uint
 	type Text struct {
 		UserIds  []uint
@@ -201,7 +292,7 @@ func (uc *UseCase) Format(comments *[]Comment) error {
 		commentText := Text{
 			Mentions: &(*comments)[i].Mentions,
 		}
-		matches := r.FindAllStringSubmatch(comment.Text, -1)
+		matches := mentionRegexp.FindAllStringSubmatch(comment.Text, -1)
 		for _, match := range matches {
 			userId, err := strconv.ParseUint(match[1], 10, 32)
 			if err != nil {
@@ -219,7 +310,7 @@ func (uc *UseCase) Format(comments *[]Comment) error {
 			subCommentsText := Text{
 				Mentions: &(*comments)[i].SubComments[j].Mentions,
 			}
-			matches = r.FindAllStringSubmatch(subComment.Text, -1)
+			matches = mentionRegexp.FindAllStringSubmatch(subComment.Text, -1)
 			for _, match := range matches {
 				userId, err := strconv.ParseUint(match[1], 10, 32)
 				if err != nil {
@@ -79,7 +79,7 @@ func (h *Handler) GetMultipleSubCommentsHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	render.JSON(w, r, comments)
+	render.JSON(w, r, subPagination.NewList(comments))
 }
 
 func (h *Handler) GetMultipleHandler(w http.ResponseWriter, r *http.Request) {
@@ -94,12 +94,7 @@ func (h *Handler) GetMultipleHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if comments != nil {
-		render.JSON(w, r, comments)
-	} else {
-		render.Status(r, http.StatusOK)
-		render.JSON(w, r, make([]struct{}, 0))
-	}
+	render.JSON(w, r, pagination.NewList(comments))
 }
 
 func (h *Handler) DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
@@ -10,9 +10,13 @@ type Repository struct {
 }
 
 func (r *Repository) Create(comment *Comment) error {
-	result := r.DB.Create(comment)
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
 
-	return result.Error
+		return r.UpdateMovieCommentsCount(tx, comment.MovieID)
+	})
 }
 
 func (r *Repository) GetDistinctMultiple(where, distinct interface{}) ([]Comment, error) {
@@ -31,8 +35,7 @@ func (r *Repository) GetMultiple(where interface{}, order string, pagination *pa
 		Preload("User").
 		Preload("User.Picture").
 		Where(where).
-		Limit(pagination.Limit).
-		Offset(pagination.Offset).
+		Scopes(pagination.Scope).
 		Order(order).
 		Find(&comments)
 
@@ -50,7 +53,31 @@ func (r *Repository) Count(where interface{}) (int64, error) {
 }
 
 func (r *Repository) Delete(commentId, userId uint) (int64, error) {
-	result := r.DB.Select("SubComments").Where("user_id = ?", userId).Delete(&Comment{ID: commentId})
+	var rowsAffected int64
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		comment := &Comment{}
+		if err := tx.Select("id", "movie_id").Where("user_id = ?", userId).First(comment, commentId).Error; err != nil {
+			return err
+		}
 
-	return result.RowsAffected, result.Error
+		result := tx.Select("SubComments").Delete(comment)
+		if result.Error != nil {
+			return result.Error
+		}
+		rowsAffected = result.RowsAffected
+
+		return r.UpdateMovieCommentsCount(tx, comment.MovieID)
+	})
+
+	return rowsAffected, err
+}
+
+// UpdateMovieCommentsCount recounts instead of incrementing because deleting
+// a comment also removes its sub comments.
+func (r *Repository) UpdateMovieCommentsCount(tx *gorm.DB, movieId uint) error {
+	return tx.
+		Table("movies").
+		Where("id = ?", movieId).
+		UpdateColumn("comments_count", tx.Model(&Comment{}).Select("COUNT(*)").Where("movie_id = ?", movieId)).
+		Error
 }
@@ -24,7 +24,7 @@ func (h *Handler) Routes(r chi.Router) {
 							With(h.UserHandler.IsAuthorized).
 							Post("/", h.AddCommentHandler)
 						r.
-							With(pagination.SetPaginationContextMiddleware).
+							With(pagination.NewMiddleware(10)).
 							With(h.UserHandler.TryToGetUserId).
 							Get("/", h.GetMultipleSubCommentsHandler)
 						r.
@@ -10,7 +10,7 @@ import (
 type Comment struct {
 	gorm.Model
 	ID               uint
-	Text             string
+	Text             string    `gorm:"index:idx_comments_search,class:FULLTEXT"`
 	Mentions         []Mention `gorm:"-"`
 	MovieID          uint      `gorm:"not null"`
 	Movie            movie.Movie
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/websocket"
 	"gorm.io/gorm"
@@ -74,14 +75,58 @@ func (uc *UseCase) CreateFromPath(filePath, name, path, fileType string) (*File,
 	return uc.FileInteractor.CreateFromPath(filePath, name, path, fileType)
 }
 
-func (uc *UseCase) Get(name string) ([]byte, error) {
-	return uc.FileInteractor.Get(name)
+func (uc *UseCase) GetPublic(name string) (*File, error) {
+	return uc.FileInteractor.GetWhere(map[string]interface{}{
+		"name": name,
+		"type": "public",
+	})
+}
+
+func (uc *UseCase) Read(file *File) ([]byte, error) {
+	return uc.FileInteractor.Read(file)
+}
+
+// GetMovieCode returns the code of the movie the file belongs to, files of
+// movies are kept in movies/{code}/
+func GetMovieCode(file *File) string {
+	parts := strings.Split(filepath.ToSlash(strings.TrimPrefix(file.Path, SaveFolderPrefix)), "/")
+	if len(parts) < 2 || parts[0] != "movies" {
+		return ""
+	}
+
+	return parts[1]
 }
 
 func (uc *UseCase) Stream(file *File, requestRange string) ([]byte, string, int, error) {
 	return uc.FileInteractor.Stream(file, requestRange)
 }
 
+func (uc *UseCase) DownloadToPath(file *File, path, name string) (*os.File, error) {
+	err := os.MkdirAll(path, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpFile, err := os.Create(filepath.Join(path, name))
+	if err != nil {
+		return nil, err
+	}
+	defer tmpFile.Close()
+
+	if err = uc.FileInteractor.Download(file, tmpFile); err != nil {
+		os.Remove(tmpFile.Name())
+		return nil, err
+	}
+
+	return tmpFile, nil
+}
+
+func (uc *UseCase) GetMultipleInPath(path string) ([]File, error) {
+	return uc.FileInteractor.GetWhereMultiple(map[string]interface{}{
+		"path": strings.TrimPrefix(path, SaveFolderPrefix),
+	})
+}
+
 func (uc *UseCase) Delete(name string) error {
 	return uc.FileInteractor.Delete(name)
 }
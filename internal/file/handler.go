@@ -7,24 +7,55 @@ import (
 	"strconv"
 )
 
+// MovieAccess checks the files of a movie against the movie visibility,
+// the movie use case depends on files so it's passed in as an interface.
+type MovieAccess interface {
+	GetFileAccess(userId *uint, code string) (isPublic bool, err error)
+}
+
 type Handler struct {
 	FileUseCase *UseCase
+	MovieAccess MovieAccess
 }
 
-func NewHandler(uc *UseCase) *Handler {
+func NewHandler(uc *UseCase, ma MovieAccess) *Handler {
 	return &Handler{
 		FileUseCase: uc,
+		MovieAccess: ma,
 	}
 }
+
+// GetFile serves a public file. Files of movies are served only to those
+// who may watch the movie and are cached by shared caches only for public
+// movies.
 func (h *Handler) GetFile(w http.ResponseWriter, r *http.Request) {
 	fileName := chi.URLParam(r, "fileName")
+	userId, _ := r.Context().Value("userId").(*uint)
+
+	file, err := h.FileUseCase.GetPublic(fileName)
+	if err != nil {
+		response.RenderError(w, r, http.StatusNotFound, "No such file")
+		return
+	}
+
+	cacheControl := "max-age=604800"
+	if movieCode := GetMovieCode(file); movieCode != "" {
+		isPublic, err := h.MovieAccess.GetFileAccess(userId, movieCode)
+		if err != nil {
+			response.RenderError(w, r, http.StatusNotFound, "No such file")
+			return
+		} else if !isPublic {
+			cacheControl = "private"
+		}
+	}
 
-	buff, err := h.FileUseCase.Get(fileName)
+	buff, err := h.FileUseCase.Read(file)
 	if err != nil {
 		response.RenderError(w, r, http.StatusNotFound, "No such file")
 		return
 	}
 
+	w.Header().Set("Cache-Control", cacheControl)
 	w.Header().Set("Content-Length", strconv.Itoa(len(buff)))
 	w.Write(buff)
 }
@@ -13,8 +13,9 @@ type Interactor interface {
 	Create(file io.ReadSeeker, name, path string, fileType string) (*File, error)
 	CreateMultipart(ctx context.Context, filePath, name, path, fileType string) (*File, error)
 	CreateFromPath(filePath, name, path, fileType string) (*File, error)
-	Get(name string) ([]byte, error)
+	Read(file *File) ([]byte, error)
 	Stream(file *File, requestRange string) ([]byte, string, int, error)
+	Download(file *File, dst io.Writer) error
 	Delete(name string) error
 	DeleteMultiple(names []string) error
 	DeleteAllInPath(path string) error
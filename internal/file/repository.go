@@ -240,15 +240,7 @@ func (fr *Repository) CreateMultipartInternal(ctx context.Context, filePath, nam
 	return savedFile, result.Error
 }
 
-func (fr *Repository) Get(name string) ([]byte, error) {
-	file, err := fr.GetWhere(map[string]interface{}{
-		"name": name,
-		"type": "public",
-	})
-	if err != nil {
-		return nil, errors.WithMessage(err, "File:")
-	}
-
+func (fr *Repository) Read(file *File) ([]byte, error) {
 	switch fr.SaveType {
 	case SaveTypeLocal:
 		return fr.ReadLocal(file)
@@ -278,6 +270,41 @@ func (fr *Repository) ReadInternal(file *File) ([]byte, error) {
 	return io.ReadAll(output.Body)
 }
 
+func (fr *Repository) Download(file *File, dst io.Writer) error {
+	switch fr.SaveType {
+	case SaveTypeLocal:
+		return fr.DownloadLocal(file, dst)
+	case SaveTypeInternal:
+		return fr.DownloadInternal(file, dst)
+	default:
+		return errors.New("File download: file save type is not specified")
+	}
+}
+
+func (fr *Repository) DownloadLocal(file *File, dst io.Writer) error {
+	f, err := os.Open(file.FullPath)
+	if err != nil {
+		return errors.WithMessage(err, "File download local:")
+	}
+	defer f.Close()
+
+	_, err = io.Copy(dst, f)
+
+	return err
+}
+
+func (fr *Repository) DownloadInternal(file *File, dst io.Writer) error {
+	output, err := fr.S3Storage.GetObject(file.Name+file.Extension, filepath.Join(SaveFolderPrefix, file.Path))
+	if err != nil {
+		return errors.WithMessage(err, "File download internal:")
+	}
+	defer output.Body.Close()
+
+	_, err = io.Copy(dst, output.Body)
+
+	return err
+}
+
 func (fr *Repository) Stream(file *File, requestRange string) ([]byte, string, int, error) {
 	switch fr.SaveType {
 	case SaveTypeLocal:
@@ -0,0 +1,62 @@
+package history
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+	"nine-dubz/internal/response"
+	"nine-dubz/internal/user"
+)
+
+type Handler struct {
+	HistoryUseCase *UseCase
+	UserHandler    *user.Handler
+}
+
+func NewHandler(uc *UseCase, uh *user.Handler) *Handler {
+	return &Handler{
+		HistoryUseCase: uc,
+		UserHandler:    uh,
+	}
+}
+
+func (h *Handler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	if err := h.HistoryUseCase.Delete(userId, chi.URLParam(r, "movieCode")); err != nil {
+		response.RenderError(w, r, http.StatusInternalServerError, "Can't delete history")
+		return
+	}
+
+	response.RenderSuccess(w, r, http.StatusOK, "")
+}
+
+func (h *Handler) DeleteAllHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	if err := h.HistoryUseCase.DeleteAll(userId); err != nil {
+		response.RenderError(w, r, http.StatusInternalServerError, "Can't delete history")
+		return
+	}
+
+	response.RenderSuccess(w, r, http.StatusOK, "")
+}
+
+func (h *Handler) GetPauseHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	render.JSON(w, r, &PauseResponse{Paused: h.HistoryUseCase.IsPaused(userId)})
+}
+
+func (h *Handler) SetPauseHandler(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(uint)
+
+		if err := h.HistoryUseCase.SetPaused(userId, paused); err != nil {
+			response.RenderError(w, r, http.StatusInternalServerError, "Can't change history settings")
+			return
+		}
+
+		render.JSON(w, r, &PauseResponse{Paused: paused})
+	}
+}
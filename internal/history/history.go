@@ -0,0 +1,135 @@
+package history
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"nine-dubz/internal/pagination"
+	"time"
+)
+
+// History goes from the last watched movie
+var sortKey = pagination.Key{SortBy: "watched_at", Column: "history_entries.watched_at", IdColumn: "history_entries.id", Desc: true}
+
+const (
+	// Movies watched for less than this are started over
+	minResumePosition = 10
+	// A movie is finished when less than this share of it is left, which
+	// is usually the end credits
+	finishedShare = 0.95
+)
+
+type UseCase struct {
+	HistoryInteractor Interactor
+}
+
+func New(db *gorm.DB) *UseCase {
+	return &UseCase{
+		HistoryInteractor: &Repository{
+			DB: db,
+		},
+	}
+}
+
+// IsFinished reports whether the position is at the end of the movie
+func IsFinished(position, duration int) bool {
+	return duration > 0 && float64(position) >= float64(duration)*finishedShare
+}
+
+// Save records the position the user is at in the movie. Nothing is saved
+// while the user's history is paused.
+func (uc *UseCase) Save(userId, movieId uint, position, duration int) error {
+	if position < 0 {
+		return errors.New("invalid position")
+	}
+	if duration > 0 {
+		position = min(position, duration)
+	}
+
+	if uc.IsPaused(userId) {
+		return nil
+	}
+
+	return uc.HistoryInteractor.Save(&Entry{
+		WatchedAt: time.Now(),
+		UserId:    userId,
+		MovieId:   movieId,
+		Position:  position,
+		Finished:  IsFinished(position, duration),
+	})
+}
+
+// ClampPositions fits the saved positions into the movie duration once it's
+// known or changed by a trim, positions saved before that could be past it.
+func (uc *UseCase) ClampPositions(movieId uint, duration int) error {
+	if duration <= 0 {
+		return nil
+	}
+
+	return uc.HistoryInteractor.ClampPositions(movieId, duration, float64(duration)*finishedShare)
+}
+
+// GetResumePosition returns where the user should continue the movie from,
+// zero to start it over.
+func (uc *UseCase) GetResumePosition(userId, movieId uint) int {
+	entry, err := uc.HistoryInteractor.Get(userId, movieId)
+	if err != nil {
+		return 0
+	}
+
+	return entry.ResumePosition()
+}
+
+func (e *Entry) ResumePosition() int {
+	if e.Finished || e.Position < minResumePosition {
+		return 0
+	}
+
+	return e.Position
+}
+
+// GetMultiple returns the user's history, last watched first. Unfinished
+// limits it to movies the user can continue, movieScope to movies the user
+// can still watch.
+func (uc *UseCase) GetMultiple(userId uint, unfinished bool, movieScope func(db *gorm.DB) *gorm.DB, pagination *pagination.Pagination) ([]Entry, error) {
+	entries, err := uc.HistoryInteractor.GetMultiple(userId, unfinished, movieScope, pagination, pagination.OrderBy(sortKey))
+	if err != nil {
+		return nil, err
+	}
+	entries = entries[:pagination.Trim(entries, func(i int) (interface{}, uint) {
+		return entries[i].WatchedAt, entries[i].ID
+	})]
+
+	if pagination.WithTotal {
+		total, err := uc.HistoryInteractor.Count(userId, unfinished, movieScope)
+		if err != nil {
+			return nil, err
+		}
+		pagination.Total = &total
+	}
+
+	return entries, nil
+}
+
+func (uc *UseCase) Delete(userId uint, movieCode string) error {
+	return uc.HistoryInteractor.Delete(userId, movieCode)
+}
+
+func (uc *UseCase) DeleteAll(userId uint) error {
+	return uc.HistoryInteractor.DeleteAll(userId)
+}
+
+func (uc *UseCase) IsPaused(userId uint) bool {
+	settings, err := uc.HistoryInteractor.GetSettings(userId)
+	if err != nil {
+		return false
+	}
+
+	return settings.Paused
+}
+
+func (uc *UseCase) SetPaused(userId uint, paused bool) error {
+	return uc.HistoryInteractor.SaveSettings(&Settings{
+		UserId: userId,
+		Paused: paused,
+	})
+}
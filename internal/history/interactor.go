@@ -0,0 +1,18 @@
+package history
+
+import (
+	"gorm.io/gorm"
+	"nine-dubz/internal/pagination"
+)
+
+type Interactor interface {
+	Save(entry *Entry) error
+	Get(userId, movieId uint) (*Entry, error)
+	GetMultiple(userId uint, unfinished bool, movieScope func(db *gorm.DB) *gorm.DB, pagination *pagination.Pagination, order string) ([]Entry, error)
+	Count(userId uint, unfinished bool, movieScope func(db *gorm.DB) *gorm.DB) (int64, error)
+	Delete(userId uint, movieCode string) error
+	DeleteAll(userId uint) error
+	ClampPositions(movieId uint, duration int, finishedPosition float64) error
+	GetSettings(userId uint) (*Settings, error)
+	SaveSettings(settings *Settings) error
+}
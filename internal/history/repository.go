@@ -0,0 +1,96 @@
+package history
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"nine-dubz/internal/pagination"
+)
+
+type Repository struct {
+	DB *gorm.DB
+}
+
+// Save creates the user's entry for the movie or moves the existing one
+func (r *Repository) Save(entry *Entry) error {
+	return r.DB.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "movie_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"watched_at", "position", "finished"}),
+		}).
+		Create(entry).
+		Error
+}
+
+func (r *Repository) Get(userId, movieId uint) (*Entry, error) {
+	entry := &Entry{}
+	result := r.DB.Where("user_id = ? AND movie_id = ?", userId, movieId).First(entry)
+
+	return entry, result.Error
+}
+
+func (r *Repository) GetMultiple(userId uint, unfinished bool, movieScope func(db *gorm.DB) *gorm.DB, pagination *pagination.Pagination, order string) ([]Entry, error) {
+	var entries []Entry
+	result := r.DB.
+		Scopes(withMovie, movieScope, whereUser(userId, unfinished)).
+		Scopes(pagination.Scope).
+		Order(order).
+		Find(&entries)
+
+	return entries, result.Error
+}
+
+func (r *Repository) Count(userId uint, unfinished bool, movieScope func(db *gorm.DB) *gorm.DB) (int64, error) {
+	var count int64
+	result := r.DB.Model(&Entry{}).Scopes(withMovie, movieScope, whereUser(userId, unfinished)).Count(&count)
+
+	return count, result.Error
+}
+
+func (r *Repository) Delete(userId uint, movieCode string) error {
+	return r.DB.
+		Where("user_id = ? AND movie_id IN (?)", userId, r.DB.Table("movies").Select("id").Where("code = ?", movieCode)).
+		Delete(&Entry{}).
+		Error
+}
+
+func (r *Repository) DeleteAll(userId uint) error {
+	return r.DB.Where("user_id = ?", userId).Delete(&Entry{}).Error
+}
+
+func (r *Repository) ClampPositions(movieId uint, duration int, finishedPosition float64) error {
+	return r.DB.
+		Model(&Entry{}).
+		Where("movie_id = ? AND position >= ?", movieId, finishedPosition).
+		Updates(map[string]interface{}{
+			"position": gorm.Expr("LEAST(position, ?)", duration),
+			"finished": true,
+		}).
+		Error
+}
+
+func (r *Repository) GetSettings(userId uint) (*Settings, error) {
+	settings := &Settings{}
+	result := r.DB.Where("user_id = ?", userId).First(settings)
+
+	return settings, result.Error
+}
+
+func (r *Repository) SaveSettings(settings *Settings) error {
+	return r.DB.Save(settings).Error
+}
+
+// Unfinished entries are the ones worth resuming
+func whereUser(userId uint, unfinished bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("history_entries.user_id = ?", userId)
+		if unfinished {
+			db = db.Where("history_entries.finished = ? AND history_entries.position >= ?", false, minResumePosition)
+		}
+
+		return db
+	}
+}
+
+func withMovie(db *gorm.DB) *gorm.DB {
+	return db.Joins("JOIN movies ON movies.id = history_entries.movie_id AND movies.deleted_at IS NULL")
+}
@@ -0,0 +1,20 @@
+package history
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+// Routes are mounted by the movie handler at /movie/history, next to the
+// list of the watched movies
+func (h *Handler) Routes(r chi.Router) {
+	r.Group(func(r chi.Router) {
+		r.Use(h.UserHandler.IsAuthorized)
+		r.Delete("/", h.DeleteAllHandler)
+		r.Route("/pause", func(r chi.Router) {
+			r.Get("/", h.GetPauseHandler)
+			r.Post("/", h.SetPauseHandler(true))
+			r.Delete("/", h.SetPauseHandler(false))
+		})
+		r.Delete("/{movieCode}", h.DeleteHandler)
+	})
+}
@@ -0,0 +1,43 @@
+package history
+
+import "time"
+
+// Entry is the user's progress on a movie, one per user and movie. Every
+// heartbeat from the player moves it up in the history.
+type Entry struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	WatchedAt time.Time `gorm:"not null;index"`
+	UserId    uint      `gorm:"not null;uniqueIndex:idx_history_entries_user_movie"`
+	MovieId   uint      `gorm:"not null;uniqueIndex:idx_history_entries_user_movie;index"`
+	Position  int       `gorm:"not null;default:0"`
+	Finished  bool      `gorm:"not null;default:false"`
+}
+
+func (Entry) TableName() string {
+	return "history_entries"
+}
+
+// Settings keeps whether the user paused the history, while it's paused
+// heartbeats are not saved.
+type Settings struct {
+	UserId    uint `gorm:"primarykey;autoIncrement:false"`
+	UpdatedAt time.Time
+	Paused    bool `gorm:"not null;default:false"`
+}
+
+func (Settings) TableName() string {
+	return "history_settings"
+}
+
+// ProgressRequest is the player's heartbeat. Position is where the player
+// is in seconds, Watched is how many seconds it played since the movie was
+// opened.
+type ProgressRequest struct {
+	Position int `json:"position"`
+	Watched  int `json:"watched"`
+}
+
+type PauseResponse struct {
+	Paused bool `json:"paused"`
+}
@@ -1,5 +1,10 @@
 package mail
 
+import "time"
+
 type Interactor interface {
-	SendMail(name, from, to, subject, content string) error
+	Create(message *Message) error
+	GetDue(now time.Time, limit int) ([]Message, error)
+	Claim(message *Message, until time.Time) (bool, error)
+	Save(message *Message) error
 }
@@ -1,16 +1,77 @@
 package mail
 
 import (
+	"gorm.io/gorm"
 	"log"
 	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// Attempts to send a message before it's dead
+	maxAttempts = 8
+	// Failed messages wait twice as long after every attempt, from a minute
+	// up to six hours
+	retryDelay    = time.Minute
+	maxRetryDelay = 6 * time.Hour
+	// Messages sent in one pass over the outbox
+	outboxBatch = 50
+	// A claimed message is left to other workers after this long
+	claimTimeout = 10 * time.Minute
+
+	maxErrorLength = 1000
+
+	senderName = "Nine Dubz"
 )
 
 type UseCase struct {
 	MailInteractor   Interactor
+	Transport        Transport
 	DefaultEmailFrom string
+	Templates        map[string]*Template
+	Logo             []byte
+	// Wake makes the outbox worker send messages queued just now
+	Wake chan struct{}
+}
+
+func New(db *gorm.DB) *UseCase {
+	emailFrom, ok := os.LookupEnv("MAIL_EMAIL")
+	if !ok {
+		log.Println("No MAIL_EMAIL environment variable")
+	}
+
+	templatePath := GetTemplatePath()
+	logo, err := os.ReadFile(filepath.Join(templatePath, "logo.png"))
+	if err != nil {
+		log.Println("mail: no logo:", err)
+	}
+	templates, err := ParseTemplates(templatePath, logo)
+	if err != nil {
+		log.Println("mail: templates:", err)
+	}
+
+	return &UseCase{
+		MailInteractor: &Repository{
+			DB: db,
+		},
+		Transport:        NewTransport(),
+		DefaultEmailFrom: emailFrom,
+		Templates:        templates,
+		Logo:             logo,
+		Wake:             make(chan struct{}, 1),
+	}
 }
 
-func New() *UseCase {
+// NewTransport picks the transport by MAIL_TRANSPORT: smtp by default,
+// file to write messages into MAIL_FILE_PATH or the log
+func NewTransport() Transport {
+	transport, _ := os.LookupEnv("MAIL_TRANSPORT")
+	if transport == "file" || transport == "log" {
+		path, _ := os.LookupEnv("MAIL_FILE_PATH")
+		return &FileTransport{Path: path}
+	}
+
 	host, ok := os.LookupEnv("MAIL_HOST")
 	if !ok {
 		log.Println("No MAIL_HOST environment variable")
@@ -19,10 +80,6 @@ func New() *UseCase {
 	if !ok {
 		log.Println("No MAIL_PORT environment variable")
 	}
-	emailFrom, ok := os.LookupEnv("MAIL_EMAIL")
-	if !ok {
-		log.Println("No MAIL_EMAIL environment variable")
-	}
 	login, ok := os.LookupEnv("MAIL_LOGIN")
 	if !ok {
 		log.Println("No MAIL_LOGIN environment variable")
@@ -32,17 +89,117 @@ func New() *UseCase {
 		log.Println("No MAIL_PASSWORD environment variable")
 	}
 
-	return &UseCase{
-		MailInteractor: &Repository{
-			Host:     host,
-			Port:     port,
-			Username: login,
-			Password: password,
-		},
-		DefaultEmailFrom: emailFrom,
+	security, ok := os.LookupEnv("MAIL_SECURITY")
+	if !ok {
+		security = SecurityStartTls
+		if port == "465" {
+			security = SecurityTls
+		}
+	}
+
+	return &SmtpTransport{
+		Host:     host,
+		Port:     port,
+		Username: login,
+		Password: password,
+		Security: security,
 	}
 }
 
-func (uc *UseCase) SendMail(to, subject, content string) error {
-	return uc.MailInteractor.SendMail("Nine Dubz", uc.DefaultEmailFrom, to, subject, content)
+// Queue puts the message into the outbox, it's sent by RunOutbox
+func (uc *UseCase) Queue(to string, message *Message) error {
+	message.To = to
+	message.Status = StatusQueued
+	message.SendAt = time.Now()
+
+	if err := uc.MailInteractor.Create(message); err != nil {
+		return err
+	}
+
+	select {
+	case uc.Wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (uc *UseCase) RunOutbox(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := uc.SendQueued(); err != nil {
+			log.Println("mail: outbox:", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-uc.Wake:
+		}
+	}
+}
+
+// SendQueued sends the due messages of the outbox. Every message is claimed
+// first, so app instances sharing the outbox don't send it twice. A failed
+// message is put off for longer after every attempt, the last failure makes
+// it dead.
+func (uc *UseCase) SendQueued() error {
+	messages, err := uc.MailInteractor.GetDue(time.Now(), outboxBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		claimed, err := uc.MailInteractor.Claim(&message, time.Now().Add(claimTimeout))
+		if err != nil {
+			return err
+		} else if !claimed {
+			continue
+		}
+
+		if err = uc.send(&message); err != nil {
+			message.Error = err.Error()
+			if len(message.Error) > maxErrorLength {
+				message.Error = message.Error[:maxErrorLength]
+			}
+
+			if message.Attempts >= maxAttempts {
+				message.Status = StatusDead
+				log.Printf("mail: message %d to %s is dead: %s", message.ID, message.To, message.Error)
+			} else {
+				message.SendAt = time.Now().Add(RetryDelay(message.Attempts))
+			}
+		} else {
+			now := time.Now()
+			message.Status = StatusSent
+			message.SentAt = &now
+			message.Error = ""
+		}
+
+		if err = uc.MailInteractor.Save(&message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RetryDelay is how long a message waits after the failed attempt
+func RetryDelay(attempts int) time.Duration {
+	delay := retryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
+
+func (uc *UseCase) send(message *Message) error {
+	raw, err := NewMime(senderName, uc.DefaultEmailFrom, message, uc.Logo)
+	if err != nil {
+		return err
+	}
+
+	return uc.Transport.Send(uc.DefaultEmailFrom, message.To, raw)
 }
@@ -0,0 +1,209 @@
+package mail
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type sentMessage struct {
+	From    string
+	To      string
+	Message []byte
+}
+
+// memoryTransport keeps the messages, err makes every send fail
+type memoryTransport struct {
+	messages []sentMessage
+	err      error
+}
+
+func (t *memoryTransport) Send(from, to string, message []byte) error {
+	if t.err != nil {
+		return t.err
+	}
+	t.messages = append(t.messages, sentMessage{From: from, To: to, Message: message})
+
+	return nil
+}
+
+// memoryOutbox claims messages the way Repository does
+type memoryOutbox struct {
+	messages []Message
+}
+
+func (o *memoryOutbox) Create(message *Message) error {
+	message.ID = uint(len(o.messages) + 1)
+	o.messages = append(o.messages, *message)
+
+	return nil
+}
+
+func (o *memoryOutbox) GetDue(now time.Time, limit int) ([]Message, error) {
+	var messages []Message
+	for _, message := range o.messages {
+		if message.Status == StatusQueued && !message.SendAt.After(now) && len(messages) < limit {
+			messages = append(messages, message)
+		}
+	}
+
+	return messages, nil
+}
+
+func (o *memoryOutbox) Claim(message *Message, until time.Time) (bool, error) {
+	stored := &o.messages[message.ID-1]
+	if stored.Status != StatusQueued || stored.Attempts != message.Attempts {
+		return false, nil
+	}
+
+	stored.Attempts++
+	stored.SendAt = until
+	message.Attempts = stored.Attempts
+	message.SendAt = until
+
+	return true, nil
+}
+
+func (o *memoryOutbox) Save(message *Message) error {
+	o.messages[message.ID-1] = *message
+
+	return nil
+}
+
+func newOutboxUseCase(transport *memoryTransport) (*UseCase, *memoryOutbox) {
+	outbox := &memoryOutbox{}
+
+	return &UseCase{
+		MailInteractor:   outbox,
+		Transport:        transport,
+		DefaultEmailFrom: "noreply@example.com",
+		Wake:             make(chan struct{}, 1),
+	}, outbox
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		4:  8 * time.Minute,
+		9:  256 * time.Minute,
+		10: maxRetryDelay,
+		50: maxRetryDelay,
+	} {
+		if got := RetryDelay(attempts); got != want {
+			t.Errorf("RetryDelay(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestSendQueuedFailure(t *testing.T) {
+	transport := &memoryTransport{err: errors.New("connection refused")}
+	uc, outbox := newOutboxUseCase(transport)
+
+	if err := uc.Queue("user@example.com", &Message{Subject: "Hi", Content: "Hi\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		before := time.Now()
+		if err := uc.SendQueued(); err != nil {
+			t.Fatal(err)
+		}
+
+		message := outbox.messages[0]
+		if message.Attempts != attempt {
+			t.Fatalf("attempts = %d, want %d", message.Attempts, attempt)
+		}
+		if message.Error != "connection refused" {
+			t.Errorf("error = %q", message.Error)
+		}
+
+		if attempt < maxAttempts {
+			if message.Status != StatusQueued {
+				t.Fatalf("attempt %d: status = %q, want %q", attempt, message.Status, StatusQueued)
+			}
+			if delay := message.SendAt.Sub(before); delay < RetryDelay(attempt) || delay > RetryDelay(attempt)+time.Minute {
+				t.Errorf("attempt %d: retried after %s, want %s", attempt, delay, RetryDelay(attempt))
+			}
+
+			// Nothing is due before the delay
+			if err := uc.SendQueued(); err != nil {
+				t.Fatal(err)
+			}
+			if outbox.messages[0].Attempts != attempt {
+				t.Fatalf("attempt %d: message was retried before its delay", attempt)
+			}
+
+			outbox.messages[0].SendAt = time.Now().Add(-time.Second)
+		} else if message.Status != StatusDead {
+			t.Errorf("status = %q, want %q", message.Status, StatusDead)
+		}
+	}
+
+	// A dead message is never sent again
+	transport.err = nil
+	outbox.messages[0].SendAt = time.Now().Add(-time.Second)
+	if err := uc.SendQueued(); err != nil {
+		t.Fatal(err)
+	}
+	if len(transport.messages) != 0 || outbox.messages[0].Attempts != maxAttempts {
+		t.Errorf("dead message was sent again")
+	}
+}
+
+func TestSendQueuedSuccess(t *testing.T) {
+	transport := &memoryTransport{}
+	uc, outbox := newOutboxUseCase(transport)
+
+	if err := uc.Queue("user@example.com", &Message{Subject: "Hi", Content: "Hi\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.SendQueued(); err != nil {
+		t.Fatal(err)
+	}
+
+	message := outbox.messages[0]
+	if message.Status != StatusSent || message.SentAt == nil || message.Attempts != 1 {
+		t.Errorf("message = %+v", message)
+	}
+	if len(transport.messages) != 1 || transport.messages[0].To != "user@example.com" {
+		t.Fatalf("sent = %+v", transport.messages)
+	}
+
+	if err := uc.SendQueued(); err != nil {
+		t.Fatal(err)
+	}
+	if len(transport.messages) != 1 {
+		t.Errorf("sent message was sent again")
+	}
+}
+
+func TestSendQueuedClaimed(t *testing.T) {
+	transport := &memoryTransport{}
+	uc, outbox := newOutboxUseCase(transport)
+
+	if err := uc.Queue("user@example.com", &Message{Subject: "Hi", Content: "Hi\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another instance read the same batch and claimed the message first
+	due, err := outbox.GetDue(time.Now(), outboxBatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := due[0]
+	if claimed, _ := outbox.Claim(&due[0], time.Now().Add(-time.Second)); !claimed {
+		t.Fatal("first claim failed")
+	}
+	if claimed, _ := outbox.Claim(&stale, time.Now().Add(-time.Second)); claimed {
+		t.Fatal("message was claimed twice")
+	}
+
+	// The claim is past its timeout, so this instance takes the message over
+	if err = uc.SendQueued(); err != nil {
+		t.Fatal(err)
+	}
+	if len(transport.messages) != 1 || outbox.messages[0].Attempts != 2 {
+		t.Errorf("sent = %d, attempts = %d", len(transport.messages), outbox.messages[0].Attempts)
+	}
+}
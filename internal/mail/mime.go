@@ -0,0 +1,171 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// LogoCid is how the HTML part refers to the inline logo
+const LogoCid = "logo"
+
+// NewMime builds the message as sent over SMTP. The text and HTML parts go
+// as multipart/alternative, with the logo they are wrapped in
+// multipart/related. Headers are encoded by RFC 2047, bodies are UTF-8.
+func NewMime(name, from string, message *Message, logo []byte) ([]byte, error) {
+	buff := &bytes.Buffer{}
+
+	fromAddress := mail.Address{Name: name, Address: from}
+	writeHeader(buff, "From", fromAddress.String())
+	writeHeader(buff, "To", message.To)
+	writeHeader(buff, "Subject", mime.QEncoding.Encode("utf-8", message.Subject))
+	writeHeader(buff, "Date", time.Now().Format(time.RFC1123Z))
+	writeHeader(buff, "Message-ID", newMessageId(from))
+	writeHeader(buff, "MIME-Version", "1.0")
+	if message.Unsubscribe != "" {
+		writeHeader(buff, "List-Unsubscribe", "<"+message.Unsubscribe+">")
+		writeHeader(buff, "List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+
+	if message.Html == "" {
+		writeHeader(buff, "Content-Type", "text/plain; charset=utf-8")
+		writeHeader(buff, "Content-Transfer-Encoding", "quoted-printable")
+		buff.WriteString("\r\n")
+
+		if err := writeQuotedPrintable(buff, message.Content); err != nil {
+			return nil, err
+		}
+
+		return buff.Bytes(), nil
+	}
+
+	// Only the boundary of this writer is used, the parts are written by
+	// writeAlternative
+	alternativeBoundary := multipart.NewWriter(io.Discard).Boundary()
+
+	if len(logo) == 0 {
+		writeHeader(buff, "Content-Type", "multipart/alternative; boundary="+alternativeBoundary)
+		buff.WriteString("\r\n")
+
+		if err := writeAlternative(buff, alternativeBoundary, message); err != nil {
+			return nil, err
+		}
+
+		return buff.Bytes(), nil
+	}
+
+	related := multipart.NewWriter(buff)
+	writeHeader(buff, "Content-Type", "multipart/related; boundary="+related.Boundary())
+	buff.WriteString("\r\n")
+
+	part, err := related.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + alternativeBoundary},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = writeAlternative(part, alternativeBoundary, message); err != nil {
+		return nil, err
+	}
+
+	part, err = related.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"image/png"},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Id":                {"<" + LogoCid + ">"},
+		"Content-Disposition":       {"inline; filename=\"logo.png\""},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = writeBase64(part, logo); err != nil {
+		return nil, err
+	}
+
+	if err = related.Close(); err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
+
+// writeAlternative writes the text and HTML parts, the last one is the one
+// mail clients prefer
+func writeAlternative(w io.Writer, boundary string, message *Message) error {
+	alternative := multipart.NewWriter(w)
+	if err := alternative.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", message.Content},
+		{"text/html; charset=utf-8", message.Html},
+	}
+	for _, part := range parts {
+		partWriter, err := alternative.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return err
+		}
+		if err = writeQuotedPrintable(partWriter, part.content); err != nil {
+			return err
+		}
+	}
+
+	return alternative.Close()
+}
+
+func writeHeader(buff *bytes.Buffer, key, value string) {
+	// Header injection through user supplied values
+	value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+	buff.WriteString(key + ": " + value + "\r\n")
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+
+	return qp.Close()
+}
+
+// writeBase64 writes the content in lines of 76 characters
+func writeBase64(w io.Writer, content []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 0 {
+		line := encoded[:min(76, len(encoded))]
+		encoded = encoded[len(line):]
+		if _, err := io.WriteString(w, line+"\r\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newMessageId(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at != -1 {
+		domain = from[at+1:]
+	}
+
+	random := make([]byte, 16)
+	rand.Read(random)
+
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random), domain)
+}
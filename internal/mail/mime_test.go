@@ -0,0 +1,211 @@
+package mail
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readPart(t *testing.T, part *multipart.Part) string {
+	t.Helper()
+
+	content, err := io.ReadAll(part)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(content)
+}
+
+func TestNewMime(t *testing.T) {
+	logo := []byte("png")
+	message := &Message{
+		To:          "user@example.com",
+		Subject:     "Подтвердите регистрацию",
+		Content:     "Привет!\n",
+		Html:        "<p>Привет!</p>",
+		Unsubscribe: "https://example.com/unsubscribe",
+	}
+
+	raw, err := NewMime("Nine Dubz", "noreply@example.com", message, logo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, err := (&mime.WordDecoder{}).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != message.Subject {
+		t.Errorf("subject = %q, want %q", subject, message.Subject)
+	}
+	if parsed.Header.Get("Subject") == message.Subject {
+		t.Error("subject is not encoded")
+	}
+	if got := parsed.Header.Get("List-Unsubscribe"); got != "<"+message.Unsubscribe+">" {
+		t.Errorf("List-Unsubscribe = %q", got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/related" {
+		t.Fatalf("content type = %q, want multipart/related", mediaType)
+	}
+
+	related := multipart.NewReader(parsed.Body, params["boundary"])
+
+	alternativePart, err := related.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err = mime.ParseMediaType(alternativePart.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("first related part = %q, want multipart/alternative", mediaType)
+	}
+
+	alternative := multipart.NewReader(alternativePart, params["boundary"])
+	for _, want := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", message.Content},
+		{"text/html; charset=utf-8", message.Html},
+	} {
+		part, err := alternative.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := part.Header.Get("Content-Type"); got != want.contentType {
+			t.Errorf("alternative part = %q, want %q", got, want.contentType)
+		}
+		// The reader decodes quoted-printable itself
+		if got := strings.ReplaceAll(readPart(t, part), "\r\n", "\n"); got != want.content {
+			t.Errorf("%s content = %q, want %q", want.contentType, got, want.content)
+		}
+	}
+	if _, err = alternative.NextPart(); err != io.EOF {
+		t.Errorf("extra alternative part: %v", err)
+	}
+
+	logoPart, err := related.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := logoPart.Header.Get("Content-Id"); got != "<"+LogoCid+">" {
+		t.Errorf("logo Content-Id = %q", got)
+	}
+	if got := logoPart.Header.Get("Content-Type"); got != "image/png" {
+		t.Errorf("logo Content-Type = %q", got)
+	}
+	if _, err = related.NextPart(); err != io.EOF {
+		t.Errorf("extra related part: %v", err)
+	}
+}
+
+func TestNewMimeText(t *testing.T) {
+	raw, err := NewMime("Nine Dubz", "noreply@example.com", &Message{To: "user@example.com", Subject: "Hi", Content: "Hi\n"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.Header.Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("content type = %q", got)
+	}
+}
+
+func writeTemplate(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	path := t.TempDir()
+	writeTemplate(t, filepath.Join(path, "layout.html"), `<title>{{subject}}</title>{{if logo}}<img src="{{logoSrc}}">{{end}}{{template "content" .}}`)
+	writeTemplate(t, filepath.Join(path, "ru", "hello.txt"), `{{define "subject"}}Привет, {{.Name}}{{end}}Привет, {{.Name}}!`)
+	writeTemplate(t, filepath.Join(path, "ru", "hello.html"), `{{define "content"}}<p>Привет, {{.Name}}!</p>{{end}}`)
+	writeTemplate(t, filepath.Join(path, "eng", "hello.txt"), `{{define "subject"}}Hi, {{.Name}}{{end}}Hi, {{.Name}}!`)
+
+	logo := []byte("png")
+	templates, err := ParseTemplates(path, logo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc := &UseCase{Templates: templates, Logo: logo}
+	data := map[string]string{"Name": "<b>"}
+
+	message, err := uc.Render("hello", "eng", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Subject != "Hi, <b>" || message.Content != "Hi, <b>!\n" || message.Html != "" {
+		t.Errorf("eng message = %+v", message)
+	}
+
+	// Unknown languages and anything looking like a path fall back to ru
+	for _, languageCode := range []string{"de", "", "../ru"} {
+		message, err = uc.Render("hello", languageCode, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if message.Subject != "Привет, <b>" {
+			t.Errorf("%q subject = %q", languageCode, message.Subject)
+		}
+	}
+
+	wantHtml := `<title>Привет, &lt;b&gt;</title><img src="cid:logo"><p>Привет, &lt;b&gt;!</p>`
+	if message.Html != wantHtml {
+		t.Errorf("html = %q, want %q", message.Html, wantHtml)
+	}
+
+	if _, err = uc.Render("missing", "ru", data); err == nil {
+		t.Error("missing template rendered")
+	}
+}
+
+func TestShippedTemplates(t *testing.T) {
+	templates, err := ParseTemplates(filepath.Join("..", "..", "templates", "mail"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"ru/registration", "eng/registration", "ru/notifications", "eng/notifications"} {
+		if templates[key] == nil || templates[key].Html == nil {
+			t.Errorf("%s is missing", key)
+		}
+	}
+
+	uc := &UseCase{Templates: templates}
+	message, err := uc.Render("registration", "eng", map[string]string{"UserName": "user", "Link": "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(message.Html, "https://example.com") {
+		t.Errorf("registration html has no link: %s", message.Html)
+	}
+}
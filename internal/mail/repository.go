@@ -1,32 +1,48 @@
 package mail
 
 import (
-	"fmt"
-	"net/smtp"
+	"gorm.io/gorm"
+	"time"
 )
 
 type Repository struct {
-	Host     string
-	Port     string
-	Username string
-	Password string
+	DB *gorm.DB
 }
 
-func (mr *Repository) SendMail(name, from, to, subject, content string) error {
-	auth := smtp.PlainAuth("", mr.Username, mr.Password, mr.Host)
+func (r *Repository) Create(message *Message) error {
+	return r.DB.Create(message).Error
+}
+
+// GetDue returns queued messages whose time has come, oldest first
+func (r *Repository) GetDue(now time.Time, limit int) ([]Message, error) {
+	var messages []Message
+	result := r.DB.
+		Where("status = ? AND send_at <= ?", StatusQueued, now).
+		Order("send_at, id").
+		Limit(limit).
+		Find(&messages)
 
-	msg := fmt.Sprintf(
-		"To: %s\r\n"+
-			"From: %s <%s>\r\n"+
-			"Subject: %s\r\n"+
-			"\r\n"+
-			"%s\r\n",
-		to, name, from, subject, content)
+	return messages, result.Error
+}
 
-	err := smtp.SendMail(mr.Host+":"+mr.Port, auth, from, []string{to}, []byte(msg))
-	if err != nil {
-		return err
+// Claim counts the attempt and puts the message off until the given time,
+// unless another worker claimed it first. A worker that stops while sending
+// leaves the message to be retried after that time.
+func (r *Repository) Claim(message *Message, until time.Time) (bool, error) {
+	result := r.DB.
+		Model(&Message{}).
+		Where("id = ? AND status = ? AND attempts = ?", message.ID, StatusQueued, message.Attempts).
+		Updates(map[string]interface{}{"attempts": message.Attempts + 1, "send_at": until})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
 	}
 
-	return nil
+	message.Attempts++
+	message.SendAt = until
+
+	return true, nil
+}
+
+func (r *Repository) Save(message *Message) error {
+	return r.DB.Save(message).Error
 }
@@ -0,0 +1,34 @@
+package mail
+
+import "time"
+
+const (
+	StatusQueued = "queued"
+	StatusSent   = "sent"
+	// StatusDead is a message that ran out of attempts, it stays in the
+	// outbox with the last error until someone looks into it
+	StatusDead = "dead"
+)
+
+// Message is a mail in the outbox. Failed messages are retried until they
+// run out of attempts. Content is the plain text part, Html is the HTML
+// alternative of it.
+type Message struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	To        string `gorm:"size:255;not null"`
+	Subject   string `gorm:"size:255;not null"`
+	Content   string `gorm:"type:text;not null"`
+	Html      string `gorm:"type:mediumtext"`
+	// Unsubscribe is the link for the List-Unsubscribe header
+	Unsubscribe string    `gorm:"size:500"`
+	Status      string    `gorm:"size:10;not null;default:'queued';index:idx_mail_outbox_status_send_at,priority:1"`
+	Attempts    int       `gorm:"not null;default:0"`
+	SendAt      time.Time `gorm:"not null;index:idx_mail_outbox_status_send_at,priority:2"`
+	SentAt      *time.Time
+	Error       string `gorm:"size:1000"`
+}
+
+func (Message) TableName() string {
+	return "mail_outbox"
+}
@@ -0,0 +1,130 @@
+package mail
+
+import (
+	"bytes"
+	"errors"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Templates are looked up in the user's language directory first and in
+// this one when there is no such language
+const defaultLanguage = "ru"
+
+// Template is a parsed mail of one language. The HTML template is never
+// executed itself, every render executes a clone of it.
+type Template struct {
+	Text *texttemplate.Template
+	Html *htmltemplate.Template
+}
+
+func GetTemplatePath() string {
+	path, ok := os.LookupEnv("MAIL_TEMPLATE_PATH")
+	if !ok {
+		return filepath.Join("templates", "mail")
+	}
+
+	return path
+}
+
+// ParseTemplates reads the mails of every language directory in the path.
+// Every mail is {language}/{name}.txt, defining the "subject" and the text
+// part, and optional {language}/{name}.html, defining the "content" of
+// layout.html. Templates are keyed by "{language}/{name}".
+func ParseTemplates(path string, logo []byte) (map[string]*Template, error) {
+	languages, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*Template)
+	for _, language := range languages {
+		if !language.IsDir() {
+			continue
+		}
+
+		textPaths, err := filepath.Glob(filepath.Join(path, language.Name(), "*.txt"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, textPath := range textPaths {
+			name := strings.TrimSuffix(filepath.Base(textPath), ".txt")
+
+			template := &Template{}
+			template.Text, err = texttemplate.ParseFiles(textPath)
+			if err != nil {
+				return nil, err
+			}
+
+			htmlPath := filepath.Join(path, language.Name(), name+".html")
+			if _, err = os.Stat(htmlPath); err == nil {
+				template.Html, err = htmltemplate.
+					New("layout.html").
+					Funcs(htmltemplate.FuncMap{
+						// Replaced by the subject of the rendered message
+						"subject": func() string { return "" },
+						"logo":    func() bool { return len(logo) > 0 },
+						"logoSrc": func() htmltemplate.URL { return htmltemplate.URL("cid:" + LogoCid) },
+					}).
+					ParseFiles(filepath.Join(path, "layout.html"), htmlPath)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			templates[language.Name()+"/"+name] = template
+		}
+	}
+
+	return templates, nil
+}
+
+// Render makes a message from the templates of the language, falling back
+// to the default language
+func (uc *UseCase) Render(name, languageCode string, data interface{}) (*Message, error) {
+	template, ok := uc.Templates[languageCode+"/"+name]
+	if !ok {
+		template, ok = uc.Templates[defaultLanguage+"/"+name]
+	}
+	if !ok {
+		return nil, errors.New("mail: no template " + name)
+	}
+
+	subject := &bytes.Buffer{}
+	if err := template.Text.ExecuteTemplate(subject, "subject", data); err != nil {
+		return nil, err
+	}
+	content := &bytes.Buffer{}
+	if err := template.Text.Execute(content, data); err != nil {
+		return nil, err
+	}
+
+	message := &Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Content: strings.TrimSpace(content.String()) + "\n",
+	}
+
+	if template.Html == nil {
+		return message, nil
+	}
+
+	htmlTemplate, err := template.Html.Clone()
+	if err != nil {
+		return nil, err
+	}
+	htmlTemplate.Funcs(htmltemplate.FuncMap{
+		"subject": func() string { return message.Subject },
+	})
+
+	html := &bytes.Buffer{}
+	if err = htmlTemplate.Execute(html, data); err != nil {
+		return nil, err
+	}
+	message.Html = html.String()
+
+	return message, nil
+}
@@ -0,0 +1,117 @@
+package mail
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// SecurityStartTls upgrades the connection after connecting, usually on
+	// port 587
+	SecurityStartTls = "starttls"
+	// SecurityTls connects over TLS right away, usually on port 465
+	SecurityTls = "tls"
+	// SecurityNone is for local SMTP stand-ins only
+	SecurityNone = "none"
+
+	dialTimeout = 30 * time.Second
+)
+
+// Transport delivers a built MIME message
+type Transport interface {
+	Send(from, to string, message []byte) error
+}
+
+type SmtpTransport struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	Security string
+}
+
+func (t *SmtpTransport) Send(from, to string, message []byte) error {
+	address := net.JoinHostPort(t.Host, t.Port)
+	tlsConfig := &tls.Config{ServerName: t.Host}
+
+	var conn net.Conn
+	var err error
+	if t.Security == SecurityTls {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", address, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", address, dialTimeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, t.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if t.Security == SecurityStartTls {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("mail: server doesn't support STARTTLS")
+		}
+		if err = client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if t.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", t.Username, t.Password, t.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(from); err != nil {
+		return err
+	}
+	if err = client.Rcpt(to); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(message); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// FileTransport writes every message into an .eml file in the directory,
+// without a directory the messages go to the log
+type FileTransport struct {
+	Path string
+}
+
+func (t *FileTransport) Send(from, to string, message []byte) error {
+	if t.Path == "" {
+		log.Printf("mail: from %s to %s:\n%s", from, to, message)
+		return nil
+	}
+
+	if err := os.MkdirAll(t.Path, 0755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), filepath.Base(to))
+
+	return os.WriteFile(filepath.Join(t.Path, name), message, 0644)
+}
@@ -0,0 +1,127 @@
+package mail
+
+import (
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// smtpSession is what the test server received
+type smtpSession struct {
+	Commands []string
+	Data     string
+}
+
+// startSmtpServer accepts one connection and answers like a plain SMTP
+// server without extensions
+func startSmtpServer(t *testing.T) (string, string, <-chan smtpSession) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		session := smtpSession{}
+		defer func() { sessions <- session }()
+
+		text := textproto.NewConn(conn)
+		text.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			session.Commands = append(session.Commands, line)
+
+			command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch command {
+			case "EHLO", "HELO":
+				text.PrintfLine("250 localhost")
+			case "MAIL", "RCPT":
+				text.PrintfLine("250 OK")
+			case "DATA":
+				text.PrintfLine("354 Go ahead")
+				data, err := text.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				session.Data = string(data)
+				text.PrintfLine("250 Queued")
+			case "QUIT":
+				text.PrintfLine("221 Bye")
+				return
+			default:
+				text.PrintfLine("502 Not implemented")
+			}
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return host, port, sessions
+}
+
+func TestSmtpTransportSend(t *testing.T) {
+	host, port, sessions := startSmtpServer(t)
+
+	transport := &SmtpTransport{Host: host, Port: port, Security: SecurityNone}
+	message := "Subject: Hi\r\n\r\nHi\r\n.leading dot\r\n"
+	if err := transport.Send("noreply@example.com", "user@example.com", []byte(message)); err != nil {
+		t.Fatal(err)
+	}
+
+	session := <-sessions
+	var commands []string
+	for _, command := range session.Commands {
+		if !strings.HasPrefix(command, "EHLO") {
+			commands = append(commands, command)
+		}
+	}
+	want := []string{
+		"MAIL FROM:<noreply@example.com>",
+		"RCPT TO:<user@example.com>",
+		"DATA",
+		"QUIT",
+	}
+	if strings.Join(commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands = %q, want %q", commands, want)
+	}
+
+	// The dot is unstuffed and lines end with CRLF on the wire, textproto
+	// turns them into LF
+	if wantData := "Subject: Hi\n\nHi\n.leading dot\n"; session.Data != wantData {
+		t.Errorf("data = %q, want %q", session.Data, wantData)
+	}
+}
+
+func TestSmtpTransportStartTlsRequired(t *testing.T) {
+	host, port, sessions := startSmtpServer(t)
+
+	transport := &SmtpTransport{Host: host, Port: port, Security: SecurityStartTls}
+	err := transport.Send("noreply@example.com", "user@example.com", []byte("Subject: Hi\r\n\r\nHi\r\n"))
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("error = %v, want no STARTTLS support", err)
+	}
+
+	// Nothing is sent over the plain connection
+	session := <-sessions
+	for _, command := range session.Commands {
+		if strings.HasPrefix(command, "MAIL") || strings.HasPrefix(command, "DATA") {
+			t.Errorf("sent %q without TLS", command)
+		}
+	}
+}
@@ -6,17 +6,22 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
 	"net/http"
+	"nine-dubz/internal/chapter"
 	"nine-dubz/internal/file"
+	"nine-dubz/internal/history"
 	"nine-dubz/internal/pagination"
 	"nine-dubz/internal/response"
 	"nine-dubz/internal/sorting"
 	"nine-dubz/internal/token"
 	"nine-dubz/internal/user"
+	"nine-dubz/internal/view"
 	"nine-dubz/pkg/language"
 	"nine-dubz/pkg/tokenauthorize"
 	"nine-dubz/pkg/userip"
 	"strconv"
+	"time"
 )
 
 type Handler struct {
@@ -25,15 +30,28 @@ type Handler struct {
 	FileUseCase    *file.UseCase
 	TokenAuthorize *tokenauthorize.TokenAuthorize
 	TokenUseCase   *token.UseCase
+	UserIp         *userip.Resolver
+	HistoryHandler *history.Handler
 }
 
-func NewHandler(uc *UseCase, uh *user.Handler, fuc *file.UseCase, ta *tokenauthorize.TokenAuthorize, tuc *token.UseCase) *Handler {
+func NewHandler(uc *UseCase, uh *user.Handler, fuc *file.UseCase, ta *tokenauthorize.TokenAuthorize, tuc *token.UseCase, uip *userip.Resolver, hh *history.Handler) *Handler {
 	return &Handler{
 		MovieUseCase:   uc,
 		UserHandler:    uh,
 		FileUseCase:    fuc,
 		TokenAuthorize: ta,
 		TokenUseCase:   tuc,
+		UserIp:         uip,
+		HistoryHandler: hh,
+	}
+}
+
+func (h *Handler) getClient(r *http.Request) view.Client {
+	userIp, _ := h.UserIp.GetIP(r)
+
+	return view.Client{
+		IP:        userIp,
+		UserAgent: r.UserAgent(),
 	}
 }
 
@@ -163,7 +181,36 @@ func (h *Handler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
 
 	categoryId, err := strconv.ParseUint(r.PostForm.Get("category"), 10, 32)
 	if err == nil {
-		movieUpdateRequest.Category.ID = uint(categoryId)
+		movieUpdateRequest.CategoryId = uint(categoryId)
+	}
+
+	if chaptersJson := r.PostForm.Get("chapters"); chaptersJson != "" {
+		chapters := &[]chapter.SetRequest{}
+		if err = json.Unmarshal([]byte(chaptersJson), chapters); err != nil {
+			response.RenderError(w, r, http.StatusBadRequest, "Can't parse chapters")
+			return
+		}
+		movieUpdateRequest.Chapters = chapters
+	}
+
+	if tagsJson := r.PostForm.Get("tags"); tagsJson != "" {
+		tags := &[]string{}
+		if err = json.Unmarshal([]byte(tagsJson), tags); err != nil {
+			response.RenderError(w, r, http.StatusBadRequest, "Can't parse tags")
+			return
+		}
+		movieUpdateRequest.Tags = tags
+	}
+
+	movieUpdateRequest.Visibility = r.PostForm.Get("visibility")
+	movieUpdateRequest.Language = r.PostForm.Get("language")
+	if sharedWithJson := r.PostForm.Get("sharedWith"); sharedWithJson != "" {
+		sharedWith := &[]string{}
+		if err = json.Unmarshal([]byte(sharedWithJson), sharedWith); err != nil {
+			response.RenderError(w, r, http.StatusBadRequest, "Can't parse shared users")
+			return
+		}
+		movieUpdateRequest.SharedWith = sharedWith
 	}
 
 	isPublished, err := strconv.ParseBool(r.PostForm.Get("isPublished"))
@@ -194,12 +241,45 @@ func (h *Handler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
 	}{true})
 }
 
+func (h *Handler) TrimHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	trimRequest := &TrimRequest{}
+	if err := json.NewDecoder(r.Body).Decode(trimRequest); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't parse fields")
+		return
+	}
+	trimRequest.Code = chi.URLParam(r, "movieCode")
+
+	if err := h.MovieUseCase.Trim(userId, trimRequest); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't trim movie: "+err.Error())
+		return
+	}
+
+	response.RenderSuccess(w, r, http.StatusAccepted, "")
+}
+
 func (h *Handler) GetHandler(w http.ResponseWriter, r *http.Request) {
 	movieCode := chi.URLParam(r, "movieCode")
 	userId := r.Context().Value("userId").(*uint)
 
-	userIp, _ := userip.GetIP(r)
-	movie, err := h.MovieUseCase.GetPublic(userId, movieCode, userIp)
+	var playlistId uint64
+	if playlistParam := r.URL.Query().Get("playlist"); playlistParam != "" {
+		var err error
+		playlistId, err = strconv.ParseUint(playlistParam, 10, 32)
+		if err != nil {
+			response.RenderError(w, r, http.StatusBadRequest, "Invalid playlist id")
+			return
+		}
+	}
+
+	client := h.getClient(r)
+	client.Source = view.NewSource(r.URL.Query().Get("source"), r.Referer(), h.MovieUseCase.SiteUrl)
+	if playlistId > 0 {
+		client.Source = view.SourcePlaylist
+	}
+
+	movie, err := h.MovieUseCase.GetPublic(userId, movieCode, client, uint(playlistId))
 	if err != nil {
 		response.RenderError(w, r, http.StatusNotFound, "Movie not found")
 		return
@@ -216,6 +296,20 @@ func (h *Handler) GetHandler(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, movie)
 }
 
+func (h *Handler) GetChaptersHandler(w http.ResponseWriter, r *http.Request) {
+	movieCode := chi.URLParam(r, "movieCode")
+	userId := r.Context().Value("userId").(*uint)
+
+	chapters, err := h.MovieUseCase.GetChaptersVtt(userId, movieCode)
+	if err != nil {
+		response.RenderError(w, r, http.StatusNotFound, "Movie not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.Write(chapters)
+}
+
 func (h *Handler) GetForUserHandler(w http.ResponseWriter, r *http.Request) {
 	movieCode := chi.URLParam(r, "movieCode")
 	userId := r.Context().Value("userId").(uint)
@@ -236,36 +330,65 @@ func (h *Handler) GetMultipleForUserHandler(w http.ResponseWriter, r *http.Reque
 
 	moviesResponse, err := h.MovieUseCase.GetMultipleByUserId(userId, pagination, sorting)
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, make([]struct{}, 0))
+		render.Status(r, pagination.ErrorStatus(err))
+		render.JSON(w, r, pagination.NewList(nil))
 		return
 	}
 
-	if len(moviesResponse) > 0 {
-		render.JSON(w, r, moviesResponse)
-	} else {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, make([]struct{}, 0))
-	}
+	render.JSON(w, r, pagination.NewList(moviesResponse))
 }
 
 func (h *Handler) GetMultipleHandler(w http.ResponseWriter, r *http.Request) {
 	pagination := r.Context().Value("pagination").(*pagination.Pagination)
 	sorting := r.Context().Value("sorting").(*sorting.Sort)
 
-	moviesResponse, err := h.MovieUseCase.GetMultiplePublic(pagination, sorting)
+	filterRequest, err := NewFilterRequest(r)
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, make([]struct{}, 0))
+		response.RenderError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if len(moviesResponse) > 0 {
-		render.JSON(w, r, moviesResponse)
-	} else {
-		render.Status(r, http.StatusOK)
-		render.JSON(w, r, make([]struct{}, 0))
+	filter, err := h.MovieUseCase.NewFilter(filterRequest)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		render.JSON(w, r, pagination.NewList(nil))
+		return
+	} else if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Invalid filter: "+err.Error())
+		return
+	}
+
+	moviesResponse, err := h.MovieUseCase.GetMultiplePublic(filter, pagination, sorting)
+	if err != nil {
+		render.Status(r, pagination.ErrorStatus(err))
+		render.JSON(w, r, pagination.NewList(nil))
+		return
+	}
+
+	render.JSON(w, r, pagination.NewList(moviesResponse))
+}
+
+func (h *Handler) GetTagHandler(w http.ResponseWriter, r *http.Request) {
+	tagResponse, err := h.MovieUseCase.GetTag(chi.URLParam(r, "tagName"))
+	if err != nil {
+		response.RenderError(w, r, http.StatusNotFound, "Tag not found")
+		return
 	}
+
+	render.JSON(w, r, tagResponse)
+}
+
+func (h *Handler) GetMultipleByTagHandler(w http.ResponseWriter, r *http.Request) {
+	pagination := r.Context().Value("pagination").(*pagination.Pagination)
+	sorting := r.Context().Value("sorting").(*sorting.Sort)
+
+	moviesResponse, err := h.MovieUseCase.GetMultipleByTag(chi.URLParam(r, "tagName"), pagination, sorting)
+	if err != nil {
+		render.Status(r, pagination.ErrorStatus(err))
+		render.JSON(w, r, pagination.NewList(nil))
+		return
+	}
+
+	render.JSON(w, r, pagination.NewList(moviesResponse))
 }
 
 func (h *Handler) GetMultipleByChannelHandler(w http.ResponseWriter, r *http.Request) {
@@ -279,17 +402,30 @@ func (h *Handler) GetMultipleByChannelHandler(w http.ResponseWriter, r *http.Req
 
 	moviesResponse, err := h.MovieUseCase.GetMultipleByChannel(uint(channelId), pagination, sorting)
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, make([]struct{}, 0))
+		render.Status(r, pagination.ErrorStatus(err))
+		render.JSON(w, r, pagination.NewList(nil))
+		return
+	}
+
+	render.JSON(w, r, pagination.NewList(moviesResponse))
+}
+
+func (h *Handler) GetMultiplePremieresHandler(w http.ResponseWriter, r *http.Request) {
+	pagination := r.Context().Value("pagination").(*pagination.Pagination)
+	channelId, err := strconv.ParseUint(chi.URLParam(r, "channelId"), 10, 32)
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Invalid Channel ID")
 		return
 	}
 
-	if len(moviesResponse) > 0 {
-		render.JSON(w, r, moviesResponse)
-	} else {
-		render.Status(r, http.StatusOK)
-		render.JSON(w, r, make([]struct{}, 0))
+	moviesResponse, err := h.MovieUseCase.GetMultiplePremieres(uint(channelId), pagination)
+	if err != nil {
+		render.Status(r, pagination.ErrorStatus(err))
+		render.JSON(w, r, pagination.NewList(nil))
+		return
 	}
+
+	render.JSON(w, r, pagination.NewList(moviesResponse))
 }
 
 func (h *Handler) GetMultipleSubscribedHandler(w http.ResponseWriter, r *http.Request) {
@@ -298,19 +434,138 @@ func (h *Handler) GetMultipleSubscribedHandler(w http.ResponseWriter, r *http.Re
 
 	moviesResponse, err := h.MovieUseCase.GetMultipleSubscribed(userId, pagination)
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, make([]struct{}, 0))
+		render.Status(r, pagination.ErrorStatus(err))
+		render.JSON(w, r, pagination.NewList(nil))
 		return
 	}
 
-	if len(moviesResponse) > 0 {
-		render.JSON(w, r, moviesResponse)
-	} else {
-		render.Status(r, http.StatusOK)
-		render.JSON(w, r, make([]struct{}, 0))
+	render.JSON(w, r, pagination.NewList(moviesResponse))
+}
+
+func (h *Handler) SetReactionHandler(reactionType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(uint)
+
+		reactionResponse, err := h.MovieUseCase.SetReaction(userId, chi.URLParam(r, "movieCode"), reactionType)
+		if err != nil {
+			renderReactionError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, reactionResponse)
 	}
 }
 
+func (h *Handler) DeleteReactionHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	reactionResponse, err := h.MovieUseCase.DeleteReaction(userId, chi.URLParam(r, "movieCode"))
+	if err != nil {
+		renderReactionError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, reactionResponse)
+}
+
+func renderReactionError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, ErrNotAllowed):
+		response.RenderError(w, r, http.StatusNotFound, "Movie not found")
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		response.RenderError(w, r, http.StatusConflict, "Reaction is being changed, try again")
+	default:
+		response.RenderError(w, r, http.StatusInternalServerError, "Can't save reaction")
+	}
+}
+
+func (h *Handler) GetMultipleLikedHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+	pagination := r.Context().Value("pagination").(*pagination.Pagination)
+
+	moviesResponse, err := h.MovieUseCase.GetMultipleLiked(userId, pagination)
+	if err != nil {
+		render.Status(r, pagination.ErrorStatus(err))
+		render.JSON(w, r, pagination.NewList(nil))
+		return
+	}
+
+	render.JSON(w, r, pagination.NewList(moviesResponse))
+}
+
+func (h *Handler) SaveProgressHandler(w http.ResponseWriter, r *http.Request) {
+	movieCode := chi.URLParam(r, "movieCode")
+	userId := r.Context().Value("userId").(*uint)
+
+	progressRequest := &history.ProgressRequest{}
+	if err := json.NewDecoder(r.Body).Decode(progressRequest); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't parse fields")
+		return
+	}
+
+	if err := h.MovieUseCase.SaveProgress(userId, movieCode, progressRequest, h.getClient(r)); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't save progress")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetMultipleHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+	pagination := r.Context().Value("pagination").(*pagination.Pagination)
+
+	moviesResponse, err := h.MovieUseCase.GetMultipleHistory(userId, pagination)
+	if err != nil {
+		render.Status(r, pagination.ErrorStatus(err))
+		render.JSON(w, r, pagination.NewList(nil))
+		return
+	}
+
+	render.JSON(w, r, pagination.NewList(moviesResponse))
+}
+
+func (h *Handler) GetMultipleContinueWatchingHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+	pagination := r.Context().Value("pagination").(*pagination.Pagination)
+
+	moviesResponse, err := h.MovieUseCase.GetMultipleContinueWatching(userId, pagination)
+	if err != nil {
+		render.Status(r, pagination.ErrorStatus(err))
+		render.JSON(w, r, pagination.NewList(nil))
+		return
+	}
+
+	render.JSON(w, r, pagination.NewList(moviesResponse))
+}
+
+func (h *Handler) GetMultipleTrendingHandler(w http.ResponseWriter, r *http.Request) {
+	pagination := r.Context().Value("pagination").(*pagination.Pagination)
+
+	moviesResponse, err := h.MovieUseCase.GetMultipleTrending(pagination)
+	if err != nil {
+		render.Status(r, pagination.ErrorStatus(err))
+		render.JSON(w, r, pagination.NewList(nil))
+		return
+	}
+
+	render.JSON(w, r, pagination.NewList(moviesResponse))
+}
+
+func (h *Handler) GetMultipleForYouHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+	pagination := r.Context().Value("pagination").(*pagination.Pagination)
+
+	moviesResponse, err := h.MovieUseCase.GetMultipleForYou(userId, pagination)
+	if err != nil {
+		render.Status(r, pagination.ErrorStatus(err))
+		render.JSON(w, r, pagination.NewList(nil))
+		return
+	}
+
+	render.JSON(w, r, pagination.NewList(moviesResponse))
+}
+
 func (h *Handler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 	userId := r.Context().Value("userId").(uint)
 	movieCode := chi.URLParam(r, "movieCode")
@@ -354,7 +609,11 @@ func (h *Handler) UpdatePublishStatusHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	rowsAffected, err := h.MovieUseCase.UpdatePublishStatus(userId, movieUpdatePublishStatusRequest)
-	if err != nil || rowsAffected == 0 {
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't update publish status: "+err.Error())
+		return
+	}
+	if rowsAffected == 0 {
 		response.RenderError(w, r, http.StatusNotFound, "Movie not found")
 		return
 	}
@@ -403,3 +662,65 @@ func (h *Handler) StreamFile(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusPartialContent)
 	w.Write(buff)
 }
+
+// NewFilterRequest reads list filters from the query string. Dates are
+// YYYY-MM-DD, the date-to day is included, durations are in seconds.
+func NewFilterRequest(r *http.Request) (*FilterRequest, error) {
+	query := r.URL.Query()
+	filterRequest := &FilterRequest{
+		Tag:      query.Get("tag"),
+		Quality:  query.Get("quality"),
+		Language: query.Get("language"),
+	}
+
+	if category := query.Get("category"); category != "" {
+		categoryId, err := strconv.ParseUint(category, 10, 32)
+		if err != nil {
+			return nil, errors.New("invalid category")
+		}
+		filterRequest.Category = uint(categoryId)
+	}
+
+	if channel := query.Get("channel"); channel != "" {
+		channelId, err := strconv.ParseUint(channel, 10, 32)
+		if err != nil {
+			return nil, errors.New("invalid channel")
+		}
+		filterRequest.Channel = uint(channelId)
+	}
+
+	if durationFrom := query.Get("duration-from"); durationFrom != "" {
+		duration, err := strconv.Atoi(durationFrom)
+		if err != nil {
+			return nil, errors.New("invalid duration from")
+		}
+		filterRequest.DurationFrom = &duration
+	}
+
+	if durationTo := query.Get("duration-to"); durationTo != "" {
+		duration, err := strconv.Atoi(durationTo)
+		if err != nil {
+			return nil, errors.New("invalid duration to")
+		}
+		filterRequest.DurationTo = &duration
+	}
+
+	if dateFrom := query.Get("date-from"); dateFrom != "" {
+		date, err := time.Parse(time.DateOnly, dateFrom)
+		if err != nil {
+			return nil, errors.New("invalid date from")
+		}
+		filterRequest.DateFrom = &date
+	}
+
+	if dateTo := query.Get("date-to"); dateTo != "" {
+		date, err := time.Parse(time.DateOnly, dateTo)
+		if err != nil {
+			return nil, errors.New("invalid date to")
+		}
+		date = date.Add(24*time.Hour - time.Nanosecond)
+		filterRequest.DateTo = &date
+	}
+
+	return filterRequest, nil
+}
@@ -2,6 +2,8 @@ package movie
 
 import (
 	"nine-dubz/internal/pagination"
+	"nine-dubz/internal/video"
+	"time"
 )
 
 type Interactor interface {
@@ -12,13 +14,32 @@ type Interactor interface {
 	UpdatesWhere(movie *Movie, where map[string]interface{}) (int64, error)
 	UpdatesSelectWhere(movie *Movie, selectQuery, whereQuery interface{}) (int64, error)
 	AppendAssociation(movie *Movie, association string, append interface{}) error
+	ReplaceAssociation(movie *Movie, association string, replace interface{}) error
+	ReplaceVideos(movie *Movie, videos []*video.Video, clear []string) error
+	CountAssociationWhere(movie *Movie, association string, where interface{}) (int64, error)
 	Get(code string) (*Movie, error)
 	GetWhere(where interface{}) (*Movie, error)
 	GetSelectWhere(selectQuery, where interface{}) (*Movie, error)
 	GetWhereCount(where interface{}) (int64, error)
+	GetFilterCount(whereQuery interface{}, filter *Filter) (int64, error)
 	GetMultipleByUserId(userId uint, pagination *pagination.Pagination, order string) (*[]Movie, error)
 	GetMultiple(pagination *pagination.Pagination, order string) (*[]Movie, error)
 	GetWhereMultiple(where map[string]interface{}, pagination *pagination.Pagination, order string) (*[]Movie, error)
 	GetPreloadWhere(preloads []string, whereQuery interface{}) (*Movie, error)
-	GetPreloadWhereMultiple(preloads []string, whereQuery interface{}, pagination *pagination.Pagination, order string) (*[]Movie, error)
+	GetPreloadWhereMultiple(preloads []string, whereQuery interface{}, filter *Filter, pagination *pagination.Pagination, order string) (*[]Movie, error)
+	GetPreloadAccessibleMultiple(preloads []string, userId *uint, ids []uint) (*[]Movie, error)
+	GetTagMoviesCount(tagId uint) (int64, error)
+	GetScheduledBefore(publishAt time.Time) (*[]Movie, error)
+	GetPreloadWhereScheduledMultiple(preloads []string, whereQuery interface{}, publishAfter time.Time, pagination *pagination.Pagination, order string) (*[]Movie, error)
+	GetDecayedCounts(table, condition string, now, since time.Time, halfLife time.Duration) (map[uint]float64, error)
+	UpdateTrendingScores(scores map[uint]float64) error
+	GetWatchedByUser(userId uint, since time.Time) ([]uint, []uint, error)
+}
+
+// PlaylistUseCase finds the movie in a playlist and takes watched movies
+// off the watch later list. Playlists depend on movies, so the playlist use
+// case is set on UseCase after both are built.
+type PlaylistUseCase interface {
+	GetContext(userId *uint, playlistId, movieId uint) (*PlaylistContext, error)
+	RemoveWatched(userId, movieId uint) error
 }
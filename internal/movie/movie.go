@@ -8,12 +8,19 @@ import (
 	"io"
 	"log"
 	"math/rand"
-	"net"
 	"net/http"
+	"nine-dubz/internal/analytics"
+	"nine-dubz/internal/category"
+	"nine-dubz/internal/chapter"
 	"nine-dubz/internal/file"
+	"nine-dubz/internal/history"
+	"nine-dubz/internal/notification"
 	"nine-dubz/internal/pagination"
+	"nine-dubz/internal/reaction"
 	"nine-dubz/internal/sorting"
 	"nine-dubz/internal/subscription"
+	"nine-dubz/internal/tag"
+	"nine-dubz/internal/user"
 	"nine-dubz/internal/video"
 	"nine-dubz/internal/view"
 	"nine-dubz/pkg/ffmpegthumbs"
@@ -21,6 +28,7 @@ import (
 	"nine-dubz/pkg/webvtt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"sort"
 	"strconv"
@@ -36,6 +44,40 @@ import (
 	"gorm.io/gorm"
 )
 
+// Movie language is an ISO 639-1 code
+var languageRegexp = regexp.MustCompile(`^[a-z]{2}$`)
+
+var sortColumns = map[string]string{
+	"created_at": "movies.created_at",
+	"views":      "movies.views_count",
+	"comments":   "movies.comments_count",
+	"likes":      "movies.likes_count",
+	"trending":   "movies.trending_score",
+}
+
+const (
+	// Views, comments and likes older than the window don't count for
+	// trending, newer ones weigh half as much every half-life
+	trendingWindow        = 7 * 24 * time.Hour
+	trendingHalfLife      = 24 * time.Hour
+	trendingCommentWeight = 3
+	trendingLikeWeight    = 2
+
+	// Watched movies and their categories drive the "for you" feed
+	watchedPeriod    = 30 * 24 * time.Hour
+	maxWatchedMovies = 500
+
+	trendingFeedTTL = time.Minute
+	forYouFeedTTL   = 5 * time.Minute
+	maxCachedFeeds  = 1000
+)
+
+// ErrNotAllowed is returned for movies the user has no access to
+var ErrNotAllowed = errors.New("not allowed")
+
+// Premieres go from the nearest one
+var premieresSortKey = pagination.Key{SortBy: "publish_at", Column: "movies.publish_at", IdColumn: "movies.id"}
+
 type UseCase struct {
 	MovieInteractor     Interactor
 	SiteUrl             string
@@ -44,11 +86,22 @@ type UseCase struct {
 	FileUseCase         *file.UseCase
 	ViewUseCase         *view.UseCase
 	SubscriptionUseCase *subscription.UseCase
+	ChapterUseCase      *chapter.UseCase
+	CategoryUseCase     *category.UseCase
+	TagUseCase          *tag.UseCase
+	ReactionUseCase     *reaction.UseCase
+	HistoryUseCase      *history.UseCase
+	AnalyticsUseCase    *analytics.UseCase
+	NotificationUseCase *notification.UseCase
+	UserUseCase         *user.UseCase
+	PlaylistUseCase     PlaylistUseCase
 	MoviePool           map[string]PoolItem
 	Mutex               *sync.RWMutex
+	Feeds               map[string]cachedFeed
+	FeedsMutex          *sync.Mutex
 }
 
-func New(db *gorm.DB, pool *pond.WorkerPool, viduc *video.UseCase, fuc *file.UseCase, vuc *view.UseCase, subuc *subscription.UseCase) *UseCase {
+func New(db *gorm.DB, pool *pond.WorkerPool, viduc *video.UseCase, fuc *file.UseCase, vuc *view.UseCase, subuc *subscription.UseCase, chuc *chapter.UseCase, catuc *category.UseCase, taguc *tag.UseCase, reacuc *reaction.UseCase, huc *history.UseCase, auc *analytics.UseCase, nuc *notification.UseCase, uuc *user.UseCase) *UseCase {
 	siteUrl, ok := os.LookupEnv("SITE_URL")
 	if !ok {
 		log.Println("movie: SITE_URL not found in environment")
@@ -64,8 +117,18 @@ func New(db *gorm.DB, pool *pond.WorkerPool, viduc *video.UseCase, fuc *file.Use
 		FileUseCase:         fuc,
 		ViewUseCase:         vuc,
 		SubscriptionUseCase: subuc,
+		ChapterUseCase:      chuc,
+		CategoryUseCase:     catuc,
+		TagUseCase:          taguc,
+		ReactionUseCase:     reacuc,
+		HistoryUseCase:      huc,
+		AnalyticsUseCase:    auc,
+		NotificationUseCase: nuc,
+		UserUseCase:         uuc,
 		MoviePool:           make(map[string]PoolItem),
 		Mutex:               &sync.RWMutex{},
+		Feeds:               make(map[string]cachedFeed),
+		FeedsMutex:          &sync.Mutex{},
 	}
 }
 
@@ -126,6 +189,7 @@ func (uc *UseCase) Delete(code string) error {
 	}
 
 	go uc.FileUseCase.DeleteAllInPath("movies/" + code)
+	uc.InvalidateFeeds()
 
 	return nil
 }
@@ -266,6 +330,11 @@ func (uc *UseCase) PostProcessVideo(ctx context.Context, movie Movie, tmpFile *o
 		return err
 	}
 
+	// Animated preview
+	if err := uc.CreateAnimatedPreview(ctx, movie, thumbsPath, tmpFile); err != nil {
+		log.Println(err)
+	}
+
 	// Resize
 	if err := uc.CreateResizedVideos(ctx, movie, resizedVideoPath, tmpFile); err != nil {
 		uc.Delete(movie.Code)
@@ -296,10 +365,31 @@ func (uc *UseCase) CreateThumbnails(ctx context.Context, movie Movie, thumbsPath
 		return nil
 	}
 
+	thumbnails, err := uc.GenerateThumbnails(ctx, thumbsPath, tmpFile.Name())
+	if err != nil {
+		return err
+	}
+
+	movieUpdateRequest := &VideoUpdateRequest{
+		Code:               movie.Code,
+		DefaultPreview:     thumbnails.DefaultPreview,
+		DefaultPreviewWebp: thumbnails.DefaultPreviewWebp,
+		WebVtt:             thumbnails.WebVtt,
+		Duration:           thumbnails.Duration,
+	}
+	rowsAffected, err := uc.UpdateVideo(movieUpdateRequest)
+	if err != nil || rowsAffected == 0 {
+		return errors.New("failed to update video")
+	}
+
+	return nil
+}
+
+func (uc *UseCase) GenerateThumbnails(ctx context.Context, thumbsPath, videoPath string) (*Thumbnails, error) {
 	frameDuration := 10
-	err := ffmpegthumbs.SplitVideoToThumbnails(tmpFile.Name(), thumbsPath, frameDuration)
+	err := ffmpegthumbs.SplitVideoToThumbnails(videoPath, thumbsPath, frameDuration)
 	if err != nil {
-		return errors.New("movie thumbnails: failed to create thumbnails")
+		return nil, errors.New("movie thumbnails: failed to create thumbnails")
 	}
 
 	thumbsWebvttPath := "/api/file/"
@@ -315,7 +405,7 @@ func (uc *UseCase) CreateThumbnails(ctx context.Context, movie Movie, thumbsPath
 	for i, item := range items {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return nil, ctx.Err()
 		default:
 			if item.IsDir() {
 				continue
@@ -332,7 +422,7 @@ func (uc *UseCase) CreateThumbnails(ctx context.Context, movie Movie, thumbsPath
 	}
 
 	if len(imagesFilePath) == 0 {
-		return errors.New("movie thumbnails: no thumbnails")
+		return nil, errors.New("movie thumbnails: no thumbnails")
 	}
 
 	if preview != nil {
@@ -342,18 +432,41 @@ func (uc *UseCase) CreateThumbnails(ctx context.Context, movie Movie, thumbsPath
 	}
 
 	var savedVttFile *file.File
-	videoDuration, _ := ffmpegthumbs.GetVideoDuration(tmpFile.Name())
+	videoDuration, _ := ffmpegthumbs.GetVideoDuration(videoPath)
 	vttFile, err := webvtt.CreateFromFilePaths(imagesFilePath, thumbsPath, videoDuration, frameDuration)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	savedVttFile, _ = uc.FileUseCase.CreateFromPath(vttFile.Name(), "thumbs.vtt", thumbsPath, "public")
 
-	movieUpdateRequest := &VideoUpdateRequest{
-		Code:               movie.Code,
+	return &Thumbnails{
 		DefaultPreview:     preview,
 		DefaultPreviewWebp: previewWebp,
 		WebVtt:             savedVttFile,
+		Duration:           videoDuration,
+	}, nil
+}
+
+func (uc *UseCase) CreateAnimatedPreview(ctx context.Context, movie Movie, previewPath string, tmpFile *os.File) error {
+	if movie.AnimatedPreview != nil {
+		return nil
+	}
+
+	err := ffmpegthumbs.CreateAnimatedPreview(ctx, tmpFile.Name(), previewPath, "animated", 5, 2)
+	if err != nil {
+		return errors.New("movie animated preview: failed to create animated preview")
+	}
+
+	animatedPreview, err := uc.FileUseCase.CreateFromPath(
+		filepath.Join(previewPath, "animated.webp"), "animated.webp", previewPath, "public",
+	)
+	if err != nil {
+		return err
+	}
+
+	movieUpdateRequest := &VideoUpdateRequest{
+		Code:            movie.Code,
+		AnimatedPreview: animatedPreview,
 	}
 	rowsAffected, err := uc.UpdateVideo(movieUpdateRequest)
 	if err != nil || rowsAffected == 0 {
@@ -363,6 +476,364 @@ func (uc *UseCase) CreateThumbnails(ctx context.Context, movie Movie, thumbsPath
 	return nil
 }
 
+func (uc *UseCase) BackfillAnimatedPreviews() {
+	movies, err := uc.MovieInteractor.GetWhereMultiple(
+		map[string]interface{}{"status": StatusReady, "animated_preview_id": nil},
+		&pagination.Pagination{
+			Limit:  -1,
+			Offset: -1,
+		},
+		"created_at desc",
+	)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for _, movie := range *movies {
+		sourceVideo := GetSourceVideo(movie.Videos)
+		if sourceVideo == nil {
+			continue
+		}
+
+		backfillPath := filepath.Join("upload/movies", movie.Code, "backfill")
+		thumbsPath := filepath.Join("upload/movies", movie.Code, "thumbs")
+
+		tmpFile, err := uc.FileUseCase.DownloadToPath(sourceVideo.File, backfillPath, sourceVideo.File.Name+sourceVideo.File.Extension)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		err = uc.CreateAnimatedPreview(context.TODO(), movie, thumbsPath, tmpFile)
+		if err != nil {
+			log.Println(err)
+		}
+
+		os.RemoveAll(backfillPath)
+		if uc.FileUseCase.GetSaveType() == file.SaveTypeInternal {
+			os.RemoveAll(filepath.Join("upload/movies", movie.Code))
+		}
+	}
+}
+
+// BackfillDurations probes movies processed before the duration was saved.
+// The smallest rendition is enough to know it.
+func (uc *UseCase) BackfillDurations() {
+	movies, err := uc.MovieInteractor.GetWhereMultiple(
+		map[string]interface{}{"status": StatusReady, "duration": 0},
+		&pagination.Pagination{
+			Limit:  -1,
+			Offset: -1,
+		},
+		"created_at desc",
+	)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for _, movie := range *movies {
+		var smallestVideo *video.Video
+		for i, movieVideo := range movie.Videos {
+			if movieVideo.File != nil && (smallestVideo == nil || movieVideo.Height < smallestVideo.Height) {
+				smallestVideo = &movie.Videos[i]
+			}
+		}
+		if smallestVideo == nil {
+			continue
+		}
+
+		backfillPath := filepath.Join("upload/movies", movie.Code, "backfill")
+
+		tmpFile, err := uc.FileUseCase.DownloadToPath(smallestVideo.File, backfillPath, smallestVideo.File.Name+smallestVideo.File.Extension)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		duration, err := ffmpegthumbs.GetVideoDuration(tmpFile.Name())
+		if err != nil {
+			log.Println(err)
+		} else if duration > 0 {
+			if _, err = uc.UpdateVideo(&VideoUpdateRequest{Code: movie.Code, Duration: duration}); err != nil {
+				log.Println(err)
+			} else {
+				if err = uc.ChapterUseCase.Cut(movie.ID, duration); err != nil {
+					log.Println(err)
+				}
+				if err = uc.HistoryUseCase.ClampPositions(movie.ID, duration); err != nil {
+					log.Println(err)
+				}
+			}
+		}
+
+		os.RemoveAll(backfillPath)
+		if uc.FileUseCase.GetSaveType() == file.SaveTypeInternal {
+			os.RemoveAll(filepath.Join("upload/movies", movie.Code))
+		}
+	}
+}
+
+func GetSourceVideo(videos []video.Video) *video.Video {
+	var sourceVideo *video.Video
+	for i, movieVideo := range videos {
+		if movieVideo.File == nil {
+			continue
+		}
+		if sourceVideo == nil || movieVideo.Height > sourceVideo.Height {
+			sourceVideo = &videos[i]
+		}
+	}
+
+	return sourceVideo
+}
+
+func (uc *UseCase) Trim(userId uint, trimRequest *TrimRequest) error {
+	movie, err := uc.MovieInteractor.GetWhere(map[string]interface{}{
+		"user_id": userId,
+		"code":    trimRequest.Code,
+	})
+	if err != nil {
+		return errors.New("movie not found")
+	}
+
+	if movie.Status != StatusReady {
+		return errors.New("movie is not ready yet")
+	}
+
+	if len(trimRequest.Segments) == 0 && trimRequest.Start == nil && trimRequest.End == nil {
+		return errors.New("nothing to trim")
+	}
+
+	segments := NewTrimSegments(trimRequest)
+	if len(segments) > 20 {
+		return errors.New("too many segments")
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].Start < segments[j].Start
+	})
+	for i, segment := range segments {
+		if segment.Start < 0 || segment.End <= segment.Start {
+			return errors.New("invalid segment")
+		}
+		if i > 0 && segment.Start < segments[i-1].End {
+			return errors.New("segments overlap")
+		}
+	}
+
+	sourceVideo := GetSourceVideo(movie.Videos)
+	if sourceVideo == nil {
+		return errors.New("no source video")
+	}
+
+	uc.Mutex.Lock()
+	if _, ok := uc.MoviePool[movie.Code]; ok {
+		uc.Mutex.Unlock()
+		return errors.New("movie is already processing")
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	uc.MoviePool[movie.Code] = PoolItem{ctx, cancel}
+	uc.Mutex.Unlock()
+
+	uc.Pool.Submit(func() {
+		if err := uc.TrimVideo(ctx, *movie, *sourceVideo, segments); err != nil {
+			log.Println(err)
+		}
+		uc.Mutex.Lock()
+		delete(uc.MoviePool, movie.Code)
+		uc.Mutex.Unlock()
+	})
+
+	return nil
+}
+
+func (uc *UseCase) TrimVideo(ctx context.Context, movie Movie, sourceVideo video.Video, segments []TrimSegment) error {
+	editPath := filepath.Join("upload/movies", movie.Code, "edit", strconv.FormatInt(time.Now().Unix(), 10))
+	sourcePath := filepath.Join(editPath, "source")
+	thumbsPath := filepath.Join(editPath, "thumbs")
+	resizedVideoPath := filepath.Join(editPath, "resize")
+	defer os.RemoveAll(sourcePath)
+	if uc.FileUseCase.GetSaveType() == file.SaveTypeInternal {
+		defer os.RemoveAll(editPath)
+	}
+
+	sourceFile, err := uc.FileUseCase.DownloadToPath(sourceVideo.File, sourcePath, "source"+sourceVideo.File.Extension)
+	if err != nil {
+		return err
+	}
+
+	videoDuration, err := ffmpegthumbs.GetVideoDuration(sourceFile.Name())
+	if err != nil {
+		return err
+	}
+
+	var ffmpegSegments []ffmpegthumbs.Segment
+	for _, segment := range segments {
+		if segment.Start >= float64(videoDuration) {
+			break
+		}
+		ffmpegSegments = append(ffmpegSegments, ffmpegthumbs.Segment{
+			Start: segment.Start,
+			End:   min(segment.End, float64(videoDuration)),
+		})
+	}
+	if len(ffmpegSegments) == 0 {
+		return errors.New("movie trim: segments are out of video duration")
+	}
+
+	err = ffmpegthumbs.Trim(ctx, sourceFile.Name(), sourcePath, "trimmed", ffmpegSegments)
+	if err != nil {
+		return errors.New("movie trim: failed to trim video")
+	}
+
+	trimmedPath := filepath.Join(sourcePath, "trimmed.mp4")
+
+	var newVideos []*video.Video
+	var newFiles []*file.File
+	rollback := func() {
+		for _, newVideo := range newVideos {
+			uc.VideoUseCase.Delete(newVideo)
+		}
+		for _, newFile := range newFiles {
+			if newFile != nil {
+				uc.FileUseCase.Delete(newFile.Name)
+			}
+		}
+	}
+
+	thumbnails, err := uc.GenerateThumbnails(ctx, thumbsPath, trimmedPath)
+	if err != nil {
+		rollback()
+		return err
+	}
+	newFiles = append(newFiles, thumbnails.DefaultPreview, thumbnails.DefaultPreviewWebp, thumbnails.WebVtt)
+
+	// Without an animated preview the movie gets one from
+	// BackfillAnimatedPreviews
+	var animatedPreview *file.File
+	err = ffmpegthumbs.CreateAnimatedPreview(ctx, trimmedPath, thumbsPath, "animated", 5, 2)
+	if err == nil {
+		animatedPreview, err = uc.FileUseCase.CreateFromPath(
+			filepath.Join(thumbsPath, "animated.webp"), "animated.webp", thumbsPath, "public",
+		)
+		newFiles = append(newFiles, animatedPreview)
+	}
+	if err != nil {
+		log.Println("movie trim: animated preview:", err)
+	}
+
+	loudness, err := uc.VideoUseCase.MeasureLoudness(ctx, trimmedPath)
+	if err != nil {
+		log.Println("movie: measure loudness, audio isn't normalised:", err)
+	}
+	_, videoHeight, _ := ffmpegthumbs.GetVideoSize(trimmedPath)
+
+	for _, quality := range video.SupportedQualities {
+		if quality.Type == video.QualityTypeSkip || videoHeight <= quality.Settings.MinHeight {
+			continue
+		}
+
+		err = uc.VideoUseCase.Process(ctx, quality, trimmedPath, resizedVideoPath, loudness)
+		if err != nil {
+			rollback()
+			return err
+		}
+
+		resizedPath := filepath.Join(resizedVideoPath, quality.Code+".mp4")
+		savedVideo, err := uc.VideoUseCase.Save(ctx, resizedPath, quality.Code+".mp4", resizedVideoPath, quality.ID)
+		if err != nil {
+			rollback()
+			return err
+		}
+		newVideos = append(newVideos, savedVideo)
+	}
+
+	select {
+	case <-ctx.Done():
+		rollback()
+		return ctx.Err()
+	default:
+	}
+
+	trimmedMovie := NewVideoUpdateRequest(&VideoUpdateRequest{
+		Code:               movie.Code,
+		DefaultPreview:     thumbnails.DefaultPreview,
+		DefaultPreviewWebp: thumbnails.DefaultPreviewWebp,
+		WebVtt:             thumbnails.WebVtt,
+		AnimatedPreview:    animatedPreview,
+		Duration:           thumbnails.Duration,
+	})
+	trimmedMovie.ID = movie.ID
+	if loudness != nil {
+		trimmedMovie.Loudness = NewLoudness(loudness, uc.VideoUseCase.LoudnessTarget)
+	}
+
+	// The old files are deleted below, so missing new ones must not leave
+	// the movie pointing to them
+	var clear []string
+	for column, newFile := range map[string]*file.File{
+		"default_preview_id":      thumbnails.DefaultPreview,
+		"default_preview_webp_id": thumbnails.DefaultPreviewWebp,
+		"animated_preview_id":     animatedPreview,
+		"web_vtt_id":              thumbnails.WebVtt,
+	} {
+		if newFile == nil {
+			clear = append(clear, column)
+		}
+	}
+
+	if err = uc.MovieInteractor.ReplaceVideos(trimmedMovie, newVideos, clear); err != nil {
+		rollback()
+		return errors.New("failed to update video")
+	}
+	if err = uc.ChapterUseCase.Cut(movie.ID, thumbnails.Duration); err != nil {
+		log.Println("movie trim: cut chapters:", err)
+	}
+	if err = uc.HistoryUseCase.ClampPositions(movie.ID, thumbnails.Duration); err != nil {
+		log.Println("movie trim: clamp history:", err)
+	}
+
+	uc.DeleteRenditions(movie)
+
+	return nil
+}
+
+func (uc *UseCase) DeleteRenditions(movie Movie) {
+	for _, movieVideo := range movie.Videos {
+		uc.VideoUseCase.Delete(&movieVideo)
+	}
+
+	keepFiles := make(map[string]bool)
+	if movie.Preview != nil {
+		keepFiles[movie.Preview.Name] = true
+	}
+	if movie.PreviewWebp != nil {
+		keepFiles[movie.PreviewWebp.Name] = true
+	}
+
+	var oldFiles []file.File
+	for _, oldFile := range []*file.File{movie.DefaultPreview, movie.DefaultPreviewWebp, movie.AnimatedPreview, movie.WebVtt} {
+		if oldFile != nil {
+			oldFiles = append(oldFiles, *oldFile)
+		}
+	}
+	if movie.WebVtt != nil {
+		thumbs, err := uc.FileUseCase.GetMultipleInPath(movie.WebVtt.Path)
+		if err == nil {
+			oldFiles = append(oldFiles, thumbs...)
+		}
+	}
+
+	for _, oldFile := range oldFiles {
+		if keepFiles[oldFile.Name] {
+			continue
+		}
+		keepFiles[oldFile.Name] = true
+		uc.FileUseCase.Delete(oldFile.Name)
+	}
+}
+
 func (uc *UseCase) CreateResizedVideos(ctx context.Context, movie Movie, resizedVideoPath string, tmpFile *os.File) error {
 	var resizedWebmPath string
 	var savedVideo *video.Video
@@ -373,11 +844,24 @@ func (uc *UseCase) CreateResizedVideos(ctx context.Context, movie Movie, resized
 
 	_, videoHeight, _ := ffmpegthumbs.GetVideoSize(tmpFile.Name())
 
+	loudness, err := uc.VideoUseCase.MeasureLoudness(ctx, tmpFile.Name())
+	if err != nil {
+		log.Println("movie: measure loudness, audio isn't normalised:", err)
+	} else {
+		movieUpdateRequest := &VideoUpdateRequest{
+			Code:     movie.Code,
+			Loudness: NewLoudness(loudness, uc.VideoUseCase.LoudnessTarget),
+		}
+		if _, err = uc.UpdateVideo(movieUpdateRequest); err != nil {
+			log.Println("movie: save loudness:", err)
+		}
+	}
+
 	for _, quality := range video.SupportedQualities {
 		if slices.Contains(qualitiesIds, quality.ID) || videoHeight <= quality.Settings.MinHeight {
 			continue
 		}
-		err := quality.Process(ctx, tmpFile.Name(), resizedVideoPath)
+		err := uc.VideoUseCase.Process(ctx, quality, tmpFile.Name(), resizedVideoPath, loudness)
 		if err != nil {
 			return err
 		}
@@ -429,25 +913,95 @@ func (uc *UseCase) UpdateByUserId(userId uint, movie *UpdateRequest) error {
 		selectQuery = append(selectQuery, "Description")
 	}
 
-	if movie.Category.ID > 0 {
-		selectQuery = append(selectQuery, "Category")
+	if movie.Language != "" {
+		if !languageRegexp.MatchString(movie.Language) {
+			return errors.New("invalid language")
+		}
+		selectQuery = append(selectQuery, "Language")
 	}
 
-	if movie.PreviewHeader != nil && movie.PreviewHeader.Size > 0 {
-		buff := make([]byte, 512)
-		_, err := movie.Preview.Read(buff)
-		if err != nil {
-			return err
+	if movie.CategoryId > 0 {
+		if !uc.CategoryUseCase.IsEnabled(movie.CategoryId) {
+			return errors.New("invalid category")
 		}
-		_, err = movie.Preview.Seek(0, io.SeekStart)
+		selectQuery = append(selectQuery, "CategoryId")
+	}
+
+	if movie.Visibility != "" {
+		if !slices.Contains(Visibilities, movie.Visibility) {
+			return errors.New("invalid visibility")
+		}
+		selectQuery = append(selectQuery, "Visibility")
+	}
+
+	var tags []tag.Tag
+	if movie.Tags != nil {
+		var err error
+		tags, err = uc.TagUseCase.GetOrCreateMultiple(*movie.Tags)
 		if err != nil {
 			return err
 		}
-		isCorrectType, previewFileType := uc.FileUseCase.VerifyFileType(buff, []string{
-			"image/jpeg", "image/png", "image/webp", "image/gif",
-		})
-		if !isCorrectType {
-			return errors.New("invalid file type")
+	}
+
+	var sharedWith []user.User
+	if movie.SharedWith != nil {
+		if len(*movie.SharedWith) > 50 {
+			return errors.New("movie can be shared with up to 50 users")
+		}
+
+		if len(*movie.SharedWith) > 0 {
+			users, err := uc.UserUseCase.GetMultiple(map[string]interface{}{"name": *movie.SharedWith})
+			if err != nil {
+				return err
+			}
+
+			for _, sharedUser := range users {
+				if sharedUser.ID != userId {
+					sharedWith = append(sharedWith, sharedUser)
+				}
+			}
+		}
+	}
+
+	var chapters []chapter.SetRequest
+	var duration int
+	if movie.Chapters != nil || utf8.RuneCountInString(movie.Description) > 0 {
+		ownMovie, err := uc.MovieInteractor.GetSelectWhere(
+			"duration",
+			map[string]interface{}{"code": movie.Code, "user_id": userId},
+		)
+		if err != nil {
+			return errors.New("movie not found")
+		}
+		duration = ownMovie.Duration
+	}
+	if movie.Chapters != nil {
+		chapters = *movie.Chapters
+		if err := chapter.Validate(chapters, duration); err != nil {
+			return err
+		}
+	} else if utf8.RuneCountInString(movie.Description) > 0 {
+		chapters = chapter.ParseFromText(movie.Description)
+		if err := chapter.Validate(chapters, duration); err != nil {
+			chapters = nil
+		}
+	}
+
+	if movie.PreviewHeader != nil && movie.PreviewHeader.Size > 0 {
+		buff := make([]byte, 512)
+		_, err := movie.Preview.Read(buff)
+		if err != nil {
+			return err
+		}
+		_, err = movie.Preview.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		isCorrectType, previewFileType := uc.FileUseCase.VerifyFileType(buff, []string{
+			"image/jpeg", "image/png", "image/webp", "image/gif",
+		})
+		if !isCorrectType {
+			return errors.New("invalid file type")
 		}
 
 		previewSavePath := filepath.Join("movies", movie.Code, "thumbs")
@@ -485,6 +1039,40 @@ func (uc *UseCase) UpdateByUserId(userId uint, movie *UpdateRequest) error {
 		return errors.New("movie not found")
 	}
 
+	if movie.Visibility != "" && movie.Visibility != VisibilityPublic {
+		uc.InvalidateFeeds()
+	}
+
+	if movie.Chapters == nil && len(chapters) == 0 && movie.SharedWith == nil && movie.Tags == nil {
+		return nil
+	}
+
+	updatedMovie, err := uc.MovieInteractor.GetSelectWhere(
+		"id",
+		map[string]interface{}{"code": movie.Code, "user_id": userId},
+	)
+	if err != nil {
+		return err
+	}
+
+	if movie.Chapters != nil || len(chapters) > 0 {
+		if err = uc.ChapterUseCase.Set(updatedMovie.ID, duration, chapters); err != nil {
+			return err
+		}
+	}
+
+	if movie.SharedWith != nil {
+		if err = uc.MovieInteractor.ReplaceAssociation(&Movie{ID: updatedMovie.ID}, "SharedWith", sharedWith); err != nil {
+			return err
+		}
+	}
+
+	if movie.Tags != nil {
+		if err = uc.MovieInteractor.ReplaceAssociation(&Movie{ID: updatedMovie.ID}, "Tags", tags); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -508,12 +1096,148 @@ func (uc *UseCase) RemovePreview(code string) error {
 }
 
 func (uc *UseCase) UpdatePublishStatus(userId uint, movie *UpdatePublishStatusRequest) (int64, error) {
+	if movie.PublishAt != nil {
+		if !movie.PublishAt.After(time.Now()) {
+			return 0, errors.New("publish time must be in the future")
+		}
+		movie.IsPublished = false
+	}
+
 	movieRequest := NewUpdatePublishStatusRequest(movie)
-	return uc.MovieInteractor.UpdatesSelectWhere(
+	rowsAffected, err := uc.MovieInteractor.UpdatesSelectWhere(
 		movieRequest,
-		[]string{"is_published"},
+		[]string{"is_published", "publish_at"},
 		map[string]interface{}{"code": movie.Code, "user_id": userId},
 	)
+	if err != nil || rowsAffected == 0 {
+		return rowsAffected, err
+	}
+
+	if movie.IsPublished {
+		uc.OnPublish(movie.Code)
+	} else {
+		uc.InvalidateFeeds()
+	}
+
+	return rowsAffected, nil
+}
+
+func (uc *UseCase) OnPublish(code string) {
+	movie, err := uc.MovieInteractor.GetPreloadWhere(
+		[]string{"User"},
+		map[string]interface{}{"code": code},
+	)
+	if err != nil || movie.PublishedAt != nil {
+		return
+	}
+
+	notifySubscribers := movie.Visibility == VisibilityPublic || movie.Visibility == VisibilitySubscribers
+
+	rowsAffected, err := uc.MovieInteractor.UpdatesSelectWhere(
+		&Movie{PublishedAt: ptr.Time(time.Now())},
+		[]string{"published_at"},
+		map[string]interface{}{"id": movie.ID, "published_at": nil},
+	)
+	if err != nil || rowsAffected == 0 {
+		return
+	}
+
+	if notifySubscribers {
+		go uc.NotifySubscribers(*movie)
+	}
+}
+
+func (uc *UseCase) NotifySubscribers(movie Movie) {
+	subscribers, err := uc.SubscriptionUseCase.GetSubscribers(movie.UserId)
+	if err != nil {
+		return
+	}
+
+	var subscriberIds []uint
+	for _, subscriber := range subscribers {
+		subscriberIds = append(subscriberIds, subscriber.ID)
+	}
+	err = uc.NotificationUseCase.Notify(
+		notification.Notification{Type: notification.TypeNewMovie, ActorId: &movie.UserId, MovieId: &movie.ID},
+		subscriberIds,
+	)
+	if err != nil {
+		log.Println("movie: notify subscribers:", err)
+	}
+}
+
+func (uc *UseCase) RunPublishScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		uc.PublishScheduled()
+	}
+}
+
+func (uc *UseCase) PublishScheduled() {
+	movies, err := uc.MovieInteractor.GetScheduledBefore(time.Now())
+	if err != nil {
+		return
+	}
+
+	for _, movie := range *movies {
+		rowsAffected, err := uc.MovieInteractor.UpdatesSelectWhere(
+			&Movie{IsPublished: true},
+			[]string{"is_published", "publish_at"},
+			map[string]interface{}{"id": movie.ID, "is_published": false},
+		)
+		if err != nil || rowsAffected == 0 {
+			continue
+		}
+
+		uc.OnPublish(movie.Code)
+	}
+}
+
+func (uc *UseCase) RunTrendingUpdater(interval time.Duration) {
+	if err := uc.UpdateTrending(); err != nil {
+		log.Println(err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := uc.UpdateTrending(); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// UpdateTrending recomputes trending scores from recent views, comments and
+// likes.
+func (uc *UseCase) UpdateTrending() error {
+	now := time.Now()
+	since := now.Add(-trendingWindow)
+
+	scores, err := uc.MovieInteractor.GetDecayedCounts("views", "deleted_at IS NULL", now, since, trendingHalfLife)
+	if err != nil {
+		return err
+	}
+
+	comments, err := uc.MovieInteractor.GetDecayedCounts("comments", "deleted_at IS NULL", now, since, trendingHalfLife)
+	if err != nil {
+		return err
+	}
+	for movieId, count := range comments {
+		scores[movieId] += count * trendingCommentWeight
+	}
+
+	likes, err := uc.MovieInteractor.GetDecayedCounts("reactions", "type = 'like'", now, since, trendingHalfLife)
+	if err != nil {
+		return err
+	}
+	for movieId, count := range likes {
+		scores[movieId] += count * trendingLikeWeight
+	}
+
+	return uc.MovieInteractor.UpdateTrendingScores(scores)
 }
 
 func (uc *UseCase) Get(userId *uint, code string) (*GetResponse, error) {
@@ -522,7 +1246,7 @@ func (uc *UseCase) Get(userId *uint, code string) (*GetResponse, error) {
 		return nil, err
 	}
 
-	if movie.IsPublished || (userId != nil && movie.UserId == *userId) {
+	if uc.HasAccess(userId, movie) {
 		response := NewGetResponse(movie)
 
 		return response, nil
@@ -531,18 +1255,20 @@ func (uc *UseCase) Get(userId *uint, code string) (*GetResponse, error) {
 	return nil, errors.New("not allowed")
 }
 
-func (uc *UseCase) GetPublic(userId *uint, code string, userIp net.IP) (*GetResponse, error) {
+// GetPublic returns the movie for watching. With a playlist ID the response
+// also has the movie's place in that playlist.
+func (uc *UseCase) GetPublic(userId *uint, code string, client view.Client, playlistId uint) (*GetResponse, error) {
 	movie, err := uc.MovieInteractor.Get(code)
 	if err != nil {
 		return nil, err
 	}
 
-	if movie.IsPublished || (userId != nil && movie.UserId == *userId) {
+	if uc.HasAccess(userId, movie) {
 		response := NewGetResponse(movie)
 
-		viewsCount, err := uc.ViewUseCase.GetCount(movie.ID)
-		if err == nil {
-			response.Views = viewsCount
+		chapters, err := uc.ChapterUseCase.GetMultiple(movie.ID, movie.Duration)
+		if err == nil && len(chapters) > 0 {
+			response.Chapters = chapters
 		}
 
 		if userId != nil {
@@ -550,19 +1276,56 @@ func (uc *UseCase) GetPublic(userId *uint, code string, userIp net.IP) (*GetResp
 			if subscription != nil {
 				response.Subscribed = ptr.Bool(subscription.ID > 0)
 			}
+
+			response.Reaction = uc.ReactionUseCase.GetType(*userId, movie.ID)
+			response.ResumePosition = uc.HistoryUseCase.GetResumePosition(*userId, movie.ID)
 		}
 
-		view, err := uc.ViewUseCase.Add(movie.ID, userId, userIp)
-		if err == nil {
-			uc.MovieInteractor.AppendAssociation(&Movie{ID: movie.ID}, "Views", view)
+		if playlistId > 0 && uc.PlaylistUseCase != nil {
+			playlistContext, err := uc.PlaylistUseCase.GetContext(userId, playlistId, movie.ID)
+			if err == nil {
+				response.Playlist = playlistContext
+			}
 		}
 
+		uc.ViewUseCase.Start(movie.ID, userId, client)
+
 		return response, nil
 	}
 
 	return nil, errors.New("not allowed")
 }
 
+func (uc *UseCase) GetChaptersVtt(userId *uint, code string) ([]byte, error) {
+	movie, err := uc.MovieInteractor.GetSelectWhere(
+		[]string{"ID", "IsPublished", "Visibility", "UserId", "Duration"},
+		map[string]interface{}{"code": code},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if !uc.HasAccess(userId, movie) {
+		return nil, errors.New("not allowed")
+	}
+
+	chapters, err := uc.ChapterUseCase.GetMultiple(movie.ID, movie.Duration)
+	if err != nil {
+		return nil, err
+	}
+
+	var vttChapters []webvtt.Chapter
+	for _, chapter := range chapters {
+		vttChapters = append(vttChapters, webvtt.Chapter{
+			Title: chapter.Title,
+			Start: chapter.Start,
+			End:   chapter.End,
+		})
+	}
+
+	return webvtt.CreateChapters(vttChapters), nil
+}
+
 func (uc *UseCase) IsMovieOwner(userId uint, code string) bool {
 	_, err := uc.MovieInteractor.GetSelectWhere(
 		"id",
@@ -579,16 +1342,75 @@ func (uc *UseCase) IsMovieOwner(userId uint, code string) bool {
 }
 
 func (uc *UseCase) CheckMovieAccess(userId *uint, code string) bool {
+	_, err := uc.GetAccessible(userId, code)
+
+	return err == nil
+}
+
+// GetAccessible returns the movie ID and access fields if the user may
+// watch the movie.
+func (uc *UseCase) GetAccessible(userId *uint, code string) (*Movie, error) {
 	movie, err := uc.MovieInteractor.GetSelectWhere(
-		[]string{"IsPublished", "UserId"},
+		[]string{"ID", "IsPublished", "Visibility", "UserId"},
 		map[string]interface{}{"code": code},
 	)
 	if err != nil {
+		return nil, err
+	}
+
+	if !uc.HasAccess(userId, movie) {
+		return nil, ErrNotAllowed
+	}
+
+	return movie, nil
+}
+
+// GetFileAccess checks the access to the files of the movie, like previews
+// and thumbnails. Only files of published public movies may be cached by
+// shared caches.
+func (uc *UseCase) GetFileAccess(userId *uint, code string) (bool, error) {
+	movie, err := uc.GetAccessible(userId, code)
+	if err != nil {
+		return false, err
+	}
+
+	return movie.IsPublished && movie.Visibility == VisibilityPublic, nil
+}
+
+// HasAccess reports whether the user (nil for guests) may watch the movie.
+// The owner always has access, everyone else only to published movies
+// that match the movie visibility level. WhereAccessible checks the same
+// for lists in SQL.
+func (uc *UseCase) HasAccess(userId *uint, movie *Movie) bool {
+	if userId != nil && movie.UserId == *userId {
+		return true
+	}
+
+	if !movie.IsPublished {
 		return false
 	}
 
-	if movie.IsPublished || (userId != nil && movie.UserId == *userId) {
+	switch movie.Visibility {
+	case VisibilityPublic, VisibilityUnlisted:
 		return true
+	case VisibilitySubscribers:
+		if userId == nil {
+			return false
+		}
+
+		subscription, err := uc.SubscriptionUseCase.Get(*userId, movie.UserId)
+		return err == nil && subscription != nil && subscription.ID > 0
+	case VisibilityPrivate:
+		if userId == nil {
+			return false
+		}
+
+		count, err := uc.MovieInteractor.CountAssociationWhere(
+			&Movie{ID: movie.ID},
+			"SharedWith",
+			map[string]interface{}{"users.id": *userId},
+		)
+		return err == nil && count > 0
 	}
 
 	return false
@@ -610,25 +1432,37 @@ func (uc *UseCase) CheckByUser(userId uint, code string) bool {
 	return true
 }
 
-func (uc *UseCase) GetMultipleByUserId(userId uint, pagination *pagination.Pagination, sorting *sorting.Sort) ([]*GetForUserResponse, error) {
-	if pagination.Limit > 20 || pagination.Limit == -1 {
-		pagination.Limit = 20
+// NewSortKey maps the requested sort key to its column, views and comments
+// are sorted by the counters kept on the movie row.
+func NewSortKey(sorting *sorting.Sort) pagination.Key {
+	if _, ok := sortColumns[sorting.SortBy]; !ok {
+		return pagination.Key{SortBy: "created_at", Column: sortColumns["created_at"], IdColumn: "movies.id", Desc: true}
 	}
 
-	order := ""
-	if slices.Contains([]string{"created_at"}, sorting.SortBy) {
-		order = fmt.Sprintf("%s %s", sorting.SortBy, sorting.SortVal)
-	} else {
-		order = "created_at desc"
+	return pagination.Key{
+		SortBy:   sorting.SortBy,
+		Column:   sortColumns[sorting.SortBy],
+		IdColumn: "movies.id",
+		Desc:     sorting.SortVal == "desc",
 	}
+}
 
-	movies, err := uc.MovieInteractor.GetMultipleByUserId(userId, pagination, order)
+func (uc *UseCase) GetMultipleByUserId(userId uint, pagination *pagination.Pagination, sorting *sorting.Sort) ([]*GetForUserResponse, error) {
+	sortKey := NewSortKey(sorting)
+	movies, err := uc.MovieInteractor.GetMultipleByUserId(userId, pagination, pagination.OrderBy(sortKey))
 	if err != nil {
 		return nil, err
 	}
+	*movies = (*movies)[:pagination.Trim(*movies, func(i int) (interface{}, uint) {
+		return (*movies)[i].SortValue(sortKey.SortBy)
+	})]
 
-	if len(*movies) == 0 {
-		return nil, err
+	if pagination.WithTotal {
+		total, err := uc.MovieInteractor.GetWhereCount(map[string]interface{}{"user_id": userId})
+		if err != nil {
+			return nil, err
+		}
+		pagination.Total = &total
 	}
 
 	var moviesPayload []*GetForUserResponse
@@ -651,86 +1485,174 @@ func (uc *UseCase) GetForUser(userId uint, code string) (*GetForUserResponse, er
 	return NewGetForUserResponse(movie), nil
 }
 
-func (uc *UseCase) GetMultiple(where interface{}, pagination *pagination.Pagination, sorting *sorting.Sort) ([]*GetResponse, error) {
-	if pagination.Limit > 20 || pagination.Limit == -1 {
-		pagination.Limit = 100
-	}
-
-	order := ""
-	if slices.Contains([]string{"created_at"}, sorting.SortBy) {
-		order = fmt.Sprintf("%s %s", sorting.SortBy, sorting.SortVal)
-	} else {
-		order = "created_at desc"
-	}
-
+func (uc *UseCase) GetMultiple(where interface{}, filter *Filter, pagination *pagination.Pagination, sorting *sorting.Sort) ([]*GetResponse, error) {
+	sortKey := NewSortKey(sorting)
 	movies, err := uc.MovieInteractor.GetPreloadWhereMultiple(
-		[]string{"Preview", "PreviewWebp", "DefaultPreview", "DefaultPreviewWebp", "WebVtt", "User", "User.Picture"},
+		[]string{"Preview", "PreviewWebp", "DefaultPreview", "DefaultPreviewWebp", "AnimatedPreview", "WebVtt", "Category", "Tags", "User", "User.Picture"},
 		where,
+		filter,
 		pagination,
-		order,
+		pagination.OrderBy(sortKey),
 	)
 	if err != nil {
 		return nil, err
 	}
+	*movies = (*movies)[:pagination.Trim(*movies, func(i int) (interface{}, uint) {
+		return (*movies)[i].SortValue(sortKey.SortBy)
+	})]
+
+	if pagination.WithTotal {
+		total, err := uc.MovieInteractor.GetFilterCount(where, filter)
+		if err != nil {
+			return nil, err
+		}
+		pagination.Total = &total
+	}
+
+	var moviesPayload []*GetResponse
+	for _, movie := range *movies {
+		moviesPayload = append(moviesPayload, NewGetResponse(&movie))
+	}
+
+	return moviesPayload, nil
+}
+
+func (uc *UseCase) GetMultipleByChannel(channelId uint, pagination *pagination.Pagination, sorting *sorting.Sort) ([]*GetResponse, error) {
+	return uc.GetMultiple(
+		map[string]interface{}{"is_published": 1, "visibility": VisibilityPublic, "user_id": channelId},
+		nil,
+		pagination,
+		sorting,
+	)
+}
 
-	if len(*movies) == 0 {
+func (uc *UseCase) GetMultiplePremieres(channelId uint, pagination *pagination.Pagination) ([]*GetResponse, error) {
+	movies, err := uc.MovieInteractor.GetPreloadWhereScheduledMultiple(
+		[]string{"Preview", "PreviewWebp", "DefaultPreview", "DefaultPreviewWebp", "AnimatedPreview", "Category", "Tags", "User", "User.Picture"},
+		map[string]interface{}{"status": StatusReady, "visibility": VisibilityPublic, "user_id": channelId},
+		time.Now(),
+		pagination,
+		pagination.OrderBy(premieresSortKey),
+	)
+	if err != nil {
 		return nil, err
 	}
+	*movies = (*movies)[:pagination.Trim(*movies, func(i int) (interface{}, uint) {
+		return (*movies)[i].SortValue("publish_at")
+	})]
 
 	var moviesPayload []*GetResponse
 	for _, movie := range *movies {
 		moviesPayload = append(moviesPayload, NewGetResponse(&movie))
 	}
 
-	var moviesIds []uint
-	for _, movie := range moviesPayload {
-		moviesIds = append(moviesIds, movie.ID)
+	return moviesPayload, nil
+}
+
+// NewFilter validates the filter request. If the requested tag doesn't
+// exist gorm.ErrRecordNotFound is returned as nothing can match it.
+func (uc *UseCase) NewFilter(filterRequest *FilterRequest) (*Filter, error) {
+	filter := &Filter{
+		CategoryId:   filterRequest.Category,
+		DurationFrom: filterRequest.DurationFrom,
+		DurationTo:   filterRequest.DurationTo,
+		DateFrom:     filterRequest.DateFrom,
+		DateTo:       filterRequest.DateTo,
+		ChannelId:    filterRequest.Channel,
+		Language:     filterRequest.Language,
+	}
+
+	if filter.CategoryId > 0 && !uc.CategoryUseCase.IsEnabled(filter.CategoryId) {
+		return nil, errors.New("invalid category")
 	}
 
-	viewsCounts, err := uc.ViewUseCase.GetMultipleCount(moviesIds)
-	if err == nil {
-		for key, movie := range moviesPayload {
-			if _, ok := viewsCounts[movie.ID]; ok {
-				moviesPayload[key].Views = viewsCounts[movie.ID]
-			}
+	if (filter.DurationFrom != nil && *filter.DurationFrom < 0) || (filter.DurationTo != nil && *filter.DurationTo < 0) {
+		return nil, errors.New("duration can't be negative")
+	}
+	if filter.DurationFrom != nil && filter.DurationTo != nil && *filter.DurationFrom > *filter.DurationTo {
+		return nil, errors.New("duration from is greater than duration to")
+	}
+
+	if filter.DateFrom != nil && filter.DateTo != nil && filter.DateFrom.After(*filter.DateTo) {
+		return nil, errors.New("date from is after date to")
+	}
+
+	if filterRequest.Quality != "" {
+		quality := video.GetQualityByCode(filterRequest.Quality)
+		if quality == nil || quality.Type == video.QualityTypeSkip {
+			return nil, errors.New("invalid quality")
 		}
+		filter.QualityId = quality.ID
 	}
 
-	if sorting.SortBy == "views" {
-		switch sorting.SortVal {
-		case "desc":
-			sort.Slice(moviesPayload, func(i, j int) bool {
-				return moviesPayload[i].Views > moviesPayload[j].Views
-			})
-			break
-		case "asc":
-			sort.Slice(moviesPayload, func(i, j int) bool {
-				return moviesPayload[i].Views < moviesPayload[j].Views
-			})
-			break
+	if filter.Language != "" && !languageRegexp.MatchString(filter.Language) {
+		return nil, errors.New("invalid language")
+	}
+
+	if filterRequest.Tag != "" {
+		movieTag, err := uc.TagUseCase.GetByName(filterRequest.Tag)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		} else if err != nil {
+			return nil, errors.New("invalid tag")
 		}
+		filter.TagId = movieTag.ID
 	}
 
-	return moviesPayload, nil
+	return filter, nil
 }
 
-func (uc *UseCase) GetMultipleByChannel(channelId uint, pagination *pagination.Pagination, sorting *sorting.Sort) ([]*GetResponse, error) {
+func (uc *UseCase) GetMultiplePublic(filter *Filter, pagination *pagination.Pagination, sorting *sorting.Sort) ([]*GetResponse, error) {
 	return uc.GetMultiple(
-		map[string]interface{}{"is_published": 1, "user_id": channelId},
+		map[string]interface{}{"is_published": 1, "visibility": VisibilityPublic},
+		filter,
 		pagination,
 		sorting,
 	)
 }
 
-func (uc *UseCase) GetMultiplePublic(pagination *pagination.Pagination, sorting *sorting.Sort) ([]*GetResponse, error) {
-	return uc.GetMultiple(map[string]interface{}{"is_published": 1}, pagination, sorting)
+// GetMultipleByTag lists tag page movies, the most viewed first by default.
+func (uc *UseCase) GetMultipleByTag(tagName string, pagination *pagination.Pagination, sorting *sorting.Sort) ([]*GetResponse, error) {
+	if sorting.SortBy == "" {
+		sorting.SortBy = "views"
+		sorting.SortVal = "desc"
+	}
+
+	filter, err := uc.NewFilter(&FilterRequest{Tag: tagName})
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.GetMultiplePublic(filter, pagination, sorting)
 }
 
-func (uc *UseCase) GetMultipleSubscribed(userId uint, pagination *pagination.Pagination) ([]*GetResponse, error) {
-	if pagination.Limit > 20 || pagination.Limit == -1 {
-		pagination.Limit = 20
+func (uc *UseCase) GetTag(tagName string) (*tag.GetResponse, error) {
+	movieTag, err := uc.TagUseCase.GetByName(tagName)
+	if err != nil {
+		return nil, err
+	}
+
+	moviesCount, err := uc.MovieInteractor.GetTagMoviesCount(movieTag.ID)
+	if err != nil {
+		return nil, err
 	}
 
+	return &tag.GetResponse{
+		Name:        movieTag.Name,
+		MoviesCount: moviesCount,
+	}, nil
+}
+
+func (uc *UseCase) GetMultiplePublicByIds(ids []uint) ([]*GetResponse, error) {
+	return uc.GetMultiple(
+		map[string]interface{}{"id": ids, "is_published": 1, "visibility": VisibilityPublic},
+		nil,
+		&pagination.Pagination{Limit: -1, Offset: -1},
+		&sorting.Sort{},
+	)
+}
+
+func (uc *UseCase) GetMultipleSubscribed(userId uint, pagination *pagination.Pagination) ([]*GetResponse, error) {
 	subscriptions, err := uc.SubscriptionUseCase.GetAll(userId)
 	if err != nil {
 		return nil, err
@@ -741,18 +1663,31 @@ func (uc *UseCase) GetMultipleSubscribed(userId uint, pagination *pagination.Pag
 		usersIds = append(usersIds, subscription.ChannelID)
 	}
 
+	where := map[string]interface{}{
+		"is_published": 1,
+		"visibility":   []string{VisibilityPublic, VisibilitySubscribers},
+		"user_id":      usersIds,
+	}
 	movies, err := uc.MovieInteractor.GetPreloadWhereMultiple(
-		[]string{"Preview", "PreviewWebp", "DefaultPreview", "DefaultPreviewWebp", "WebVtt", "User", "User.Picture"},
-		map[string]interface{}{"is_published": 1, "user_id": usersIds},
+		[]string{"Preview", "PreviewWebp", "DefaultPreview", "DefaultPreviewWebp", "AnimatedPreview", "WebVtt", "Category", "Tags", "User", "User.Picture"},
+		where,
+		nil,
 		pagination,
-		"created_at desc",
+		pagination.OrderBy(NewSortKey(&sorting.Sort{})),
 	)
 	if err != nil {
 		return nil, err
 	}
+	*movies = (*movies)[:pagination.Trim(*movies, func(i int) (interface{}, uint) {
+		return (*movies)[i].SortValue("created_at")
+	})]
 
-	if len(*movies) == 0 {
-		return nil, err
+	if pagination.WithTotal {
+		total, err := uc.MovieInteractor.GetWhereCount(where)
+		if err != nil {
+			return nil, err
+		}
+		pagination.Total = &total
 	}
 
 	var moviesPayload []*GetResponse
@@ -760,23 +1695,255 @@ func (uc *UseCase) GetMultipleSubscribed(userId uint, pagination *pagination.Pag
 		moviesPayload = append(moviesPayload, NewGetResponse(&movie))
 	}
 
-	var moviesIds []uint
-	for _, movie := range moviesPayload {
-		moviesIds = append(moviesIds, movie.ID)
+	return moviesPayload, nil
+}
+
+// SetReaction likes or dislikes the movie, repeating the same reaction
+// changes nothing.
+func (uc *UseCase) SetReaction(userId uint, code, reactionType string) (*reaction.GetResponse, error) {
+	movie, err := uc.GetAccessible(&userId, code)
+	if err != nil {
+		return nil, err
 	}
 
-	viewsCounts, err := uc.ViewUseCase.GetMultipleCount(moviesIds)
-	if err == nil {
-		for key, movie := range moviesPayload {
-			if _, ok := viewsCounts[movie.ID]; ok {
-				moviesPayload[key].Views = viewsCounts[movie.ID]
-			}
+	if err = uc.ReactionUseCase.Set(userId, movie.ID, reactionType); err != nil {
+		return nil, err
+	}
+
+	return uc.GetReaction(userId, movie.ID)
+}
+
+func (uc *UseCase) DeleteReaction(userId uint, code string) (*reaction.GetResponse, error) {
+	movie, err := uc.GetAccessible(&userId, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = uc.ReactionUseCase.Delete(userId, movie.ID); err != nil {
+		return nil, err
+	}
+
+	return uc.GetReaction(userId, movie.ID)
+}
+
+func (uc *UseCase) GetReaction(userId, movieId uint) (*reaction.GetResponse, error) {
+	movie, err := uc.MovieInteractor.GetSelectWhere(
+		[]string{"likes_count", "dislikes_count"},
+		map[string]interface{}{"id": movieId},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reaction.GetResponse{
+		Likes:    movie.LikesCount,
+		Dislikes: movie.DislikesCount,
+		Reaction: uc.ReactionUseCase.GetType(userId, movieId),
+	}, nil
+}
+
+// GetMultipleLiked returns movies the user liked that they can still watch.
+func (uc *UseCase) GetMultipleLiked(userId uint, pagination *pagination.Pagination) ([]*GetResponse, error) {
+	movieIds, err := uc.ReactionUseCase.GetMultipleLiked(userId, WhereAccessible(&userId), pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.GetMultipleAccessibleByIds(&userId, movieIds)
+}
+
+// GetMultipleAccessibleByIds returns movies in the order of the IDs, leaving
+// out the ones the user can't watch anymore. The access is checked in the
+// same query that loads the movies.
+func (uc *UseCase) GetMultipleAccessibleByIds(userId *uint, movieIds []uint) ([]*GetResponse, error) {
+	if len(movieIds) == 0 {
+		return nil, nil
+	}
+
+	movies, err := uc.MovieInteractor.GetPreloadAccessibleMultiple(
+		[]string{"Preview", "PreviewWebp", "DefaultPreview", "DefaultPreviewWebp", "AnimatedPreview", "WebVtt", "Category", "Tags", "User", "User.Picture"},
+		userId,
+		movieIds,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	moviesById := make(map[uint]*Movie)
+	for key := range *movies {
+		moviesById[(*movies)[key].ID] = &(*movies)[key]
+	}
+
+	var moviesPayload []*GetResponse
+	for _, movieId := range movieIds {
+		if movie, ok := moviesById[movieId]; ok {
+			moviesPayload = append(moviesPayload, NewGetResponse(movie))
 		}
 	}
 
 	return moviesPayload, nil
 }
 
+// SaveProgress handles the player's heartbeat. It records the position in
+// seconds the user is at in the movie, a movie watched to the end leaves
+// the user's watch later list. For guests and users alike the watch time
+// counts a view and goes to the channel analytics.
+func (uc *UseCase) SaveProgress(userId *uint, code string, progressRequest *history.ProgressRequest, client view.Client) error {
+	movie, err := uc.MovieInteractor.GetSelectWhere(
+		[]string{"ID", "IsPublished", "Visibility", "UserId", "Duration"},
+		map[string]interface{}{"code": code},
+	)
+	if err != nil {
+		return err
+	}
+
+	if !uc.HasAccess(userId, movie) {
+		return errors.New("not allowed")
+	}
+
+	uc.ViewUseCase.Add(movie.ID, userId, client, progressRequest.Watched, movie.Duration)
+	uc.AnalyticsUseCase.Heartbeat(movie.ID, movie.UserId, userId, client, progressRequest.Position, progressRequest.Watched, movie.Duration)
+
+	if userId == nil {
+		return nil
+	}
+
+	if err = uc.HistoryUseCase.Save(*userId, movie.ID, progressRequest.Position, movie.Duration); err != nil {
+		return err
+	}
+
+	if history.IsFinished(progressRequest.Position, movie.Duration) && uc.PlaylistUseCase != nil {
+		return uc.PlaylistUseCase.RemoveWatched(*userId, movie.ID)
+	}
+
+	return nil
+}
+
+// GetMultipleHistory returns movies from the user's watch history with the
+// position to resume them from, last watched first.
+func (uc *UseCase) GetMultipleHistory(userId uint, pagination *pagination.Pagination) ([]*GetResponse, error) {
+	return uc.getMultipleFromHistory(userId, false, pagination)
+}
+
+// GetMultipleContinueWatching returns movies the user started and didn't
+// finish, last watched first.
+func (uc *UseCase) GetMultipleContinueWatching(userId uint, pagination *pagination.Pagination) ([]*GetResponse, error) {
+	return uc.getMultipleFromHistory(userId, true, pagination)
+}
+
+func (uc *UseCase) getMultipleFromHistory(userId uint, unfinished bool, pagination *pagination.Pagination) ([]*GetResponse, error) {
+	entries, err := uc.HistoryUseCase.GetMultiple(userId, unfinished, WhereAccessible(&userId), pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	var movieIds []uint
+	entriesByMovie := make(map[uint]history.Entry)
+	for _, entry := range entries {
+		movieIds = append(movieIds, entry.MovieId)
+		entriesByMovie[entry.MovieId] = entry
+	}
+
+	moviesPayload, err := uc.GetMultipleAccessibleByIds(&userId, movieIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, moviePayload := range moviesPayload {
+		entry := entriesByMovie[moviePayload.ID]
+		moviePayload.ResumePosition = entry.ResumePosition()
+		moviePayload.WatchedAt = &entry.WatchedAt
+	}
+
+	return moviesPayload, nil
+}
+
+func (uc *UseCase) GetMultipleTrending(pagination *pagination.Pagination) ([]*GetResponse, error) {
+	return uc.GetCachedFeed("trending", pagination, trendingFeedTTL, func() ([]*GetResponse, error) {
+		return uc.GetMultiple(
+			map[string]interface{}{"is_published": 1, "visibility": VisibilityPublic},
+			nil,
+			pagination,
+			&sorting.Sort{SortBy: "trending", SortVal: "desc"},
+		)
+	})
+}
+
+// GetMultipleForYou returns trending movies from the user's subscriptions
+// and categories they watched recently, except already watched ones. Users
+// without either get the trending feed.
+func (uc *UseCase) GetMultipleForYou(userId uint, pagination *pagination.Pagination) ([]*GetResponse, error) {
+	return uc.GetCachedFeed(fmt.Sprintf("for-you:%d", userId), pagination, forYouFeedTTL, func() ([]*GetResponse, error) {
+		subscriptions, err := uc.SubscriptionUseCase.GetAll(userId)
+		if err != nil {
+			return nil, err
+		}
+
+		filter := &Filter{}
+		for _, subscription := range subscriptions {
+			filter.ChannelIds = append(filter.ChannelIds, subscription.ChannelID)
+		}
+
+		filter.ExcludeIds, filter.CategoryIds, err = uc.MovieInteractor.GetWatchedByUser(userId, time.Now().Add(-watchedPeriod))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(filter.ChannelIds) == 0 && len(filter.CategoryIds) == 0 {
+			filter = nil
+		}
+
+		return uc.GetMultiple(
+			map[string]interface{}{"is_published": 1, "visibility": VisibilityPublic},
+			filter,
+			pagination,
+			&sorting.Sort{SortBy: "trending", SortVal: "desc"},
+		)
+	})
+}
+
+// GetCachedFeed returns a feed page from the cache or loads it. The page
+// cursors and total are cached along with the movies.
+func (uc *UseCase) GetCachedFeed(name string, pagination *pagination.Pagination, ttl time.Duration, load func() ([]*GetResponse, error)) ([]*GetResponse, error) {
+	key := fmt.Sprintf("%s:%d:%d:%t:%v", name, pagination.Limit, pagination.Offset, pagination.WithTotal, pagination.Cursor)
+
+	uc.FeedsMutex.Lock()
+	cached, ok := uc.Feeds[key]
+	uc.FeedsMutex.Unlock()
+	if ok && time.Now().Before(cached.ExpiresAt) {
+		pagination.Total, pagination.Next, pagination.Prev = cached.Total, cached.Next, cached.Prev
+		return cached.Movies, nil
+	}
+
+	movies, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	uc.FeedsMutex.Lock()
+	if len(uc.Feeds) >= maxCachedFeeds {
+		uc.Feeds = make(map[string]cachedFeed)
+	}
+	uc.Feeds[key] = cachedFeed{
+		Movies:    movies,
+		Total:     pagination.Total,
+		Next:      pagination.Next,
+		Prev:      pagination.Prev,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	uc.FeedsMutex.Unlock()
+
+	return movies, nil
+}
+
+// InvalidateFeeds drops the cached feeds, so movies that were deleted or
+// are no longer public don't stay in them until the cache expires
+func (uc *UseCase) InvalidateFeeds() {
+	uc.FeedsMutex.Lock()
+	uc.Feeds = make(map[string]cachedFeed)
+	uc.FeedsMutex.Unlock()
+}
+
 func (uc *UseCase) GetMovieDetailSeo(movieCode string, r *http.Request) (map[string]string, error) {
 	movie, err := uc.Get(nil, movieCode)
 	if err != nil {
@@ -795,9 +1962,14 @@ func (uc *UseCase) GetMovieDetailSeo(movieCode string, r *http.Request) (map[str
 	if err != nil {
 		return nil, err
 	}
-	return map[string]string{
+	seo := map[string]string{
 		"title":       movie.Name + " - " + siteName,
 		"description": movie.Description,
 		"image":       moviePreview,
-	}, nil
+	}
+	if movie.Visibility == VisibilityUnlisted {
+		seo["robots"] = "noindex"
+	}
+
+	return seo, nil
 }
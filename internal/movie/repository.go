@@ -3,8 +3,15 @@ package movie
 import (
 	"gorm.io/gorm"
 	"nine-dubz/internal/pagination"
+	"nine-dubz/internal/video"
+	"sort"
+	"strings"
+	"time"
 )
 
+// Movies updated by one statement of UpdateTrendingScores
+const trendingScoresBatch = 500
+
 type Repository struct {
 	DB *gorm.DB
 }
@@ -47,6 +54,40 @@ func (mr *Repository) AppendAssociation(movie *Movie, association string, append
 	return mr.DB.Model(movie).Association(association).Append(append)
 }
 
+func (mr *Repository) ReplaceAssociation(movie *Movie, association string, replace interface{}) error {
+	return mr.DB.Model(movie).Association(association).Replace(replace)
+}
+
+// ReplaceVideos puts the new renditions and thumbnails on the movie in one
+// transaction. Columns in clear are set to NULL, they're thumbnails that
+// weren't generated this time and would point to deleted files otherwise.
+func (mr *Repository) ReplaceVideos(movie *Movie, videos []*video.Video, clear []string) error {
+	return mr.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Movie{ID: movie.ID}).Association("Videos").Replace(videos); err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ?", movie.ID).Updates(movie).Error; err != nil {
+			return err
+		}
+
+		for _, column := range clear {
+			if err := tx.Model(&Movie{ID: movie.ID}).UpdateColumn(column, nil).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (mr *Repository) CountAssociationWhere(movie *Movie, association string, where interface{}) (int64, error) {
+	result := mr.DB.Model(movie).Where(where).Association(association)
+	count := result.Count()
+
+	return count, result.Error
+}
+
 func (mr *Repository) Get(code string) (*Movie, error) {
 	movie := &Movie{}
 	result := mr.DB.
@@ -57,6 +98,9 @@ func (mr *Repository) Get(code string) (*Movie, error) {
 		Preload("PreviewWebp").
 		Preload("DefaultPreview").
 		Preload("DefaultPreviewWebp").
+		Preload("AnimatedPreview").
+		Preload("Category").
+		Preload("Tags").
 		Preload("WebVtt").
 		Preload("User").
 		Preload("User.Picture").
@@ -74,7 +118,11 @@ func (mr *Repository) GetWhere(where interface{}) (*Movie, error) {
 		Preload("PreviewWebp").
 		Preload("DefaultPreview").
 		Preload("DefaultPreviewWebp").
+		Preload("AnimatedPreview").
+		Preload("Category").
+		Preload("Tags").
 		Preload("WebVtt").
+		Preload("SharedWith").
 		Where(where).
 		First(&movie)
 
@@ -95,6 +143,18 @@ func (mr *Repository) GetWhereCount(where interface{}) (int64, error) {
 	return count, result.Error
 }
 
+func (mr *Repository) GetFilterCount(whereQuery interface{}, filter *Filter) (int64, error) {
+	var count int64
+	result := mr.DB.Model(&Movie{})
+	if filter != nil {
+		result = mr.applyFilter(result, filter)
+	}
+
+	result = result.Where(whereQuery).Count(&count)
+
+	return count, result.Error
+}
+
 func (mr *Repository) GetMultipleByUserId(userId uint, pagination *pagination.Pagination, order string) (*[]Movie, error) {
 	movies := &[]Movie{}
 	result := mr.DB.
@@ -104,10 +164,13 @@ func (mr *Repository) GetMultipleByUserId(userId uint, pagination *pagination.Pa
 		Preload("PreviewWebp").
 		Preload("DefaultPreview").
 		Preload("DefaultPreviewWebp").
+		Preload("AnimatedPreview").
+		Preload("Category").
+		Preload("Tags").
 		Preload("WebVtt").
+		Preload("SharedWith").
 		Where("user_id = ?", userId).
-		Limit(pagination.Limit).
-		Offset(pagination.Offset).
+		Scopes(pagination.Scope).
 		Order(order).
 		Find(&movies)
 
@@ -123,12 +186,14 @@ func (mr *Repository) GetMultiple(pagination *pagination.Pagination, order strin
 		Preload("PreviewWebp").
 		Preload("DefaultPreview").
 		Preload("DefaultPreviewWebp").
+		Preload("AnimatedPreview").
+		Preload("Category").
+		Preload("Tags").
 		Preload("WebVtt").
 		Preload("User").
 		Preload("User.Picture").
-		Limit(pagination.Limit).
-		Offset(pagination.Offset).
-		Where("is_published = 1").
+		Scopes(pagination.Scope).
+		Where("is_published = 1 AND visibility = ?", VisibilityPublic).
 		Order(order).
 		Find(&movies)
 
@@ -144,11 +209,13 @@ func (mr *Repository) GetWhereMultiple(where map[string]interface{}, pagination
 		Preload("PreviewWebp").
 		Preload("DefaultPreview").
 		Preload("DefaultPreviewWebp").
+		Preload("AnimatedPreview").
+		Preload("Category").
+		Preload("Tags").
 		Preload("WebVtt").
 		Preload("User").
 		Preload("User.Picture").
-		Limit(pagination.Limit).
-		Offset(pagination.Offset).
+		Scopes(pagination.Scope).
 		Where(where).
 		Order(order).
 		Find(&movies)
@@ -168,7 +235,146 @@ func (mr *Repository) GetPreloadWhere(preloads []string, whereQuery interface{})
 	return movie, result.Error
 }
 
-func (mr *Repository) GetPreloadWhereMultiple(preloads []string, whereQuery interface{}, pagination *pagination.Pagination, order string) (*[]Movie, error) {
+func (mr *Repository) GetPreloadWhereMultiple(preloads []string, whereQuery interface{}, filter *Filter, pagination *pagination.Pagination, order string) (*[]Movie, error) {
+	movies := &[]Movie{}
+	result := mr.DB
+	for _, preload := range preloads {
+		result = result.Preload(preload)
+	}
+
+	if filter != nil {
+		result = mr.applyFilter(result, filter)
+	}
+
+	result = result.
+		Scopes(pagination.Scope).
+		Where(whereQuery).
+		Order(order).
+		Find(&movies)
+
+	return movies, result.Error
+}
+
+// GetPreloadAccessibleMultiple loads the movies the user may watch out of
+// the IDs
+func (mr *Repository) GetPreloadAccessibleMultiple(preloads []string, userId *uint, ids []uint) (*[]Movie, error) {
+	movies := &[]Movie{}
+	result := mr.DB
+	for _, preload := range preloads {
+		result = result.Preload(preload)
+	}
+
+	result = result.
+		Scopes(WhereAccessible(userId)).
+		Where("movies.id IN ?", ids).
+		Find(&movies)
+
+	return movies, result.Error
+}
+
+// WhereAccessible limits a query over movies to the ones the user (nil for
+// guests) may watch, it's the SQL form of HasAccess for whole lists.
+func WhereAccessible(userId *uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if userId == nil {
+			return db.Where(
+				"movies.is_published = ? AND movies.visibility IN ?",
+				true, []string{VisibilityPublic, VisibilityUnlisted},
+			)
+		}
+
+		return db.Where(
+			"(movies.user_id = ? OR (movies.is_published = ? AND ("+
+				"movies.visibility IN ? OR "+
+				"(movies.visibility = ? AND EXISTS (SELECT 1 FROM subscriptions WHERE subscriptions.channel_id = movies.user_id AND subscriptions.user_id = ? AND subscriptions.deleted_at IS NULL)) OR "+
+				"(movies.visibility = ? AND EXISTS (SELECT 1 FROM movie_shares WHERE movie_shares.movie_id = movies.id AND movie_shares.user_id = ?)))))",
+			*userId, true,
+			[]string{VisibilityPublic, VisibilityUnlisted},
+			VisibilitySubscribers, *userId,
+			VisibilityPrivate, *userId,
+		)
+	}
+}
+
+func (mr *Repository) applyFilter(db *gorm.DB, filter *Filter) *gorm.DB {
+	if filter.TagId > 0 {
+		db = db.Where(
+			"movies.id IN (?)",
+			mr.DB.Table("movie_tags").Select("movie_id").Where("tag_id = ?", filter.TagId),
+		)
+	}
+	if filter.CategoryId > 0 {
+		db = db.Where("movies.category = ?", filter.CategoryId)
+	}
+	if filter.DurationFrom != nil {
+		db = db.Where("movies.duration >= ?", *filter.DurationFrom)
+	}
+	if filter.DurationTo != nil {
+		db = db.Where("movies.duration <= ?", *filter.DurationTo)
+	}
+	if filter.DateFrom != nil {
+		db = db.Where("movies.created_at >= ?", *filter.DateFrom)
+	}
+	if filter.DateTo != nil {
+		db = db.Where("movies.created_at <= ?", *filter.DateTo)
+	}
+	if filter.ChannelId > 0 {
+		db = db.Where("movies.user_id = ?", filter.ChannelId)
+	}
+	if filter.QualityId > 0 {
+		db = db.Where(
+			"movies.id IN (?)",
+			mr.DB.
+				Table("movie_videos").
+				Select("movie_videos.movie_id").
+				Joins("JOIN videos ON videos.id = movie_videos.video_id AND videos.deleted_at IS NULL").
+				Where("videos.quality = ?", filter.QualityId),
+		)
+	}
+	if filter.Language != "" {
+		db = db.Where("movies.language = ?", filter.Language)
+	}
+	if len(filter.ChannelIds) > 0 || len(filter.CategoryIds) > 0 {
+		var conditions []string
+		var args []interface{}
+		if len(filter.ChannelIds) > 0 {
+			conditions = append(conditions, "movies.user_id IN ?")
+			args = append(args, filter.ChannelIds)
+		}
+		if len(filter.CategoryIds) > 0 {
+			conditions = append(conditions, "movies.category IN ?")
+			args = append(args, filter.CategoryIds)
+		}
+		db = db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+	if len(filter.ExcludeIds) > 0 {
+		db = db.Where("movies.id NOT IN ?", filter.ExcludeIds)
+	}
+
+	return db
+}
+
+func (mr *Repository) GetTagMoviesCount(tagId uint) (int64, error) {
+	var count int64
+	result := mr.DB.
+		Model(&Movie{}).
+		Where("is_published = 1 AND visibility = ?", VisibilityPublic).
+		Where("id IN (?)", mr.DB.Table("movie_tags").Select("movie_id").Where("tag_id = ?", tagId)).
+		Count(&count)
+
+	return count, result.Error
+}
+
+func (mr *Repository) GetScheduledBefore(publishAt time.Time) (*[]Movie, error) {
+	movies := &[]Movie{}
+	result := mr.DB.
+		Where("is_published = 0 AND status = ? AND publish_at <= ?", StatusReady, publishAt).
+		Find(&movies)
+
+	return movies, result.Error
+}
+
+func (mr *Repository) GetPreloadWhereScheduledMultiple(preloads []string, whereQuery interface{}, publishAfter time.Time, pagination *pagination.Pagination, order string) (*[]Movie, error) {
 	movies := &[]Movie{}
 	result := mr.DB
 	for _, preload := range preloads {
@@ -176,11 +382,97 @@ func (mr *Repository) GetPreloadWhereMultiple(preloads []string, whereQuery inte
 	}
 
 	result = result.
-		Limit(pagination.Limit).
-		Offset(pagination.Offset).
+		Scopes(pagination.Scope).
 		Where(whereQuery).
+		Where("is_published = 0 AND publish_at > ?", publishAfter).
 		Order(order).
 		Find(&movies)
 
 	return movies, result.Error
 }
+
+// GetDecayedCounts counts rows of a movie activity table (views, comments,
+// reactions) matching the condition and created after since, each row weighs
+// half as much every halfLife.
+func (mr *Repository) GetDecayedCounts(table, condition string, now, since time.Time, halfLife time.Duration) (map[uint]float64, error) {
+	var stats []TrendingStat
+	result := mr.DB.
+		Table(table).
+		Select(
+			"movie_id, SUM(POW(0.5, TIMESTAMPDIFF(SECOND, created_at, ?) / ?)) AS score",
+			now, halfLife.Seconds(),
+		).
+		Where("created_at > ?", since).
+		Where(condition).
+		Group("movie_id").
+		Scan(&stats)
+
+	counts := make(map[uint]float64)
+	for _, stat := range stats {
+		counts[stat.MovieId] = stat.Score
+	}
+
+	return counts, result.Error
+}
+
+// UpdateTrendingScores sets the scores with one UPDATE per batch of movies,
+// movies that are no longer trending get zero.
+func (mr *Repository) UpdateTrendingScores(scores map[uint]float64) error {
+	movieIds := make([]uint, 0, len(scores))
+	for movieId := range scores {
+		movieIds = append(movieIds, movieId)
+	}
+	sort.Slice(movieIds, func(i, j int) bool {
+		return movieIds[i] < movieIds[j]
+	})
+
+	for start := 0; start < len(movieIds); start += trendingScoresBatch {
+		batch := movieIds[start:min(start+trendingScoresBatch, len(movieIds))]
+
+		cases := make([]string, 0, len(batch))
+		values := make([]interface{}, 0, len(batch)*2)
+		for _, movieId := range batch {
+			cases = append(cases, "WHEN ? THEN ?")
+			values = append(values, movieId, scores[movieId])
+		}
+
+		err := mr.DB.Model(&Movie{}).
+			Where("id IN ?", batch).
+			UpdateColumn("trending_score", gorm.Expr("CASE id "+strings.Join(cases, " ")+" END", values...)).
+			Error
+		if err != nil {
+			return err
+		}
+	}
+
+	query := mr.DB.Model(&Movie{}).Where("trending_score > 0")
+	if len(movieIds) > 0 {
+		query = query.Where("id NOT IN ?", movieIds)
+	}
+
+	return query.UpdateColumn("trending_score", 0).Error
+}
+
+// GetWatchedByUser returns movies the user viewed after since and their
+// categories.
+func (mr *Repository) GetWatchedByUser(userId uint, since time.Time) ([]uint, []uint, error) {
+	var movieIds []uint
+	result := mr.DB.
+		Table("views").
+		Distinct("movie_id").
+		Where("user_id = ? AND created_at > ? AND deleted_at IS NULL", userId, since).
+		Limit(maxWatchedMovies).
+		Pluck("movie_id", &movieIds)
+	if result.Error != nil || len(movieIds) == 0 {
+		return nil, nil, result.Error
+	}
+
+	var categoryIds []uint
+	result = mr.DB.
+		Model(&Movie{}).
+		Distinct("category").
+		Where("id IN ?", movieIds).
+		Pluck("category", &categoryIds)
+
+	return movieIds, categoryIds, result.Error
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"nine-dubz/internal/pagination"
+	"nine-dubz/internal/reaction"
 	"nine-dubz/internal/sorting"
 )
 
@@ -31,6 +32,9 @@ func (h *Handler) Routes(r chi.Router) {
 						With(middleware.RequestSize(2<<20)).
 						Post("/", h.UpdateHandler)
 					r.Get("/", h.GetForUserHandler)
+					r.Route("/trim", func(r chi.Router) {
+						r.Post("/", h.TrimHandler)
+					})
 				})
 				r.Route("/upload", func(r chi.Router) {
 					r.Get("/", h.UploadVideoHandler)
@@ -48,6 +52,33 @@ func (h *Handler) Routes(r chi.Router) {
 			r.
 				With(h.UserHandler.TryToGetUserId).
 				Get("/", h.GetHandler)
+
+			r.Route("/chapters", func(r chi.Router) {
+				r.
+					With(h.UserHandler.TryToGetUserId).
+					Get("/", h.GetChaptersHandler)
+			})
+
+			r.
+				With(h.UserHandler.IsAuthorized).
+				Group(func(r chi.Router) {
+					r.Post("/like", h.SetReactionHandler(reaction.TypeLike))
+					r.Post("/dislike", h.SetReactionHandler(reaction.TypeDislike))
+					r.Delete("/reaction", h.DeleteReactionHandler)
+				})
+		})
+		r.Route("/liked", func(r chi.Router) {
+			r.
+				With(pagination.SetPaginationContextMiddleware).
+				With(h.UserHandler.IsAuthorized).
+				Get("/", h.GetMultipleLikedHandler)
+		})
+		r.Route("/history", func(r chi.Router) {
+			r.
+				With(pagination.SetPaginationContextMiddleware).
+				With(h.UserHandler.IsAuthorized).
+				Get("/", h.GetMultipleHistoryHandler)
+			h.HistoryHandler.Routes(r)
 		})
 		r.Route("/stream/{movieCode}", func(r chi.Router) {
 			r.
@@ -58,19 +89,46 @@ func (h *Handler) Routes(r chi.Router) {
 				With(h.UserHandler.TryToGetUserId).
 				Head("/", h.StreamFile)
 		})
+		r.
+			With(pagination.SetPaginationContextMiddleware).
+			Route("/feed", func(r chi.Router) {
+				r.Get("/trending", h.GetMultipleTrendingHandler)
+				r.
+					With(h.UserHandler.IsAuthorized).
+					Get("/for-you", h.GetMultipleForYouHandler)
+				r.
+					With(h.UserHandler.IsAuthorized).
+					Get("/continue-watching", h.GetMultipleContinueWatchingHandler)
+			})
 		r.Route("/subscription", func(r chi.Router) {
 			r.
 				With(pagination.SetPaginationContextMiddleware).
 				With(h.UserHandler.IsAuthorized).
 				Get("/", h.GetMultipleSubscribedHandler)
 		})
+		r.Route("/tag/{tagName}", func(r chi.Router) {
+			r.Get("/", h.GetTagHandler)
+			r.
+				With(pagination.SetPaginationContextMiddleware).
+				With(sorting.SetSortContextMiddleware).
+				Get("/movies", h.GetMultipleByTagHandler)
+		})
 		r.Route("/channel", func(r chi.Router) {
 			r.
 				With(pagination.SetPaginationContextMiddleware).
 				With(sorting.SetSortContextMiddleware).
 				Route("/{channelId}", func(r chi.Router) {
 					r.Get("/", h.GetMultipleByChannelHandler)
+					r.Get("/premieres", h.GetMultiplePremieresHandler)
 				})
 		})
 	})
 }
+
+// HeartbeatRoutes are mounted outside the API rate limit, every player
+// posts them periodically
+func (h *Handler) HeartbeatRoutes(r chi.Router) {
+	r.
+		With(h.UserHandler.TryToGetUserId).
+		Post("/movie/{movieCode}/progress", h.SaveProgressHandler)
+}
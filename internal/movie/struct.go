@@ -3,39 +3,83 @@ package movie
 import (
 	"golang.org/x/net/context"
 	"gorm.io/gorm"
+	"math"
 	"mime/multipart"
 	"nine-dubz/internal/category"
+	"nine-dubz/internal/chapter"
 	"nine-dubz/internal/file"
+	"nine-dubz/internal/tag"
 	"nine-dubz/internal/user"
 	"nine-dubz/internal/video"
 	"nine-dubz/internal/view"
+	"nine-dubz/pkg/ffmpegthumbs"
+	"strconv"
 	"time"
 )
 
 type Movie struct {
 	gorm.Model
-	ID                   uint              `json:"ID"`
-	Status               string            `json:"-" gorm:"default:'uploading'"`
-	CreatedAt            time.Time         `json:"createdAt"`
-	Code                 string            `json:"code"`
-	IsPublished          bool              `json:"-" gorm:"default:false"`
-	Description          string            `json:"description"`
-	PreviewId            *uint             `json:"-"`
-	Preview              *file.File        `json:"preview,omitempty" gorm:"foreignKey:PreviewId;references:ID;constraint:OnDelete:SET NULL;"`
-	PreviewWebpId        *uint             `json:"-"`
-	PreviewWebp          *file.File        `json:"previewWebp,omitempty" gorm:"foreignKey:PreviewWebpId;references:ID;constraint:OnDelete:SET NULL;"`
-	DefaultPreviewId     *uint             `json:"-"`
-	DefaultPreview       *file.File        `json:"defaultPreview" gorm:"foreignKey:DefaultPreviewId;references:ID;constraint:OnDelete:SET NULL;"`
-	DefaultPreviewWebpId *uint             `json:"-"`
-	DefaultPreviewWebp   *file.File        `json:"defaultPreviewWebp" gorm:"foreignKey:DefaultPreviewWebpId;references:ID;constraint:OnDelete:SET NULL;"`
-	Name                 string            `json:"name"`
-	Videos               []video.Video     `gorm:"many2many:movie_videos"`
-	UserId               uint              `json:"-"`
-	User                 user.User         `json:"-" gorm:"foreignKey:UserId;references:ID"`
-	Category             category.Category `gorm:"default:1;"`
-	WebVttId             *uint             `json:"-"`
-	WebVtt               *file.File        `json:"webVtt" gorm:"foreignKey:WebVttId;references:ID;constraint:OnDelete:SET NULL;"`
-	Views                []view.View       `gorm:"-"`
+	ID                   uint               `json:"ID"`
+	Status               string             `json:"-" gorm:"default:'uploading'"`
+	CreatedAt            time.Time          `json:"createdAt"`
+	Code                 string             `json:"code"`
+	IsPublished          bool               `json:"-" gorm:"default:false"`
+	Visibility           string             `json:"-" gorm:"default:'public';index"`
+	SharedWith           []user.User        `json:"-" gorm:"many2many:movie_shares"`
+	PublishAt            *time.Time         `json:"-" gorm:"index"`
+	PublishedAt          *time.Time         `json:"-"`
+	Description          string             `json:"description" gorm:"index:idx_movies_search,class:FULLTEXT"`
+	PreviewId            *uint              `json:"-"`
+	Preview              *file.File         `json:"preview,omitempty" gorm:"foreignKey:PreviewId;references:ID;constraint:OnDelete:SET NULL;"`
+	PreviewWebpId        *uint              `json:"-"`
+	PreviewWebp          *file.File         `json:"previewWebp,omitempty" gorm:"foreignKey:PreviewWebpId;references:ID;constraint:OnDelete:SET NULL;"`
+	DefaultPreviewId     *uint              `json:"-"`
+	DefaultPreview       *file.File         `json:"defaultPreview" gorm:"foreignKey:DefaultPreviewId;references:ID;constraint:OnDelete:SET NULL;"`
+	DefaultPreviewWebpId *uint              `json:"-"`
+	DefaultPreviewWebp   *file.File         `json:"defaultPreviewWebp" gorm:"foreignKey:DefaultPreviewWebpId;references:ID;constraint:OnDelete:SET NULL;"`
+	AnimatedPreviewId    *uint              `json:"-"`
+	AnimatedPreview      *file.File         `json:"animatedPreview" gorm:"foreignKey:AnimatedPreviewId;references:ID;constraint:OnDelete:SET NULL;"`
+	Name                 string             `json:"name" gorm:"index:idx_movies_search,class:FULLTEXT"`
+	Duration             int                `json:"duration"`
+	Language             string             `json:"language" gorm:"size:8;index"`
+	Videos               []video.Video      `gorm:"many2many:movie_videos"`
+	UserId               uint               `json:"-"`
+	User                 user.User          `json:"-" gorm:"foreignKey:UserId;references:ID"`
+	CategoryId           uint               `json:"-" gorm:"column:category;default:1"`
+	Category             *category.Category `json:"category" gorm:"foreignKey:CategoryId;references:ID;constraint:OnDelete:RESTRICT;"`
+	Tags                 []tag.Tag          `json:"-" gorm:"many2many:movie_tags"`
+	WebVttId             *uint              `json:"-"`
+	WebVtt               *file.File         `json:"webVtt" gorm:"foreignKey:WebVttId;references:ID;constraint:OnDelete:SET NULL;"`
+	Views                []view.View        `gorm:"-"`
+	ViewsCount           int64              `json:"-" gorm:"not null;default:0;index"`
+	CommentsCount        int64              `json:"-" gorm:"not null;default:0;index"`
+	TrendingScore        float64            `json:"-" gorm:"not null;default:0;index"`
+	LikesCount           int64              `json:"-" gorm:"not null;default:0;index"`
+	DislikesCount        int64              `json:"-" gorm:"not null;default:0"`
+	Loudness             *Loudness          `json:"loudness,omitempty" gorm:"embedded;embeddedPrefix:loudness_"`
+}
+
+type Loudness struct {
+	Integrated float64 `json:"integrated"`
+	TruePeak   float64 `json:"truePeak"`
+	Range      float64 `json:"range"`
+	Threshold  float64 `json:"threshold"`
+	Target     float64 `json:"target"`
+}
+
+func NewLoudness(loudness *ffmpegthumbs.Loudness, target ffmpegthumbs.LoudnessTarget) *Loudness {
+	integrated, _ := strconv.ParseFloat(loudness.InputI, 64)
+	truePeak, _ := strconv.ParseFloat(loudness.InputTP, 64)
+	loudnessRange, _ := strconv.ParseFloat(loudness.InputLRA, 64)
+	threshold, _ := strconv.ParseFloat(loudness.InputThresh, 64)
+
+	return &Loudness{
+		Integrated: integrated,
+		TruePeak:   truePeak,
+		Range:      loudnessRange,
+		Threshold:  threshold,
+		Target:     target.Integrated,
+	}
 }
 
 const (
@@ -43,6 +87,69 @@ const (
 	StatusReady     = "ready"
 )
 
+const (
+	VisibilityPublic      = "public"
+	VisibilityUnlisted    = "unlisted"
+	VisibilityPrivate     = "private"
+	VisibilitySubscribers = "subscribers"
+)
+
+var Visibilities = []string{
+	VisibilityPublic,
+	VisibilityUnlisted,
+	VisibilityPrivate,
+	VisibilitySubscribers,
+}
+
+// Filter narrows movie lists, zero values are ignored.
+type Filter struct {
+	TagId        uint
+	CategoryId   uint
+	DurationFrom *int
+	DurationTo   *int
+	DateFrom     *time.Time
+	DateTo       *time.Time
+	ChannelId    uint
+	QualityId    uint
+	Language     string
+	// Movies from any of the channels or categories, used by the feeds
+	ChannelIds  []uint
+	CategoryIds []uint
+	ExcludeIds  []uint
+}
+
+type TrendingStat struct {
+	MovieId uint
+	Score   float64
+}
+
+type cachedFeed struct {
+	Movies    []*GetResponse
+	Total     *int64
+	Next      string
+	Prev      string
+	ExpiresAt time.Time
+}
+
+type FilterRequest struct {
+	Tag          string
+	Category     uint
+	DurationFrom *int
+	DurationTo   *int
+	DateFrom     *time.Time
+	DateTo       *time.Time
+	Channel      uint
+	Quality      string
+	Language     string
+}
+
+type Thumbnails struct {
+	DefaultPreview     *file.File
+	DefaultPreviewWebp *file.File
+	WebVtt             *file.File
+	Duration           int
+}
+
 type PoolItem struct {
 	Ctx    context.Context
 	Cancel context.CancelFunc
@@ -84,13 +191,39 @@ type GetResponse struct {
 	PreviewWebp        *file.File              `json:"previewWebp"`
 	DefaultPreview     *file.File              `json:"defaultPreview"`
 	DefaultPreviewWebp *file.File              `json:"defaultPreviewWebp"`
+	AnimatedPreview    *file.File              `json:"animatedPreview"`
 	Name               string                  `json:"name"`
+	Duration           int                     `json:"duration"`
+	Language           string                  `json:"language,omitempty"`
 	Videos             []*video.GetResponse    `json:"videos"`
-	Category           category.Category       `json:"category"`
+	Category           *category.Category      `json:"category"`
+	Tags               []string                `json:"tags"`
 	WebVtt             *file.File              `json:"webVtt"`
+	Chapters           []*chapter.GetResponse  `json:"chapters,omitempty"`
 	User               *user.GetPublicResponse `json:"user"`
+	PublishAt          *time.Time              `json:"publishAt,omitempty"`
+	Visibility         string                  `json:"visibility"`
 	Subscribed         *bool                   `json:"subscribed,omitempty"`
 	Views              int64                   `json:"views"`
+	Comments           int64                   `json:"comments"`
+	Likes              int64                   `json:"likes"`
+	Dislikes           int64                   `json:"dislikes"`
+	Reaction           string                  `json:"reaction,omitempty"`
+	Playlist           *PlaylistContext        `json:"playlist,omitempty"`
+	ResumePosition     int                     `json:"resumePosition,omitempty"`
+	WatchedAt          *time.Time              `json:"watchedAt,omitempty"`
+}
+
+// PlaylistContext is the place of the movie in the playlist it's watched
+// from. Position counts from 1, Prev and Next skip movies the user can't
+// watch.
+type PlaylistContext struct {
+	ID          uint         `json:"id"`
+	Name        string       `json:"name"`
+	Position    int64        `json:"position"`
+	MoviesCount int64        `json:"moviesCount"`
+	Prev        *GetResponse `json:"prev"`
+	Next        *GetResponse `json:"next"`
 }
 
 func NewGetResponse(movie *Movie) *GetResponse {
@@ -103,16 +236,56 @@ func NewGetResponse(movie *Movie) *GetResponse {
 		PreviewWebp:        movie.PreviewWebp,
 		DefaultPreview:     movie.DefaultPreview,
 		DefaultPreviewWebp: movie.DefaultPreviewWebp,
+		AnimatedPreview:    movie.AnimatedPreview,
 		Name:               movie.Name,
+		Duration:           movie.Duration,
+		Language:           movie.Language,
 		Videos:             video.NewGetResponseMultiple(movie.Videos),
 		Category:           movie.Category,
+		Tags:               NewTagNames(movie.Tags),
 		WebVtt:             movie.WebVtt,
 		User:               user.NewGetPublicResponse(&movie.User),
+		PublishAt:          movie.PublishAt,
+		Visibility:         movie.Visibility,
+		Views:              movie.ViewsCount,
+		Comments:           movie.CommentsCount,
+		Likes:              movie.LikesCount,
+		Dislikes:           movie.DislikesCount,
+	}
+}
+
+// SortValue returns the value of the sort key and the ID for the list cursor.
+func (m *Movie) SortValue(sortBy string) (interface{}, uint) {
+	switch sortBy {
+	case "views":
+		return m.ViewsCount, m.ID
+	case "comments":
+		return m.CommentsCount, m.ID
+	case "likes":
+		return m.LikesCount, m.ID
+	case "publish_at":
+		return m.PublishAt, m.ID
+	case "trending":
+		return m.TrendingScore, m.ID
+	default:
+		return m.CreatedAt, m.ID
 	}
 }
 
+func NewTagNames(tags []tag.Tag) []string {
+	names := []string{}
+	for _, movieTag := range tags {
+		names = append(names, movieTag.Name)
+	}
+
+	return names
+}
+
 type GetForUserResponse struct {
 	IsPublished        bool                 `json:"isPublished"`
+	PublishAt          *time.Time           `json:"publishAt"`
+	Visibility         string               `json:"visibility"`
+	SharedWith         []string             `json:"sharedWith"`
 	Code               string               `json:"code"`
 	CreatedAt          time.Time            `json:"createdAt"`
 	Description        string               `json:"description"`
@@ -121,12 +294,28 @@ type GetForUserResponse struct {
 	DefaultPreview     *file.File           `json:"defaultPreview"`
 	DefaultPreviewWebp *file.File           `json:"defaultPreviewWebp"`
 	Name               string               `json:"name"`
+	Category           *category.Category   `json:"category"`
+	Tags               []string             `json:"tags"`
+	Language           string               `json:"language"`
 	Videos             []*video.GetResponse `json:"videos"`
+	Loudness           *Loudness            `json:"loudness"`
+	Views              int64                `json:"views"`
+	Comments           int64                `json:"comments"`
+	Likes              int64                `json:"likes"`
+	Dislikes           int64                `json:"dislikes"`
 }
 
 func NewGetForUserResponse(movie *Movie) *GetForUserResponse {
+	sharedWith := []string{}
+	for _, sharedUser := range movie.SharedWith {
+		sharedWith = append(sharedWith, sharedUser.Name)
+	}
+
 	return &GetForUserResponse{
 		IsPublished:        movie.IsPublished,
+		PublishAt:          movie.PublishAt,
+		Visibility:         movie.Visibility,
+		SharedWith:         sharedWith,
 		Code:               movie.Code,
 		CreatedAt:          movie.CreatedAt,
 		Description:        movie.Description,
@@ -135,7 +324,15 @@ func NewGetForUserResponse(movie *Movie) *GetForUserResponse {
 		DefaultPreview:     movie.DefaultPreview,
 		DefaultPreviewWebp: movie.DefaultPreviewWebp,
 		Name:               movie.Name,
+		Category:           movie.Category,
+		Tags:               NewTagNames(movie.Tags),
+		Language:           movie.Language,
 		Videos:             video.NewGetResponseMultiple(movie.Videos),
+		Loudness:           movie.Loudness,
+		Views:              movie.ViewsCount,
+		Comments:           movie.CommentsCount,
+		Likes:              movie.LikesCount,
+		Dislikes:           movie.DislikesCount,
 	}
 }
 
@@ -145,7 +342,10 @@ type VideoUpdateRequest struct {
 	Code               string     `json:"code"`
 	DefaultPreview     *file.File `json:"defaultPreview"`
 	DefaultPreviewWebp *file.File `json:"defaultPreviewWebp"`
+	AnimatedPreview    *file.File `json:"animatedPreview"`
 	WebVtt             *file.File `json:"webVtt"`
+	Loudness           *Loudness  `json:"-"`
+	Duration           int        `json:"-"`
 }
 
 func NewVideoUpdateRequest(movie *VideoUpdateRequest) *Movie {
@@ -155,7 +355,10 @@ func NewVideoUpdateRequest(movie *VideoUpdateRequest) *Movie {
 		Code:               movie.Code,
 		DefaultPreview:     movie.DefaultPreview,
 		DefaultPreviewWebp: movie.DefaultPreviewWebp,
+		AnimatedPreview:    movie.AnimatedPreview,
 		WebVtt:             movie.WebVtt,
+		Loudness:           movie.Loudness,
+		Duration:           movie.Duration,
 	}
 }
 
@@ -167,7 +370,12 @@ type UpdateRequest struct {
 	PreviewHeader *multipart.FileHeader `json:"-"`
 	RemovePreview bool                  `json:"-"`
 	Name          string                `json:"name,omitempty"`
-	Category      category.Category     `json:"category,omitempty"`
+	CategoryId    uint                  `json:"category,omitempty"`
+	Chapters      *[]chapter.SetRequest `json:"chapters,omitempty"`
+	Visibility    string                `json:"visibility,omitempty"`
+	SharedWith    *[]string             `json:"sharedWith,omitempty"`
+	Tags          *[]string             `json:"tags,omitempty"`
+	Language      string                `json:"language,omitempty"`
 }
 
 func NewUpdateRequest(movie *UpdateRequest) *Movie {
@@ -176,7 +384,9 @@ func NewUpdateRequest(movie *UpdateRequest) *Movie {
 		IsPublished: movie.IsPublished,
 		Description: movie.Description,
 		Name:        movie.Name,
-		Category:    movie.Category,
+		CategoryId:  movie.CategoryId,
+		Visibility:  movie.Visibility,
+		Language:    movie.Language,
 	}
 }
 
@@ -185,13 +395,45 @@ type DeleteRequest struct {
 }
 
 type UpdatePublishStatusRequest struct {
-	Code        string `json:"code"`
-	IsPublished bool   `json:"isPublished"`
+	Code        string     `json:"code"`
+	IsPublished bool       `json:"isPublished"`
+	PublishAt   *time.Time `json:"publishAt,omitempty"`
 }
 
 func NewUpdatePublishStatusRequest(movie *UpdatePublishStatusRequest) *Movie {
 	return &Movie{
 		Code:        movie.Code,
 		IsPublished: movie.IsPublished,
+		PublishAt:   movie.PublishAt,
 	}
 }
+
+type TrimSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+type TrimRequest struct {
+	Code     string        `json:"-"`
+	Start    *float64      `json:"start,omitempty"`
+	End      *float64      `json:"end,omitempty"`
+	Segments []TrimSegment `json:"segments,omitempty"`
+}
+
+func NewTrimSegments(trimRequest *TrimRequest) []TrimSegment {
+	if len(trimRequest.Segments) > 0 {
+		return trimRequest.Segments
+	}
+
+	// Without the end the video is cut up to its end, TrimVideo clamps the
+	// segment to the probed duration
+	segment := TrimSegment{End: math.MaxFloat64}
+	if trimRequest.Start != nil {
+		segment.Start = *trimRequest.Start
+	}
+	if trimRequest.End != nil {
+		segment.End = *trimRequest.End
+	}
+
+	return []TrimSegment{segment}
+}
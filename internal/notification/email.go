@@ -0,0 +1,172 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	// Users emailed in one pass
+	emailBatch = 100
+	// Notifications put into one email, the rest go into the next one
+	maxEmailNotifications = 50
+)
+
+func (uc *UseCase) GetEmailSettings(userId uint) *EmailSettingsResponse {
+	settings, err := uc.NotificationInteractor.GetEmailSettings(userId)
+	if err != nil {
+		return &EmailSettingsResponse{Mode: EmailOff}
+	}
+
+	return &EmailSettingsResponse{Mode: settings.Mode, Language: settings.Language}
+}
+
+// SetEmailSettings turns notification emails on or off. Emails come in the
+// language the user had when turning them on, and only about notifications
+// after that.
+func (uc *UseCase) SetEmailSettings(userId uint, mode, languageCode string) (*EmailSettingsResponse, error) {
+	if !slices.Contains(EmailModes, mode) {
+		return nil, errors.New("unknown email mode")
+	}
+
+	settings, err := uc.NotificationInteractor.GetEmailSettings(userId)
+	if err != nil {
+		settings = &EmailSettings{UserId: userId, Mode: EmailOff}
+	}
+
+	if settings.Mode == EmailOff && mode != EmailOff {
+		lastId, err := uc.NotificationInteractor.GetLastId(userId)
+		if err != nil {
+			return nil, err
+		}
+		settings.LastNotificationId = lastId
+	}
+	settings.Mode = mode
+	settings.Language = languageCode
+
+	if err = uc.NotificationInteractor.SaveEmailSettings(settings); err != nil {
+		return nil, err
+	}
+
+	return &EmailSettingsResponse{Mode: settings.Mode, Language: settings.Language}, nil
+}
+
+// Unsubscribe turns notification emails off by the signed link from an email
+func (uc *UseCase) Unsubscribe(userId uint, signature string) error {
+	if !uc.TokenAuthorize.VerifySignature(unsubscribeValue(userId), signature) {
+		return errors.New("invalid signature")
+	}
+
+	settings, err := uc.NotificationInteractor.GetEmailSettings(userId)
+	if err != nil || settings.Mode == EmailOff {
+		return nil
+	}
+	settings.Mode = EmailOff
+
+	return uc.NotificationInteractor.SaveEmailSettings(settings)
+}
+
+func (uc *UseCase) UnsubscribeLink(userId uint) string {
+	return fmt.Sprintf(
+		"%s/api/notification/unsubscribe?user=%d&signature=%s",
+		uc.SiteUrl, userId, uc.TokenAuthorize.Sign(unsubscribeValue(userId)),
+	)
+}
+
+func unsubscribeValue(userId uint) string {
+	return "unsubscribe:" + strconv.FormatUint(uint64(userId), 10)
+}
+
+func (uc *UseCase) RunEmails(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := uc.SendEmails(); err != nil {
+			log.Println("notification: emails:", err)
+		}
+	}
+}
+
+// SendEmails puts an email with the unread notifications into the mail
+// outbox for every user whose email is due
+func (uc *UseCase) SendEmails() error {
+	due, err := uc.NotificationInteractor.GetEmailDue(time.Now(), EmailTypes, emailBatch)
+	if err != nil {
+		return err
+	}
+
+	for key := range due {
+		if err = uc.sendEmail(&due[key]); err != nil {
+			log.Println("notification: email:", err)
+		}
+	}
+
+	return nil
+}
+
+func (uc *UseCase) sendEmail(settings *EmailSettings) error {
+	notifications, err := uc.NotificationInteractor.GetUnemailed(settings.UserId, settings.LastNotificationId, EmailTypes, maxEmailNotifications)
+	if err != nil || len(notifications) == 0 {
+		return err
+	}
+
+	recipient, err := uc.NotificationInteractor.GetUser(settings.UserId)
+	if err != nil {
+		return err
+	}
+
+	preferences, err := uc.GetPreferences(settings.UserId)
+	if err != nil {
+		return err
+	}
+
+	responses, err := uc.newResponses(notifications)
+	if err != nil {
+		return err
+	}
+
+	var items []emailItem
+	for _, response := range responses {
+		if !preferences[response.Type] || response.Actor == nil || response.Movie == nil {
+			continue
+		}
+
+		items = append(items, emailItem{
+			Type:      response.Type,
+			ActorName: response.Actor.Name,
+			MovieName: response.Movie.Name,
+			Link:      fmt.Sprintf("%s/movie/%s", uc.SiteUrl, response.Movie.Code),
+		})
+	}
+
+	// Inactive users haven't confirmed the email, skipped notifications are
+	// not sent later either
+	if len(items) > 0 && recipient.Active && recipient.Email != "" {
+		unsubscribeLink := uc.UnsubscribeLink(settings.UserId)
+		message, err := uc.MailUseCase.Render("notifications", settings.Language, &emailData{
+			UserName:        recipient.Name,
+			Notifications:   items,
+			UnsubscribeLink: unsubscribeLink,
+		})
+		if err != nil {
+			return err
+		}
+		message.Unsubscribe = unsubscribeLink
+
+		if err = uc.MailUseCase.Queue(recipient.Email, message); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		settings.SentAt = &now
+	}
+
+	settings.LastNotificationId = notifications[len(notifications)-1].ID
+
+	return uc.NotificationInteractor.SaveEmailSettings(settings)
+}
@@ -0,0 +1,214 @@
+package notification
+
+import (
+	"encoding/json"
+	"github.com/go-chi/render"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"nine-dubz/internal/file"
+	"nine-dubz/internal/pagination"
+	"nine-dubz/internal/response"
+	"nine-dubz/internal/user"
+	"nine-dubz/pkg/language"
+	"strconv"
+	"time"
+)
+
+const (
+	writeTimeout = 10 * time.Second
+	// Pings keep the socket open behind proxies and find dead connections
+	pingInterval = 30 * time.Second
+)
+
+type Handler struct {
+	NotificationUseCase *UseCase
+	UserHandler         *user.Handler
+	FileUseCase         *file.UseCase
+}
+
+func NewHandler(uc *UseCase, uh *user.Handler, fuc *file.UseCase) *Handler {
+	return &Handler{
+		NotificationUseCase: uc,
+		UserHandler:         uh,
+		FileUseCase:         fuc,
+	}
+}
+
+func (h *Handler) GetMultipleHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+	pagination := r.Context().Value("pagination").(*pagination.Pagination)
+	unread := r.URL.Query().Get("unread") == "1"
+
+	notifications, err := h.NotificationUseCase.GetMultiple(userId, unread, pagination)
+	if err != nil {
+		response.RenderError(w, r, pagination.ErrorStatus(err), "Can't get notifications")
+		return
+	}
+
+	render.JSON(w, r, pagination.NewList(notifications))
+}
+
+func (h *Handler) CountUnreadHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	count, err := h.NotificationUseCase.CountUnread(userId)
+	if err != nil {
+		response.RenderError(w, r, http.StatusInternalServerError, "Can't count notifications")
+		return
+	}
+
+	render.JSON(w, r, &CountResponse{Count: count})
+}
+
+func (h *Handler) MarkReadHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	readRequest := &ReadRequest{}
+	if err := json.NewDecoder(r.Body).Decode(readRequest); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't parse fields")
+		return
+	}
+
+	if err := h.NotificationUseCase.MarkRead(userId, readRequest.Ids); err != nil {
+		response.RenderError(w, r, http.StatusInternalServerError, "Can't mark notifications as read")
+		return
+	}
+
+	response.RenderSuccess(w, r, http.StatusOK, "")
+}
+
+func (h *Handler) MarkAllReadHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	if err := h.NotificationUseCase.MarkAllRead(userId); err != nil {
+		response.RenderError(w, r, http.StatusInternalServerError, "Can't mark notifications as read")
+		return
+	}
+
+	response.RenderSuccess(w, r, http.StatusOK, "")
+}
+
+func (h *Handler) GetPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	preferences, err := h.NotificationUseCase.GetPreferences(userId)
+	if err != nil {
+		response.RenderError(w, r, http.StatusInternalServerError, "Can't get notification preferences")
+		return
+	}
+
+	render.JSON(w, r, preferences)
+}
+
+func (h *Handler) SetPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	preferencesRequest := PreferencesRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&preferencesRequest); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't parse fields")
+		return
+	}
+
+	preferences, err := h.NotificationUseCase.SetPreferences(userId, preferencesRequest)
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't save notification preferences: "+err.Error())
+		return
+	}
+
+	render.JSON(w, r, preferences)
+}
+
+func (h *Handler) GetEmailSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	render.JSON(w, r, h.NotificationUseCase.GetEmailSettings(userId))
+}
+
+func (h *Handler) SetEmailSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	emailSettingsRequest := &EmailSettingsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(emailSettingsRequest); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't parse fields")
+		return
+	}
+
+	emailSettings, err := h.NotificationUseCase.SetEmailSettings(userId, emailSettingsRequest.Mode, language.GetLanguageCode(r))
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't save email settings: "+err.Error())
+		return
+	}
+
+	render.JSON(w, r, emailSettings)
+}
+
+// UnsubscribeHandler turns notification emails off from the link in an
+// email. Mail clients' one-click unsubscribe posts to the same link.
+func (h *Handler) UnsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := strconv.ParseUint(r.URL.Query().Get("user"), 10, 32)
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Invalid user id")
+		return
+	}
+
+	if err = h.NotificationUseCase.Unsubscribe(uint(userId), r.URL.Query().Get("signature")); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't unsubscribe: "+err.Error())
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	response.RenderSuccess(w, r, http.StatusOK, "")
+}
+
+// SocketHandler pushes new notifications to the user as JSON messages
+// while the socket is open
+func (h *Handler) SocketHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	listener, err := h.NotificationUseCase.Listen(userId)
+	if err != nil {
+		response.RenderError(w, r, http.StatusTooManyRequests, "Can't listen to notifications: "+err.Error())
+		return
+	}
+	defer h.NotificationUseCase.Unlisten(userId, listener)
+
+	conn, err := h.FileUseCase.UpgradeConnection(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Nothing is expected from the client, reading only notices the close
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case notification := <-listener.Send:
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err = conn.WriteJSON(notification); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package notification
+
+import (
+	"nine-dubz/internal/pagination"
+	"nine-dubz/internal/user"
+	"time"
+)
+
+type Interactor interface {
+	CreateMultiple(notifications []Notification) error
+	GetMultiple(where interface{}, pagination *pagination.Pagination, order string) ([]Notification, error)
+	Count(where interface{}) (int64, error)
+	MarkRead(userId uint, ids []uint) error
+	GetMovies(ids []uint) ([]Movie, error)
+	GetComments(ids []uint) ([]Comment, error)
+	GetDisabled(notificationType string, userIds []uint) ([]uint, error)
+	GetPreferences(userId uint) ([]Preference, error)
+	SavePreferences(preferences []Preference) error
+	GetEmailSettings(userId uint) (*EmailSettings, error)
+	SaveEmailSettings(settings *EmailSettings) error
+	GetEmailDue(now time.Time, types []string, limit int) ([]EmailSettings, error)
+	GetUnemailed(userId, afterId uint, types []string, limit int) ([]Notification, error)
+	GetLastId(userId uint) (uint, error)
+	GetUser(userId uint) (*user.User, error)
+}
@@ -0,0 +1,293 @@
+package notification
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"log"
+	"nine-dubz/internal/mail"
+	"nine-dubz/internal/pagination"
+	"nine-dubz/pkg/tokenauthorize"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Latest notifications go first
+var sortKey = pagination.Key{SortBy: "created_at", Column: "notifications.created_at", IdColumn: "notifications.id", Desc: true}
+
+const (
+	// Notifications a listener can fall behind by, the rest are dropped and
+	// the client gets them from the list
+	listenerBuffer = 16
+	// Open sockets of one user, e.g. browser tabs
+	maxListeners = 10
+)
+
+type Listener struct {
+	Send chan *GetResponse
+}
+
+type UseCase struct {
+	NotificationInteractor Interactor
+	SiteUrl                string
+	MailUseCase            *mail.UseCase
+	TokenAuthorize         *tokenauthorize.TokenAuthorize
+	Listeners              map[uint]map[*Listener]bool
+	Mutex                  *sync.RWMutex
+}
+
+func New(db *gorm.DB, muc *mail.UseCase, ta *tokenauthorize.TokenAuthorize) *UseCase {
+	siteUrl, ok := os.LookupEnv("SITE_URL")
+	if !ok {
+		log.Println("notification: SITE_URL not found in environment")
+	}
+
+	return &UseCase{
+		NotificationInteractor: &Repository{
+			DB: db,
+		},
+		SiteUrl:        siteUrl,
+		MailUseCase:    muc,
+		TokenAuthorize: ta,
+		Listeners:      make(map[uint]map[*Listener]bool),
+		Mutex:          &sync.RWMutex{},
+	}
+}
+
+// Notify sends the notification to every user in the list except its actor
+// and users who turned its type off, then pushes it to the ones online.
+func (uc *UseCase) Notify(notification Notification, userIds []uint) error {
+	var recipients []uint
+	for _, userId := range userIds {
+		if notification.ActorId != nil && *notification.ActorId == userId {
+			continue
+		}
+		if !slices.Contains(recipients, userId) {
+			recipients = append(recipients, userId)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	disabled, err := uc.NotificationInteractor.GetDisabled(notification.Type, recipients)
+	if err != nil {
+		return err
+	}
+
+	var notifications []Notification
+	for _, userId := range recipients {
+		if slices.Contains(disabled, userId) {
+			continue
+		}
+
+		notification.UserId = userId
+		notifications = append(notifications, notification)
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	if err = uc.NotificationInteractor.CreateMultiple(notifications); err != nil {
+		return err
+	}
+
+	return uc.push(notifications)
+}
+
+// push sends the created notifications to the recipients' open sockets
+func (uc *UseCase) push(notifications []Notification) error {
+	var ids []uint
+	uc.Mutex.RLock()
+	for _, notification := range notifications {
+		if len(uc.Listeners[notification.UserId]) > 0 {
+			ids = append(ids, notification.ID)
+		}
+	}
+	uc.Mutex.RUnlock()
+	if len(ids) == 0 {
+		return nil
+	}
+
+	notifications, err := uc.NotificationInteractor.GetMultiple(
+		map[string]interface{}{"id": ids},
+		&pagination.Pagination{Limit: -1, Offset: -1},
+		"",
+	)
+	if err != nil {
+		return err
+	}
+
+	responses, err := uc.newResponses(notifications)
+	if err != nil {
+		return err
+	}
+
+	uc.Mutex.RLock()
+	defer uc.Mutex.RUnlock()
+	for key, notification := range notifications {
+		for listener := range uc.Listeners[notification.UserId] {
+			select {
+			case listener.Send <- responses[key]:
+			default:
+			}
+		}
+	}
+
+	return nil
+}
+
+func (uc *UseCase) Listen(userId uint) (*Listener, error) {
+	uc.Mutex.Lock()
+	defer uc.Mutex.Unlock()
+
+	if len(uc.Listeners[userId]) >= maxListeners {
+		return nil, errors.New("too many connections")
+	}
+
+	listener := &Listener{Send: make(chan *GetResponse, listenerBuffer)}
+	if uc.Listeners[userId] == nil {
+		uc.Listeners[userId] = make(map[*Listener]bool)
+	}
+	uc.Listeners[userId][listener] = true
+
+	return listener, nil
+}
+
+func (uc *UseCase) Unlisten(userId uint, listener *Listener) {
+	uc.Mutex.Lock()
+	defer uc.Mutex.Unlock()
+
+	delete(uc.Listeners[userId], listener)
+	if len(uc.Listeners[userId]) == 0 {
+		delete(uc.Listeners, userId)
+	}
+}
+
+// GetMultiple returns the user's notifications, latest first. Unread
+// limits them to ones not read yet.
+func (uc *UseCase) GetMultiple(userId uint, unread bool, pagination *pagination.Pagination) ([]*GetResponse, error) {
+	where := map[string]interface{}{"user_id": userId}
+	if unread {
+		where["read_at"] = nil
+	}
+
+	notifications, err := uc.NotificationInteractor.GetMultiple(where, pagination, pagination.OrderBy(sortKey))
+	if err != nil {
+		return nil, err
+	}
+	notifications = notifications[:pagination.Trim(notifications, func(i int) (interface{}, uint) {
+		return notifications[i].CreatedAt, notifications[i].ID
+	})]
+
+	if pagination.WithTotal {
+		total, err := uc.NotificationInteractor.Count(where)
+		if err != nil {
+			return nil, err
+		}
+		pagination.Total = &total
+	}
+
+	return uc.newResponses(notifications)
+}
+
+// newResponses adds the movies and comments the notifications are about
+func (uc *UseCase) newResponses(notifications []Notification) ([]*GetResponse, error) {
+	var movieIds, commentIds []uint
+	for _, notification := range notifications {
+		if notification.MovieId != nil {
+			movieIds = append(movieIds, *notification.MovieId)
+		}
+		if notification.CommentId != nil {
+			commentIds = append(commentIds, *notification.CommentId)
+		}
+	}
+
+	movies := make(map[uint]*Movie)
+	if len(movieIds) > 0 {
+		moviesList, err := uc.NotificationInteractor.GetMovies(movieIds)
+		if err != nil {
+			return nil, err
+		}
+		for key := range moviesList {
+			movies[moviesList[key].ID] = &moviesList[key]
+		}
+	}
+
+	comments := make(map[uint]*Comment)
+	if len(commentIds) > 0 {
+		commentsList, err := uc.NotificationInteractor.GetComments(commentIds)
+		if err != nil {
+			return nil, err
+		}
+		for key := range commentsList {
+			comments[commentsList[key].ID] = &commentsList[key]
+		}
+	}
+
+	responses := make([]*GetResponse, 0, len(notifications))
+	for key := range notifications {
+		responses = append(responses, NewGetResponse(&notifications[key], movies, comments))
+	}
+
+	return responses, nil
+}
+
+func (uc *UseCase) CountUnread(userId uint) (int64, error) {
+	return uc.NotificationInteractor.Count(map[string]interface{}{"user_id": userId, "read_at": nil})
+}
+
+func (uc *UseCase) MarkRead(userId uint, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return uc.NotificationInteractor.MarkRead(userId, ids)
+}
+
+func (uc *UseCase) MarkAllRead(userId uint) error {
+	return uc.NotificationInteractor.MarkRead(userId, nil)
+}
+
+// GetPreferences returns whether each notification type is on for the user
+func (uc *UseCase) GetPreferences(userId uint) (map[string]bool, error) {
+	preferences, err := uc.NotificationInteractor.GetPreferences(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := make(map[string]bool)
+	for _, notificationType := range Types {
+		enabled[notificationType] = true
+	}
+	for _, preference := range preferences {
+		if _, ok := enabled[preference.Type]; ok {
+			enabled[preference.Type] = preference.Enabled
+		}
+	}
+
+	return enabled, nil
+}
+
+func (uc *UseCase) SetPreferences(userId uint, preferencesRequest PreferencesRequest) (map[string]bool, error) {
+	var preferences []Preference
+	for notificationType, enabled := range preferencesRequest {
+		if !slices.Contains(Types, notificationType) {
+			return nil, errors.New("unknown notification type")
+		}
+
+		preferences = append(preferences, Preference{
+			UserId:  userId,
+			Type:    notificationType,
+			Enabled: enabled,
+		})
+	}
+
+	if len(preferences) > 0 {
+		if err := uc.NotificationInteractor.SavePreferences(preferences); err != nil {
+			return nil, err
+		}
+	}
+
+	return uc.GetPreferences(userId)
+}
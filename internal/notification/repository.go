@@ -0,0 +1,170 @@
+package notification
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"nine-dubz/internal/pagination"
+	"nine-dubz/internal/user"
+	"time"
+)
+
+type Repository struct {
+	DB *gorm.DB
+}
+
+func (r *Repository) CreateMultiple(notifications []Notification) error {
+	return r.DB.CreateInBatches(&notifications, 500).Error
+}
+
+func (r *Repository) GetMultiple(where interface{}, pagination *pagination.Pagination, order string) ([]Notification, error) {
+	var notifications []Notification
+	result := r.DB.
+		Preload("Actor").
+		Preload("Actor.Picture").
+		Where(where).
+		Scopes(pagination.Scope).
+		Order(order).
+		Find(&notifications)
+
+	return notifications, result.Error
+}
+
+func (r *Repository) Count(where interface{}) (int64, error) {
+	var count int64
+	result := r.DB.
+		Model(&Notification{}).
+		Where(where).
+		Count(&count)
+
+	return count, result.Error
+}
+
+// MarkRead marks the user's notifications as read, all of them when no IDs
+// are given
+func (r *Repository) MarkRead(userId uint, ids []uint) error {
+	query := r.DB.
+		Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userId)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	return query.Update("read_at", time.Now()).Error
+}
+
+func (r *Repository) GetMovies(ids []uint) ([]Movie, error) {
+	var movies []Movie
+	result := r.DB.
+		Table("movies").
+		Select("id, code, name").
+		Where("id IN ? AND deleted_at IS NULL", ids).
+		Find(&movies)
+
+	return movies, result.Error
+}
+
+func (r *Repository) GetComments(ids []uint) ([]Comment, error) {
+	var comments []Comment
+	result := r.DB.
+		Table("comments").
+		Select("id, parent_id, text").
+		Where("id IN ? AND deleted_at IS NULL", ids).
+		Find(&comments)
+
+	return comments, result.Error
+}
+
+// GetDisabled returns which of the users turned the notification type off
+func (r *Repository) GetDisabled(notificationType string, userIds []uint) ([]uint, error) {
+	var disabled []uint
+	result := r.DB.
+		Model(&Preference{}).
+		Where("type = ? AND enabled = ? AND user_id IN ?", notificationType, false, userIds).
+		Pluck("user_id", &disabled)
+
+	return disabled, result.Error
+}
+
+func (r *Repository) GetPreferences(userId uint) ([]Preference, error) {
+	var preferences []Preference
+	result := r.DB.Where("user_id = ?", userId).Find(&preferences)
+
+	return preferences, result.Error
+}
+
+func (r *Repository) SavePreferences(preferences []Preference) error {
+	return r.DB.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+			DoUpdates: clause.AssignmentColumns([]string{"updated_at", "enabled"}),
+		}).
+		Create(&preferences).
+		Error
+}
+
+func (r *Repository) GetEmailSettings(userId uint) (*EmailSettings, error) {
+	settings := &EmailSettings{}
+	result := r.DB.Where("user_id = ?", userId).First(settings)
+
+	return settings, result.Error
+}
+
+func (r *Repository) SaveEmailSettings(settings *EmailSettings) error {
+	return r.DB.Save(settings).Error
+}
+
+// GetEmailDue returns settings of users whose email is due and who have
+// unread notifications of the types not emailed yet
+func (r *Repository) GetEmailDue(now time.Time, types []string, limit int) ([]EmailSettings, error) {
+	var settings []EmailSettings
+	result := r.DB.
+		Where(
+			"mode = ? OR (mode = ? AND (sent_at IS NULL OR sent_at <= ?)) OR (mode = ? AND (sent_at IS NULL OR sent_at <= ?))",
+			EmailInstant, EmailHourly, now.Add(-time.Hour), EmailDaily, now.Add(-24*time.Hour),
+		).
+		Where(
+			"EXISTS (?)",
+			r.DB.
+				Model(&Notification{}).
+				Select("1").
+				Where("notifications.user_id = notification_email_settings.user_id").
+				Where("notifications.id > notification_email_settings.last_notification_id").
+				Where("notifications.read_at IS NULL AND notifications.type IN ?", types),
+		).
+		Limit(limit).
+		Find(&settings)
+
+	return settings, result.Error
+}
+
+// GetUnemailed returns the user's unread notifications after the given
+// one, oldest first
+func (r *Repository) GetUnemailed(userId, afterId uint, types []string, limit int) ([]Notification, error) {
+	var notifications []Notification
+	result := r.DB.
+		Preload("Actor").
+		Where("user_id = ? AND id > ? AND read_at IS NULL AND type IN ?", userId, afterId, types).
+		Order("id").
+		Limit(limit).
+		Find(&notifications)
+
+	return notifications, result.Error
+}
+
+func (r *Repository) GetLastId(userId uint) (uint, error) {
+	var lastId uint
+	result := r.DB.
+		Model(&Notification{}).
+		Select("COALESCE(MAX(id), 0)").
+		Where("user_id = ?", userId).
+		Scan(&lastId)
+
+	return lastId, result.Error
+}
+
+func (r *Repository) GetUser(userId uint) (*user.User, error) {
+	recipient := &user.User{}
+	result := r.DB.Select("id, name, email, active").Where("id = ?", userId).First(recipient)
+
+	return recipient, result.Error
+}
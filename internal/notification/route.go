@@ -0,0 +1,33 @@
+package notification
+
+import (
+	"github.com/go-chi/chi/v5"
+	"nine-dubz/internal/pagination"
+)
+
+func (h *Handler) Routes(r chi.Router) {
+	r.Route("/notification/unsubscribe", func(r chi.Router) {
+		r.Get("/", h.UnsubscribeHandler)
+		r.Post("/", h.UnsubscribeHandler)
+	})
+
+	r.
+		With(h.UserHandler.IsAuthorized).
+		Route("/notification", func(r chi.Router) {
+			r.
+				With(pagination.SetPaginationContextMiddleware).
+				Get("/", h.GetMultipleHandler)
+			r.Get("/unread-count", h.CountUnreadHandler)
+			r.Post("/read", h.MarkReadHandler)
+			r.Post("/read-all", h.MarkAllReadHandler)
+			r.Route("/preferences", func(r chi.Router) {
+				r.Get("/", h.GetPreferencesHandler)
+				r.Post("/", h.SetPreferencesHandler)
+			})
+			r.Route("/email", func(r chi.Router) {
+				r.Get("/", h.GetEmailSettingsHandler)
+				r.Post("/", h.SetEmailSettingsHandler)
+			})
+			r.Get("/socket", h.SocketHandler)
+		})
+}
@@ -0,0 +1,150 @@
+package notification
+
+import (
+	"nine-dubz/internal/user"
+	"time"
+)
+
+const (
+	// TypeNewMovie is sent to subscribers when a channel publishes a movie
+	TypeNewMovie = "new_movie"
+	// TypeReply is sent to the author of the comment that got a reply
+	TypeReply = "reply"
+	// TypeMention is sent to users mentioned as <@id:N> in a comment
+	TypeMention = "mention"
+	// TypeSubscriber is sent to the channel when someone subscribes to it
+	TypeSubscriber = "subscriber"
+)
+
+var Types = []string{TypeNewMovie, TypeReply, TypeMention, TypeSubscriber}
+
+const (
+	EmailOff = "off"
+	// EmailInstant sends new notifications within a minute
+	EmailInstant = "instant"
+	// EmailHourly and EmailDaily gather notifications into a digest
+	EmailHourly = "hourly"
+	EmailDaily  = "daily"
+)
+
+var EmailModes = []string{EmailOff, EmailInstant, EmailHourly, EmailDaily}
+
+// EmailTypes are the notification types sent by email
+var EmailTypes = []string{TypeNewMovie, TypeReply, TypeMention}
+
+type Notification struct {
+	ID        uint       `gorm:"primarykey"`
+	CreatedAt time.Time  `gorm:"index:idx_notifications_user_created,priority:2"`
+	UserId    uint       `gorm:"not null;index:idx_notifications_user_created,priority:1"`
+	Type      string     `gorm:"size:20;not null"`
+	ActorId   *uint      `gorm:"index"`
+	Actor     *user.User `gorm:"foreignKey:ActorId"`
+	MovieId   *uint      `gorm:"index"`
+	CommentId *uint
+	ReadAt    *time.Time
+}
+
+// Preference keeps a notification type the user turned off or back on,
+// types without a row are on.
+type Preference struct {
+	UserId    uint   `gorm:"primarykey;autoIncrement:false"`
+	Type      string `gorm:"primarykey;size:20"`
+	UpdatedAt time.Time
+	Enabled   bool `gorm:"not null"`
+}
+
+func (Preference) TableName() string {
+	return "notification_preferences"
+}
+
+// EmailSettings keeps whether and how often the user gets notifications by
+// email. LastNotificationId is the last notification emailed or skipped.
+type EmailSettings struct {
+	UserId             uint `gorm:"primarykey;autoIncrement:false"`
+	UpdatedAt          time.Time
+	Mode               string `gorm:"size:10;not null;default:'off';index"`
+	Language           string `gorm:"size:10;not null"`
+	LastNotificationId uint   `gorm:"not null;default:0"`
+	SentAt             *time.Time
+}
+
+func (EmailSettings) TableName() string {
+	return "notification_email_settings"
+}
+
+type EmailSettingsRequest struct {
+	Mode string `json:"mode"`
+}
+
+type EmailSettingsResponse struct {
+	Mode     string `json:"mode"`
+	Language string `json:"language,omitempty"`
+}
+
+// emailData is what the notifications mail template gets
+type emailData struct {
+	UserName        string
+	Notifications   []emailItem
+	UnsubscribeLink string
+}
+
+type emailItem struct {
+	Type      string
+	ActorName string
+	MovieName string
+	Link      string
+}
+
+type Movie struct {
+	ID   uint   `json:"-"`
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type Comment struct {
+	ID       uint   `json:"id"`
+	ParentId *uint  `json:"parentId,omitempty"`
+	Text     string `json:"text"`
+}
+
+type GetResponse struct {
+	ID        uint                    `json:"id"`
+	Type      string                  `json:"type"`
+	CreatedAt time.Time               `json:"createdAt"`
+	IsRead    bool                    `json:"isRead"`
+	Actor     *user.GetPublicResponse `json:"actor,omitempty"`
+	Movie     *Movie                  `json:"movie,omitempty"`
+	Comment   *Comment                `json:"comment,omitempty"`
+}
+
+func NewGetResponse(notification *Notification, movies map[uint]*Movie, comments map[uint]*Comment) *GetResponse {
+	response := &GetResponse{
+		ID:        notification.ID,
+		Type:      notification.Type,
+		CreatedAt: notification.CreatedAt,
+		IsRead:    notification.ReadAt != nil,
+	}
+	if notification.Actor != nil {
+		response.Actor = user.NewGetPublicResponse(notification.Actor)
+	}
+	if notification.MovieId != nil {
+		response.Movie = movies[*notification.MovieId]
+	}
+	if notification.CommentId != nil {
+		response.Comment = comments[*notification.CommentId]
+	}
+
+	return response
+}
+
+type CountResponse struct {
+	Count int64 `json:"count"`
+}
+
+// PreferencesRequest turns notification types on and off, types left out
+// stay as they are.
+type PreferencesRequest map[string]bool
+
+type ReadRequest struct {
+	Ids []uint `json:"ids"`
+}
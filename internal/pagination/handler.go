@@ -3,27 +3,59 @@ package pagination
 import (
 	"context"
 	"net/http"
+	"nine-dubz/internal/response"
 	"strconv"
 )
 
+const (
+	DefaultLimit = 20
+	MaxLimit     = 50
+)
+
 func SetPaginationContextMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
-		if err != nil || limit <= 0 {
-			limit = -1
-		}
-
-		offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
-		if err != nil || offset < 0 || limit <= 0 {
-			offset = -1
-		}
-
-		pagination := &Pagination{
-			Limit:  limit,
-			Offset: offset,
-		}
-
-		ctx := context.WithValue(r.Context(), "pagination", pagination)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	return NewMiddleware(DefaultLimit)(next)
+}
+
+// NewMiddleware reads limit, offset and cursor from the query. The limit
+// falls back to defaultLimit and never exceeds MaxLimit. A cursor takes
+// precedence over the offset. The total count is loaded only with total=1.
+func NewMiddleware(defaultLimit int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+			if err != nil || limit <= 0 {
+				limit = defaultLimit
+			}
+			limit = min(limit, MaxLimit)
+
+			offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+			if err != nil || offset < 0 {
+				offset = -1
+			}
+
+			pagination := &Pagination{
+				Limit:     limit,
+				Offset:    offset,
+				WithTotal: r.URL.Query().Get("total") == "1",
+			}
+
+			if token := r.URL.Query().Get("cursor"); token != "" {
+				cursor, err := Decode(token)
+				if err != nil {
+					response.RenderError(w, r, http.StatusBadRequest, "Invalid cursor")
+					return
+				}
+
+				if cursor.SortBy == offsetSortBy {
+					pagination.Offset, _ = strconv.Atoi(cursor.Value)
+				} else {
+					pagination.Cursor = cursor
+					pagination.Offset = -1
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), "pagination", pagination)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
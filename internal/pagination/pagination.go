@@ -0,0 +1,235 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"net/http"
+	"nine-dubz/internal/response"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Lists without a sort key (search results) page by offset, their cursor
+// only carries the offset.
+const offsetSortBy = "offset"
+
+var ErrInvalidCursor = errors.New("pagination: invalid cursor")
+
+func Encode(cursor *Cursor) string {
+	cursorJson, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(cursorJson)
+}
+
+func Decode(token string) (*Cursor, error) {
+	cursorJson, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &Cursor{}
+	if err = json.Unmarshal(cursorJson, cursor); err != nil || cursor.SortBy == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+func (k *Key) Order() string {
+	direction := "asc"
+	if k.Desc {
+		direction = "desc"
+	}
+
+	return fmt.Sprintf("%s %s, %s %s", k.Column, direction, k.IdColumn, direction)
+}
+
+// OrderBy remembers the list order for the cursor and returns the ORDER BY
+// clause for it. Going back from a cursor the order is reversed, Trim puts
+// the page back in order.
+func (p *Pagination) OrderBy(key Key) string {
+	p.key = &key
+
+	if p.isBackward() {
+		return (&Key{Column: key.Column, IdColumn: key.IdColumn, Desc: !key.Desc}).Order()
+	}
+
+	return key.Order()
+}
+
+// Scope applies the cursor or offset and the limit to a query. When the
+// order is known one extra row is loaded to find out if there is one more
+// page, Trim cuts it off. Lists without OrderBy can't be paged by a cursor.
+func (p *Pagination) Scope(db *gorm.DB) *gorm.DB {
+	if p.Cursor != nil && p.key == nil {
+		db.AddError(ErrInvalidCursor)
+		return db
+	}
+
+	if p.Cursor != nil {
+		if p.Cursor.SortBy != p.key.SortBy {
+			db.AddError(ErrInvalidCursor)
+			return db
+		}
+
+		value, err := parseValue(p.Cursor.Value)
+		if err != nil {
+			db.AddError(ErrInvalidCursor)
+			return db
+		}
+
+		operator := ">"
+		if p.key.Desc != p.Cursor.Before {
+			operator = "<"
+		}
+		db = db.Where(
+			fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", p.key.Column, operator, p.key.Column, p.key.IdColumn, operator),
+			value, value, p.Cursor.ID,
+		)
+	} else {
+		db = db.Offset(p.Offset)
+	}
+
+	if p.Limit > 0 && p.key != nil {
+		return db.Limit(p.Limit + 1)
+	}
+
+	return db.Limit(p.Limit)
+}
+
+// Trim returns how many of the loaded items belong to the page and sets the
+// next and previous cursors from its first and last items. items must be a
+// slice, it's reordered in place when the page was loaded backwards. value
+// returns the sort key value and ID of the i-th item.
+func (p *Pagination) Trim(items interface{}, value func(i int) (interface{}, uint)) int {
+	p.Next, p.Prev = "", ""
+
+	count := reflect.ValueOf(items).Len()
+	if p.key == nil || p.Limit <= 0 {
+		return count
+	}
+
+	// The extra row means one more page in the direction we went, the page
+	// we came from is always there
+	hasNext := count > p.Limit
+	hasPrev := p.Cursor != nil || p.Offset > 0
+	count = min(count, p.Limit)
+
+	if p.isBackward() {
+		hasNext, hasPrev = true, hasNext
+
+		swap := reflect.Swapper(items)
+		for i, j := 0, count-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	if count == 0 {
+		return count
+	}
+
+	if hasNext {
+		lastValue, lastId := value(count - 1)
+		p.Next = p.newCursor(lastValue, lastId, false)
+	}
+	if hasPrev {
+		firstValue, firstId := value(0)
+		p.Prev = p.newCursor(firstValue, firstId, true)
+	}
+
+	return count
+}
+
+// SetOffsetCursors sets the next and previous cursors for lists paged by
+// offset.
+func (p *Pagination) SetOffsetCursors(total int) {
+	p.Next, p.Prev = "", ""
+	if p.Limit <= 0 {
+		return
+	}
+
+	offset := max(p.Offset, 0)
+	if offset+p.Limit < total {
+		p.Next = Encode(&Cursor{SortBy: offsetSortBy, Value: strconv.Itoa(offset + p.Limit)})
+	}
+	if offset > 0 {
+		p.Prev = Encode(&Cursor{SortBy: offsetSortBy, Value: strconv.Itoa(max(offset-p.Limit, 0))})
+	}
+}
+
+// NewList wraps a page of items into the list envelope. Empty pages have
+// an empty items array rather than null.
+func (p *Pagination) NewList(items interface{}) *response.List {
+	itemsValue := reflect.ValueOf(items)
+	for itemsValue.Kind() == reflect.Pointer && !itemsValue.IsNil() {
+		itemsValue = itemsValue.Elem()
+	}
+	if itemsValue.Kind() != reflect.Slice || itemsValue.IsNil() {
+		items = make([]struct{}, 0)
+	}
+
+	return &response.List{
+		Items: items,
+		Total: p.Total,
+		Next:  p.Next,
+		Prev:  p.Prev,
+	}
+}
+
+// ErrorStatus is the response status for a list that failed to load, the
+// cursor can be invalid for the requested sort or what the list belongs to
+// can be missing.
+func (p *Pagination) ErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidCursor):
+		return http.StatusBadRequest
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
+
+func (p *Pagination) isBackward() bool {
+	return p.Cursor != nil && p.Cursor.Before
+}
+
+func (p *Pagination) newCursor(value interface{}, id uint, before bool) string {
+	return Encode(&Cursor{
+		SortBy: p.key.SortBy,
+		Value:  formatValue(value),
+		ID:     id,
+		Before: before,
+	})
+}
+
+// Sort keys are times, counters or scores.
+func formatValue(value interface{}) string {
+	switch value := value.(type) {
+	case time.Time:
+		return value.UTC().Format(time.RFC3339Nano)
+	case *time.Time:
+		if value == nil {
+			return ""
+		}
+		return value.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func parseValue(value string) (interface{}, error) {
+	if valueTime, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return valueTime, nil
+	}
+
+	if valueInt, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return valueInt, nil
+	}
+
+	return strconv.ParseFloat(value, 64)
+}
@@ -0,0 +1,245 @@
+package pagination
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var positionKey = Key{SortBy: "position", Column: "items.position", IdColumn: "items.id"}
+
+type item struct {
+	ID       uint
+	Position int
+}
+
+func newDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user@tcp(localhost:3306)/db", SkipInitializeWithVersion: true}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestEncodeDecode(t *testing.T) {
+	cursor := &Cursor{SortBy: "created_at", Value: "2024-01-02T03:04:05Z", ID: 7, Before: true}
+
+	decoded, err := Decode(Encode(cursor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *decoded != *cursor {
+		t.Errorf("decoded = %+v, want %+v", decoded, cursor)
+	}
+
+	for _, token := range []string{"not base64!", Encode(&Cursor{Value: "1", ID: 1}), "e30"} {
+		if _, err = Decode(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Decode(%q) error = %v, want ErrInvalidCursor", token, err)
+		}
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	moment := time.Date(2024, 1, 2, 3, 4, 5, 600, time.FixedZone("", 3*60*60))
+
+	value, err := parseValue(formatValue(moment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed, ok := value.(time.Time); !ok || !parsed.Equal(moment) {
+		t.Errorf("time = %v, want %v", value, moment)
+	}
+
+	if value, _ = parseValue(formatValue(int64(42))); value != int64(42) {
+		t.Errorf("int = %v", value)
+	}
+	if value, _ = parseValue(formatValue(1.5)); value != 1.5 {
+		t.Errorf("float = %v", value)
+	}
+}
+
+func TestTrimForward(t *testing.T) {
+	p := &Pagination{Limit: 2, Offset: -1}
+	if order := p.OrderBy(positionKey); order != "items.position asc, items.id asc" {
+		t.Errorf("order = %q", order)
+	}
+
+	// One extra item means there is a next page
+	items := []item{{ID: 1, Position: 1}, {ID: 2, Position: 2}, {ID: 3, Position: 3}}
+	count := p.Trim(items, func(i int) (interface{}, uint) {
+		return items[i].Position, items[i].ID
+	})
+	if count != 2 {
+		t.Fatalf("count = %d, want 2", count)
+	}
+	if p.Prev != "" {
+		t.Errorf("first page has prev %q", p.Prev)
+	}
+
+	next, err := Decode(p.Next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *next != (Cursor{SortBy: "position", Value: "2", ID: 2}) {
+		t.Errorf("next = %+v", next)
+	}
+
+	// The last page has no next one
+	p = &Pagination{Limit: 2, Cursor: next}
+	p.OrderBy(positionKey)
+	items = []item{{ID: 3, Position: 3}}
+	if count = p.Trim(items, func(i int) (interface{}, uint) {
+		return items[i].Position, items[i].ID
+	}); count != 1 || p.Next != "" || p.Prev == "" {
+		t.Errorf("last page: count = %d, next = %q, prev = %q", count, p.Next, p.Prev)
+	}
+}
+
+func TestTrimBackward(t *testing.T) {
+	p := &Pagination{Limit: 2, Cursor: &Cursor{SortBy: "position", Value: "4", ID: 4, Before: true}}
+	if order := p.OrderBy(positionKey); order != "items.position desc, items.id desc" {
+		t.Errorf("backward order = %q", order)
+	}
+
+	// Going back the nearest items come first, the extra one means there
+	// is a page before
+	items := []item{{ID: 3, Position: 3}, {ID: 2, Position: 2}, {ID: 1, Position: 1}}
+	count := p.Trim(items, func(i int) (interface{}, uint) {
+		return items[i].Position, items[i].ID
+	})
+	if count != 2 {
+		t.Fatalf("count = %d, want 2", count)
+	}
+	if items[0].ID != 2 || items[1].ID != 3 {
+		t.Errorf("page = %+v, want items 2 and 3 in order", items[:count])
+	}
+
+	prev, err := Decode(p.Prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *prev != (Cursor{SortBy: "position", Value: "2", ID: 2, Before: true}) {
+		t.Errorf("prev = %+v", prev)
+	}
+	next, err := Decode(p.Next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *next != (Cursor{SortBy: "position", Value: "3", ID: 3}) {
+		t.Errorf("next = %+v", next)
+	}
+}
+
+func TestScope(t *testing.T) {
+	db := newDryRunDB(t)
+
+	p := &Pagination{Limit: 2, Cursor: &Cursor{SortBy: "position", Value: "2", ID: 2}}
+	var items []item
+	result := db.Table("items").Scopes(p.Scope).Order(p.OrderBy(positionKey)).Find(&items)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	sql := result.Statement.SQL.String()
+	for _, want := range []string{"(items.position > ? OR (items.position = ? AND items.id > ?))", "LIMIT ?"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("sql %q has no %q", sql, want)
+		}
+	}
+	// The extra row tells if there is a next page
+	if vars := result.Statement.Vars; len(vars) != 4 || vars[3] != 3 {
+		t.Errorf("vars = %v, want the cursor and a limit of 3", vars)
+	}
+
+	// Descending lists go down from the cursor
+	p = &Pagination{Limit: 2, Cursor: &Cursor{SortBy: "created_at", Value: "2024-01-02T03:04:05Z", ID: 2}}
+	result = db.Table("items").Scopes(p.Scope).Order(p.OrderBy(Key{SortBy: "created_at", Column: "items.created_at", IdColumn: "items.id", Desc: true})).Find(&items)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	if sql = result.Statement.SQL.String(); !strings.Contains(sql, "items.created_at < ?") {
+		t.Errorf("descending sql = %q", sql)
+	}
+}
+
+func TestScopeInvalidCursor(t *testing.T) {
+	db := newDryRunDB(t)
+	var items []item
+
+	// A cursor of another sort
+	p := &Pagination{Limit: 2, Cursor: &Cursor{SortBy: "created_at", Value: "2024-01-02T03:04:05Z", ID: 2}}
+	result := db.Table("items").Scopes(p.Scope).Order(p.OrderBy(positionKey)).Find(&items)
+	if !errors.Is(result.Error, ErrInvalidCursor) {
+		t.Errorf("mismatched sort error = %v, want ErrInvalidCursor", result.Error)
+	}
+
+	// A list without an order would silently go back to the first page
+	p = &Pagination{Limit: 2, Cursor: &Cursor{SortBy: "position", Value: "2", ID: 2}}
+	result = db.Table("items").Scopes(p.Scope).Find(&items)
+	if !errors.Is(result.Error, ErrInvalidCursor) {
+		t.Errorf("unordered list error = %v, want ErrInvalidCursor", result.Error)
+	}
+
+	p = &Pagination{Limit: 2, Cursor: &Cursor{SortBy: "position", Value: "two", ID: 2}}
+	result = db.Table("items").Scopes(p.Scope).Order(p.OrderBy(positionKey)).Find(&items)
+	if !errors.Is(result.Error, ErrInvalidCursor) {
+		t.Errorf("invalid value error = %v, want ErrInvalidCursor", result.Error)
+	}
+}
+
+func TestScopeOffset(t *testing.T) {
+	db := newDryRunDB(t)
+	var items []item
+
+	p := &Pagination{Limit: 20, Offset: 40}
+	result := db.Table("items").Scopes(p.Scope).Find(&items)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	if sql := result.Statement.SQL.String(); !strings.Contains(sql, "LIMIT ? OFFSET ?") {
+		t.Errorf("offset sql = %q", sql)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	p := &Pagination{}
+	for err, want := range map[error]int{
+		ErrInvalidCursor:              http.StatusBadRequest,
+		gorm.ErrRecordNotFound:        http.StatusNotFound,
+		errors.New("connection lost"): http.StatusInternalServerError,
+	} {
+		if got := p.ErrorStatus(err); got != want {
+			t.Errorf("ErrorStatus(%v) = %d, want %d", err, got, want)
+		}
+	}
+}
+
+func TestNewList(t *testing.T) {
+	total := int64(3)
+	p := &Pagination{Total: &total, Next: "next"}
+
+	list := p.NewList(nil)
+	if items, ok := list.Items.([]struct{}); !ok || items == nil {
+		t.Errorf("empty list items = %#v, want an empty array", list.Items)
+	}
+	if list.Total != &total || list.Next != "next" || list.Prev != "" {
+		t.Errorf("list = %+v", list)
+	}
+
+	var empty []*item
+	if _, ok := p.NewList(empty).Items.([]struct{}); !ok {
+		t.Errorf("nil slice items = %#v, want an empty array", p.NewList(empty).Items)
+	}
+}
@@ -1,6 +1,31 @@
 package pagination
 
 type Pagination struct {
-	Limit  int `json:"limit,omitempty"`
-	Offset int `json:"offset,omitempty"`
+	Limit     int     `json:"limit,omitempty"`
+	Offset    int     `json:"offset,omitempty"`
+	Cursor    *Cursor `json:"-"`
+	WithTotal bool    `json:"-"`
+	Total     *int64  `json:"-"`
+	Next      string  `json:"-"`
+	Prev      string  `json:"-"`
+	key       *Key
+}
+
+// Cursor points right after the last item of the previous page, or right
+// before the first item of the next one when Before is set. It is passed to
+// clients only as an opaque token, see Encode.
+type Cursor struct {
+	SortBy string `json:"s"`
+	Value  string `json:"v"`
+	ID     uint   `json:"i"`
+	Before bool   `json:"b,omitempty"`
+}
+
+// Key is the order of a list: Column is compared with the cursor value,
+// IdColumn breaks ties between rows with the same value.
+type Key struct {
+	SortBy   string
+	Column   string
+	IdColumn string
+	Desc     bool
 }
@@ -0,0 +1,221 @@
+package playlist
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"gorm.io/gorm"
+	"net/http"
+	"nine-dubz/internal/pagination"
+	"nine-dubz/internal/response"
+	"nine-dubz/internal/user"
+	"strconv"
+)
+
+type Handler struct {
+	PlaylistUseCase *UseCase
+	UserHandler     *user.Handler
+}
+
+func NewHandler(uc *UseCase, uh *user.Handler) *Handler {
+	return &Handler{
+		PlaylistUseCase: uc,
+		UserHandler:     uh,
+	}
+}
+
+// The user's watch later list is also reachable as /playlist/watch-later
+const watchLaterAlias = "watch-later"
+
+// getPlaylistId resolves the playlist ID from the URL, create makes the
+// watch later list if the user has none yet
+func (h *Handler) getPlaylistId(r *http.Request, userId *uint, create bool) (uint, error) {
+	playlistIdParam := chi.URLParam(r, "playlistId")
+	if playlistIdParam == watchLaterAlias {
+		if userId == nil {
+			return 0, errors.New("not authorized")
+		}
+
+		return h.PlaylistUseCase.GetWatchLaterId(*userId, create)
+	}
+
+	playlistId, err := strconv.ParseUint(playlistIdParam, 10, 32)
+
+	return uint(playlistId), err
+}
+
+func (h *Handler) AddHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+
+	playlistAddRequest := &AddRequest{}
+	if err := json.NewDecoder(r.Body).Decode(playlistAddRequest); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't parse fields")
+		return
+	}
+
+	playlist, err := h.PlaylistUseCase.Add(userId, playlistAddRequest)
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't add playlist: "+err.Error())
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, playlist)
+}
+
+func (h *Handler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+	playlistId, err := h.getPlaylistId(r, &userId, false)
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Invalid playlist id")
+		return
+	}
+
+	playlistUpdateRequest := &UpdateRequest{}
+	if err = json.NewDecoder(r.Body).Decode(playlistUpdateRequest); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't parse fields")
+		return
+	}
+	playlistUpdateRequest.ID = playlistId
+
+	if err = h.PlaylistUseCase.Update(userId, playlistUpdateRequest); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't update playlist: "+err.Error())
+		return
+	}
+
+	response.RenderSuccess(w, r, http.StatusOK, "")
+}
+
+func (h *Handler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+	playlistId, err := h.getPlaylistId(r, &userId, false)
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Invalid playlist id")
+		return
+	}
+
+	if err = h.PlaylistUseCase.Delete(userId, playlistId); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't delete playlist: "+err.Error())
+		return
+	}
+
+	response.RenderSuccess(w, r, http.StatusOK, "")
+}
+
+// GetHandler returns the playlist page: the playlist and the first page of
+// its movies, next pages come with the movies cursor.
+func (h *Handler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(*uint)
+	pagination := r.Context().Value("pagination").(*pagination.Pagination)
+	playlistId, err := h.getPlaylistId(r, userId, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) && userId != nil {
+		// The watch later list isn't created until a movie is added to it
+		playlist, err := h.PlaylistUseCase.GetEmptyWatchLater(*userId)
+		if err != nil {
+			response.RenderError(w, r, http.StatusNotFound, "Playlist not found")
+			return
+		}
+
+		render.JSON(w, r, &PageResponse{Playlist: playlist, Movies: pagination.NewList(nil)})
+		return
+	} else if err != nil {
+		response.RenderError(w, r, http.StatusNotFound, "Playlist not found")
+		return
+	}
+
+	playlist, err := h.PlaylistUseCase.Get(userId, playlistId)
+	if err != nil {
+		response.RenderError(w, r, http.StatusNotFound, "Playlist not found")
+		return
+	}
+
+	moviesResponse, err := h.PlaylistUseCase.GetMovies(userId, playlist.ID, pagination)
+	if err != nil {
+		render.Status(r, pagination.ErrorStatus(err))
+		render.JSON(w, r, &PageResponse{Playlist: playlist, Movies: pagination.NewList(nil)})
+		return
+	}
+
+	render.JSON(w, r, &PageResponse{Playlist: playlist, Movies: pagination.NewList(moviesResponse)})
+}
+
+func (h *Handler) GetMultipleByChannelHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(*uint)
+	pagination := r.Context().Value("pagination").(*pagination.Pagination)
+	channelId, err := strconv.ParseUint(chi.URLParam(r, "channelId"), 10, 32)
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Invalid Channel ID")
+		return
+	}
+
+	playlists, err := h.PlaylistUseCase.GetMultipleByChannel(userId, uint(channelId), pagination)
+	if err != nil {
+		render.Status(r, pagination.ErrorStatus(err))
+		render.JSON(w, r, pagination.NewList(nil))
+		return
+	}
+
+	render.JSON(w, r, pagination.NewList(playlists))
+}
+
+func (h *Handler) AddMovieHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+	playlistId, err := h.getPlaylistId(r, &userId, true)
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Invalid playlist id")
+		return
+	}
+
+	addMovieRequest := &AddMovieRequest{}
+	if err = json.NewDecoder(r.Body).Decode(addMovieRequest); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't parse fields")
+		return
+	}
+
+	if err = h.PlaylistUseCase.AddMovie(userId, playlistId, addMovieRequest.Movie); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't add movie: "+err.Error())
+		return
+	}
+
+	response.RenderSuccess(w, r, http.StatusOK, "")
+}
+
+func (h *Handler) RemoveMovieHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+	playlistId, err := h.getPlaylistId(r, &userId, false)
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Invalid playlist id")
+		return
+	}
+
+	err = h.PlaylistUseCase.RemoveMovie(userId, playlistId, chi.URLParam(r, "movieCode"))
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't remove movie: "+err.Error())
+		return
+	}
+
+	response.RenderSuccess(w, r, http.StatusOK, "")
+}
+
+func (h *Handler) ReorderHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(uint)
+	playlistId, err := h.getPlaylistId(r, &userId, false)
+	if err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Invalid playlist id")
+		return
+	}
+
+	reorderRequest := &ReorderRequest{}
+	if err = json.NewDecoder(r.Body).Decode(reorderRequest); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't parse fields")
+		return
+	}
+
+	if err = h.PlaylistUseCase.Reorder(userId, playlistId, reorderRequest.Movies); err != nil {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't reorder movies: "+err.Error())
+		return
+	}
+
+	response.RenderSuccess(w, r, http.StatusOK, "")
+}
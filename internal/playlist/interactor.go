@@ -0,0 +1,22 @@
+package playlist
+
+import "nine-dubz/internal/pagination"
+
+type Interactor interface {
+	Create(playlist *Playlist) error
+	UpdatesSelectWhere(playlist *Playlist, selectQuery, whereQuery interface{}) (int64, error)
+	Delete(id uint) error
+	Get(id uint) (*Playlist, error)
+	GetWhere(where interface{}) (*Playlist, error)
+	GetWhereMultiple(where interface{}, pagination *pagination.Pagination, order string) ([]Playlist, error)
+	Count(where interface{}) (int64, error)
+	AddItem(item *Item, maxItems int64) error
+	DeleteItem(playlistId, itemId uint) error
+	Reorder(playlistId uint, itemIds []uint) error
+	GetItem(playlistId, movieId uint) (*Item, error)
+	GetItems(userId *uint, playlistId uint, pagination *pagination.Pagination, order string) ([]Item, error)
+	GetItemCodes(playlistId uint) ([]ItemCode, error)
+	GetNeighbourItem(userId *uint, item *Item, before bool) (*Item, error)
+	CountItems(userId *uint, playlistIds []uint) (map[uint]int64, error)
+	CountItemsBefore(userId *uint, item *Item) (int64, error)
+}
@@ -0,0 +1,450 @@
+package playlist
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"nine-dubz/internal/movie"
+	"nine-dubz/internal/pagination"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Channel playlists go from the last changed one, movies in a playlist go
+// by position
+var (
+	channelSortKey = pagination.Key{SortBy: "updated_at", Column: "playlists.updated_at", IdColumn: "playlists.id", Desc: true}
+	itemsSortKey   = pagination.Key{SortBy: "position", Column: "playlist_items.position", IdColumn: "playlist_items.id"}
+)
+
+type UseCase struct {
+	PlaylistInteractor Interactor
+	MovieUseCase       *movie.UseCase
+}
+
+func New(db *gorm.DB, muc *movie.UseCase) *UseCase {
+	return &UseCase{
+		PlaylistInteractor: &Repository{
+			DB: db,
+		},
+		MovieUseCase: muc,
+	}
+}
+
+func (uc *UseCase) Add(userId uint, addRequest *AddRequest) (*GetResponse, error) {
+	playlist := &Playlist{
+		UserId:      userId,
+		Name:        strings.TrimSpace(addRequest.Name),
+		Description: strings.TrimSpace(addRequest.Description),
+		Visibility:  addRequest.Visibility,
+		Type:        TypePlaylist,
+	}
+	if playlist.Visibility == "" {
+		playlist.Visibility = VisibilityPublic
+	}
+
+	if err := validate(playlist); err != nil {
+		return nil, err
+	}
+
+	if err := uc.PlaylistInteractor.Create(playlist); err != nil {
+		return nil, err
+	}
+
+	return uc.Get(&userId, playlist.ID)
+}
+
+func (uc *UseCase) Update(userId uint, updateRequest *UpdateRequest) error {
+	playlist, err := uc.GetOwned(userId, updateRequest.ID)
+	if err != nil {
+		return err
+	}
+	if playlist.Type == TypeWatchLater {
+		return errors.New("watch later can't be changed")
+	}
+
+	var selectFields []string
+	if updateRequest.Name != nil {
+		playlist.Name = strings.TrimSpace(*updateRequest.Name)
+		selectFields = append(selectFields, "Name")
+	}
+	if updateRequest.Description != nil {
+		playlist.Description = strings.TrimSpace(*updateRequest.Description)
+		selectFields = append(selectFields, "Description")
+	}
+	if updateRequest.Visibility != nil {
+		playlist.Visibility = *updateRequest.Visibility
+		selectFields = append(selectFields, "Visibility")
+	}
+	if len(selectFields) == 0 {
+		return nil
+	}
+
+	if err = validate(playlist); err != nil {
+		return err
+	}
+
+	_, err = uc.PlaylistInteractor.UpdatesSelectWhere(
+		&Playlist{Name: playlist.Name, Description: playlist.Description, Visibility: playlist.Visibility},
+		selectFields,
+		map[string]interface{}{"id": playlist.ID},
+	)
+
+	return err
+}
+
+func (uc *UseCase) Delete(userId, id uint) error {
+	playlist, err := uc.GetOwned(userId, id)
+	if err != nil {
+		return err
+	}
+	if playlist.Type == TypeWatchLater {
+		return errors.New("watch later can't be deleted")
+	}
+
+	return uc.PlaylistInteractor.Delete(id)
+}
+
+// GetWatchLaterId returns the ID of the user's watch later list. The list
+// is created on first use when create is set, otherwise a missing list is
+// gorm.ErrRecordNotFound.
+func (uc *UseCase) GetWatchLaterId(userId uint, create bool) (uint, error) {
+	playlist, err := uc.PlaylistInteractor.GetWhere(map[string]interface{}{"watch_later_user_id": userId})
+	if err == nil || !create || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return playlist.ID, err
+	}
+
+	playlist = NewWatchLater(userId)
+	err = uc.PlaylistInteractor.Create(playlist)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Created by a concurrent request
+		playlist, err = uc.PlaylistInteractor.GetWhere(map[string]interface{}{"watch_later_user_id": userId})
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return playlist.ID, nil
+}
+
+// GetEmptyWatchLater is the page of a watch later list that isn't created
+// yet, it's created when the first movie is added.
+func (uc *UseCase) GetEmptyWatchLater(userId uint) (*GetResponse, error) {
+	owner, err := uc.MovieUseCase.UserUseCase.GetById(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist := NewWatchLater(userId)
+	playlist.User = *owner
+
+	return NewGetResponse(playlist, 0), nil
+}
+
+// RemoveWatched takes a movie the user watched to the end off their watch
+// later list.
+func (uc *UseCase) RemoveWatched(userId, movieId uint) error {
+	playlist, err := uc.PlaylistInteractor.GetWhere(map[string]interface{}{"watch_later_user_id": userId})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	item, err := uc.PlaylistInteractor.GetItem(playlist.ID, movieId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return uc.PlaylistInteractor.DeleteItem(playlist.ID, item.ID)
+}
+
+func validate(playlist *Playlist) error {
+	if utf8.RuneCountInString(playlist.Name) == 0 {
+		return errors.New("playlist name is required")
+	}
+	if utf8.RuneCountInString(playlist.Name) > 150 {
+		return errors.New("playlist name too long")
+	}
+	if utf8.RuneCountInString(playlist.Description) > 5000 {
+		return errors.New("playlist description too long")
+	}
+	if !slices.Contains(Visibilities, playlist.Visibility) {
+		return errors.New("invalid visibility")
+	}
+
+	return nil
+}
+
+// HasAccess reports whether the user (nil for guests) may open the
+// playlist. Unlisted playlists are open to anyone with the link, watch
+// later lists only to their owner.
+func HasAccess(userId *uint, playlist *Playlist) bool {
+	if userId != nil && playlist.UserId == *userId {
+		return true
+	}
+
+	if playlist.Type == TypeWatchLater {
+		return false
+	}
+
+	return playlist.Visibility == VisibilityPublic || playlist.Visibility == VisibilityUnlisted
+}
+
+// GetOwned returns the playlist if it belongs to the user
+func (uc *UseCase) GetOwned(userId, id uint) (*Playlist, error) {
+	playlist, err := uc.PlaylistInteractor.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if playlist.UserId != userId {
+		return nil, errors.New("not allowed")
+	}
+
+	return playlist, nil
+}
+
+func (uc *UseCase) GetAccessible(userId *uint, id uint) (*Playlist, error) {
+	playlist, err := uc.PlaylistInteractor.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !HasAccess(userId, playlist) {
+		return nil, errors.New("not allowed")
+	}
+
+	return playlist, nil
+}
+
+func (uc *UseCase) Get(userId *uint, id uint) (*GetResponse, error) {
+	playlist, err := uc.GetAccessible(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := uc.PlaylistInteractor.CountItems(userId, []uint{playlist.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewGetResponse(playlist, counts[playlist.ID]), nil
+}
+
+// GetMultipleByChannel lists public playlists of the channel, the owner
+// sees all of them.
+func (uc *UseCase) GetMultipleByChannel(userId *uint, channelId uint, pagination *pagination.Pagination) ([]*GetResponse, error) {
+	where := map[string]interface{}{"user_id": channelId, "type": TypePlaylist}
+	if userId == nil || *userId != channelId {
+		where["visibility"] = VisibilityPublic
+	}
+
+	playlists, err := uc.PlaylistInteractor.GetWhereMultiple(where, pagination, pagination.OrderBy(channelSortKey))
+	if err != nil {
+		return nil, err
+	}
+	playlists = playlists[:pagination.Trim(playlists, func(i int) (interface{}, uint) {
+		return playlists[i].UpdatedAt, playlists[i].ID
+	})]
+
+	if pagination.WithTotal {
+		total, err := uc.PlaylistInteractor.Count(where)
+		if err != nil {
+			return nil, err
+		}
+		pagination.Total = &total
+	}
+
+	var playlistIds []uint
+	for _, playlist := range playlists {
+		playlistIds = append(playlistIds, playlist.ID)
+	}
+
+	var counts map[uint]int64
+	if len(playlistIds) > 0 {
+		counts, err = uc.PlaylistInteractor.CountItems(userId, playlistIds)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var playlistsPayload []*GetResponse
+	for key := range playlists {
+		playlistsPayload = append(playlistsPayload, NewGetResponse(&playlists[key], counts[playlists[key].ID]))
+	}
+
+	return playlistsPayload, nil
+}
+
+// GetMovies returns a page of the playlist movies the user can watch
+func (uc *UseCase) GetMovies(userId *uint, id uint, pagination *pagination.Pagination) ([]*movie.GetResponse, error) {
+	playlist, err := uc.GetAccessible(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := uc.PlaylistInteractor.GetItems(userId, playlist.ID, pagination, pagination.OrderBy(itemsSortKey))
+	if err != nil {
+		return nil, err
+	}
+	items = items[:pagination.Trim(items, func(i int) (interface{}, uint) {
+		return items[i].Position, items[i].ID
+	})]
+
+	if pagination.WithTotal {
+		counts, err := uc.PlaylistInteractor.CountItems(userId, []uint{playlist.ID})
+		if err != nil {
+			return nil, err
+		}
+		total := counts[playlist.ID]
+		pagination.Total = &total
+	}
+
+	var movieIds []uint
+	for _, item := range items {
+		movieIds = append(movieIds, item.MovieId)
+	}
+
+	return uc.MovieUseCase.GetMultipleAccessibleByIds(userId, movieIds)
+}
+
+// AddMovie adds a movie the owner can watch to the end of the playlist
+func (uc *UseCase) AddMovie(userId, id uint, movieCode string) error {
+	playlist, err := uc.GetOwned(userId, id)
+	if err != nil {
+		return err
+	}
+
+	movie, err := uc.MovieUseCase.GetAccessible(&userId, movieCode)
+	if err != nil {
+		return errors.New("movie not found")
+	}
+
+	err = uc.PlaylistInteractor.AddItem(&Item{PlaylistId: playlist.ID, MovieId: movie.ID}, MaxMovies)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return errors.New("movie is already in the playlist")
+	}
+
+	return err
+}
+
+func (uc *UseCase) RemoveMovie(userId, id uint, movieCode string) error {
+	playlist, err := uc.GetOwned(userId, id)
+	if err != nil {
+		return err
+	}
+
+	items, err := uc.PlaylistInteractor.GetItemCodes(playlist.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if item.Code == movieCode {
+			return uc.PlaylistInteractor.DeleteItem(playlist.ID, item.ID)
+		}
+	}
+
+	return errors.New("movie is not in the playlist")
+}
+
+// Reorder puts the listed movies first in the given order, the rest of the
+// playlist keeps its order after them.
+func (uc *UseCase) Reorder(userId, id uint, movieCodes []string) error {
+	playlist, err := uc.GetOwned(userId, id)
+	if err != nil {
+		return err
+	}
+
+	items, err := uc.PlaylistInteractor.GetItemCodes(playlist.ID)
+	if err != nil {
+		return err
+	}
+
+	itemIdsByCode := make(map[string]uint)
+	for _, item := range items {
+		itemIdsByCode[item.Code] = item.ID
+	}
+
+	var itemIds []uint
+	for _, movieCode := range movieCodes {
+		itemId, ok := itemIdsByCode[movieCode]
+		if !ok {
+			return errors.New("movie is not in the playlist")
+		}
+		if slices.Contains(itemIds, itemId) {
+			return errors.New("movie is listed twice")
+		}
+		itemIds = append(itemIds, itemId)
+	}
+
+	for _, item := range items {
+		if !slices.Contains(itemIds, item.ID) {
+			itemIds = append(itemIds, item.ID)
+		}
+	}
+
+	return uc.PlaylistInteractor.Reorder(playlist.ID, itemIds)
+}
+
+// GetContext returns the place of the movie in the playlist with the
+// nearest movies before and after it the user can watch.
+func (uc *UseCase) GetContext(userId *uint, playlistId, movieId uint) (*movie.PlaylistContext, error) {
+	playlist, err := uc.GetAccessible(userId, playlistId)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := uc.PlaylistInteractor.GetItem(playlist.ID, movieId)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := uc.PlaylistInteractor.CountItemsBefore(userId, item)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := uc.PlaylistInteractor.CountItems(userId, []uint{playlist.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	playlistContext := &movie.PlaylistContext{
+		ID:          playlist.ID,
+		Name:        playlist.Name,
+		Position:    before + 1,
+		MoviesCount: counts[playlist.ID],
+	}
+
+	playlistContext.Prev, err = uc.getNeighbour(userId, item, true)
+	if err != nil {
+		return nil, err
+	}
+
+	playlistContext.Next, err = uc.getNeighbour(userId, item, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return playlistContext, nil
+}
+
+func (uc *UseCase) getNeighbour(userId *uint, item *Item, before bool) (*movie.GetResponse, error) {
+	neighbour, err := uc.PlaylistInteractor.GetNeighbourItem(userId, item, before)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	movies, err := uc.MovieUseCase.GetMultipleAccessibleByIds(userId, []uint{neighbour.MovieId})
+	if err != nil || len(movies) == 0 {
+		return nil, err
+	}
+
+	return movies[0], nil
+}
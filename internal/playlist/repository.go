@@ -0,0 +1,252 @@
+package playlist
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"nine-dubz/internal/movie"
+	"nine-dubz/internal/pagination"
+	"time"
+)
+
+type Repository struct {
+	DB *gorm.DB
+}
+
+func (r *Repository) Create(playlist *Playlist) error {
+	return r.DB.Create(playlist).Error
+}
+
+func (r *Repository) UpdatesSelectWhere(playlist *Playlist, selectQuery, whereQuery interface{}) (int64, error) {
+	result := r.DB.Select(selectQuery).Where(whereQuery).Updates(playlist)
+
+	return result.RowsAffected, result.Error
+}
+
+func (r *Repository) Delete(id uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("playlist_id = ?", id).Delete(&Item{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&Playlist{}, id).Error
+	})
+}
+
+func (r *Repository) Get(id uint) (*Playlist, error) {
+	playlist := &Playlist{}
+	result := r.DB.
+		Preload("User").
+		Preload("User.Picture").
+		First(playlist, id)
+
+	return playlist, result.Error
+}
+
+func (r *Repository) GetWhere(where interface{}) (*Playlist, error) {
+	playlist := &Playlist{}
+	result := r.DB.Where(where).First(playlist)
+
+	return playlist, result.Error
+}
+
+func (r *Repository) GetWhereMultiple(where interface{}, pagination *pagination.Pagination, order string) ([]Playlist, error) {
+	var playlists []Playlist
+	result := r.DB.
+		Preload("User").
+		Preload("User.Picture").
+		Where(where).
+		Scopes(pagination.Scope).
+		Order(order).
+		Find(&playlists)
+
+	return playlists, result.Error
+}
+
+func (r *Repository) Count(where interface{}) (int64, error) {
+	var count int64
+	result := r.DB.Model(&Playlist{}).Where(where).Count(&count)
+
+	return count, result.Error
+}
+
+// AddItem puts the item at the end of the playlist. The playlist row is
+// locked so concurrent adds don't get the same position or overfill it.
+func (r *Repository) AddItem(item *Item, maxItems int64) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			First(&Playlist{}, item.PlaylistId).
+			Error
+		if err != nil {
+			return err
+		}
+
+		var stats struct {
+			Count    int64
+			Position int
+		}
+		err = tx.
+			Model(&Item{}).
+			Select("COUNT(*) AS count, COALESCE(MAX(position), 0) AS position").
+			Where("playlist_id = ?", item.PlaylistId).
+			Scan(&stats).
+			Error
+		if err != nil {
+			return err
+		}
+		if stats.Count >= maxItems {
+			return ErrFull
+		}
+
+		item.Position = stats.Position + 1
+		if err = tx.Create(item).Error; err != nil {
+			return err
+		}
+
+		return touch(tx, item.PlaylistId)
+	})
+}
+
+func (r *Repository) DeleteItem(playlistId, itemId uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("playlist_id = ?", playlistId).Delete(&Item{}, itemId)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return touch(tx, playlistId)
+	})
+}
+
+// Reorder numbers the items by their place in itemIds
+func (r *Repository) Reorder(playlistId uint, itemIds []uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for key, itemId := range itemIds {
+			err := tx.
+				Model(&Item{}).
+				Where("id = ? AND playlist_id = ?", itemId, playlistId).
+				UpdateColumn("position", key+1).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return touch(tx, playlistId)
+	})
+}
+
+func (r *Repository) GetItem(playlistId, movieId uint) (*Item, error) {
+	item := &Item{}
+	result := r.DB.Where("playlist_id = ? AND movie_id = ?", playlistId, movieId).First(item)
+
+	return item, result.Error
+}
+
+func (r *Repository) GetItems(userId *uint, playlistId uint, pagination *pagination.Pagination, order string) ([]Item, error) {
+	var items []Item
+	result := r.DB.
+		Scopes(withMovie(userId)).
+		Where("playlist_items.playlist_id = ?", playlistId).
+		Scopes(pagination.Scope).
+		Order(order).
+		Find(&items)
+
+	return items, result.Error
+}
+
+// GetItemCodes returns all items of the playlist in order, including items
+// of deleted movies.
+func (r *Repository) GetItemCodes(playlistId uint) ([]ItemCode, error) {
+	var items []ItemCode
+	result := r.DB.
+		Model(&Item{}).
+		Select("playlist_items.id, playlist_items.movie_id, movies.code").
+		Joins("LEFT JOIN movies ON movies.id = playlist_items.movie_id").
+		Where("playlist_items.playlist_id = ?", playlistId).
+		Order("playlist_items.position, playlist_items.id").
+		Scan(&items)
+
+	return items, result.Error
+}
+
+// GetNeighbourItem returns the nearest item right before or after the item
+// with a movie the user can watch
+func (r *Repository) GetNeighbourItem(userId *uint, item *Item, before bool) (*Item, error) {
+	operator, order := ">", "playlist_items.position, playlist_items.id"
+	if before {
+		operator, order = "<", "playlist_items.position desc, playlist_items.id desc"
+	}
+
+	neighbour := &Item{}
+	result := r.DB.
+		Scopes(withMovie(userId)).
+		Where("playlist_items.playlist_id = ?", item.PlaylistId).
+		Where(
+			"(playlist_items.position "+operator+" ? OR (playlist_items.position = ? AND playlist_items.id "+operator+" ?))",
+			item.Position, item.Position, item.ID,
+		).
+		Order(order).
+		First(neighbour)
+
+	return neighbour, result.Error
+}
+
+func (r *Repository) CountItems(userId *uint, playlistIds []uint) (map[uint]int64, error) {
+	var rows []struct {
+		PlaylistId uint
+		Count      int64
+	}
+	result := r.DB.
+		Model(&Item{}).
+		Scopes(withMovie(userId)).
+		Select("playlist_items.playlist_id, COUNT(*) AS count").
+		Where("playlist_items.playlist_id IN ?", playlistIds).
+		Group("playlist_items.playlist_id").
+		Scan(&rows)
+
+	counts := make(map[uint]int64)
+	for _, row := range rows {
+		counts[row.PlaylistId] = row.Count
+	}
+
+	return counts, result.Error
+}
+
+func (r *Repository) CountItemsBefore(userId *uint, item *Item) (int64, error) {
+	var count int64
+	result := r.DB.
+		Model(&Item{}).
+		Scopes(withMovie(userId)).
+		Where("playlist_items.playlist_id = ?", item.PlaylistId).
+		Where(
+			"(playlist_items.position < ? OR (playlist_items.position = ? AND playlist_items.id < ?))",
+			item.Position, item.Position, item.ID,
+		).
+		Count(&count)
+
+	return count, result.Error
+}
+
+// Items of deleted movies stay in the playlist but are not listed or
+// counted, neither are items of movies the user can't watch
+func withMovie(userId *uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.
+			Joins("JOIN movies ON movies.id = playlist_items.movie_id AND movies.deleted_at IS NULL").
+			Scopes(movie.WhereAccessible(userId))
+	}
+}
+
+// Changes to the movies bring the playlist up in the channel list
+func touch(tx *gorm.DB, playlistId uint) error {
+	return tx.
+		Model(&Playlist{}).
+		Where("id = ?", playlistId).
+		UpdateColumn("updated_at", time.Now()).
+		Error
+}
@@ -0,0 +1,36 @@
+package playlist
+
+import (
+	"github.com/go-chi/chi/v5"
+	"nine-dubz/internal/pagination"
+)
+
+func (h *Handler) Routes(r chi.Router) {
+	r.Route("/playlist", func(r chi.Router) {
+		r.
+			With(h.UserHandler.IsAuthorized).
+			Post("/", h.AddHandler)
+
+		r.Route("/{playlistId}", func(r chi.Router) {
+			r.
+				With(pagination.SetPaginationContextMiddleware).
+				With(h.UserHandler.TryToGetUserId).
+				Get("/", h.GetHandler)
+
+			r.
+				With(h.UserHandler.IsAuthorized).
+				Group(func(r chi.Router) {
+					r.Post("/", h.UpdateHandler)
+					r.Delete("/", h.DeleteHandler)
+					r.Post("/movies", h.AddMovieHandler)
+					r.Delete("/movies/{movieCode}", h.RemoveMovieHandler)
+					r.Post("/order", h.ReorderHandler)
+				})
+		})
+
+		r.
+			With(pagination.SetPaginationContextMiddleware).
+			With(h.UserHandler.TryToGetUserId).
+			Get("/channel/{channelId}", h.GetMultipleByChannelHandler)
+	})
+}
@@ -0,0 +1,131 @@
+package playlist
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"nine-dubz/internal/response"
+	"nine-dubz/internal/user"
+	"time"
+)
+
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+)
+
+var Visibilities = []string{VisibilityPublic, VisibilityUnlisted, VisibilityPrivate}
+
+// Every user has one watch later list, it's a private playlist that can't
+// be renamed or deleted
+const (
+	TypePlaylist   = "playlist"
+	TypeWatchLater = "watch_later"
+)
+
+const MaxMovies = 500
+
+var ErrFull = errors.New("playlist is full")
+
+// Playlist is a channel's ordered list of movies. Public playlists are
+// listed on the channel, unlisted ones are seen only by link and private
+// ones only by the owner.
+type Playlist struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time      `gorm:"index"`
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	UserId      uint           `gorm:"not null;index:idx_playlists_user_type,priority:1"`
+	User        user.User      `gorm:"foreignKey:UserId;references:ID"`
+	Name        string         `gorm:"size:150;not null"`
+	Description string         `gorm:"size:5000"`
+	Visibility  string         `gorm:"size:20;not null;default:'public'"`
+	Type        string         `gorm:"size:20;not null;default:'playlist';index:idx_playlists_user_type,priority:2"`
+	// WatchLaterUserId is set only on watch later lists, a user has one
+	WatchLaterUserId *uint `gorm:"uniqueIndex"`
+}
+
+func NewWatchLater(userId uint) *Playlist {
+	return &Playlist{
+		UserId:           userId,
+		Name:             "Watch later",
+		Visibility:       VisibilityPrivate,
+		Type:             TypeWatchLater,
+		WatchLaterUserId: &userId,
+	}
+}
+
+// Item is a movie in a playlist, a movie is in a playlist at most once.
+// Items go by position, new ones are added to the end.
+type Item struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	PlaylistId uint `gorm:"not null;uniqueIndex:idx_playlist_items_movie;index:idx_playlist_items_position,priority:1"`
+	MovieId    uint `gorm:"not null;uniqueIndex:idx_playlist_items_movie;index"`
+	Position   int  `gorm:"not null;index:idx_playlist_items_position,priority:2"`
+}
+
+func (Item) TableName() string {
+	return "playlist_items"
+}
+
+// ItemCode is an item with the code of its movie
+type ItemCode struct {
+	ID      uint
+	MovieId uint
+	Code    string
+}
+
+type AddRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"`
+}
+
+type UpdateRequest struct {
+	ID          uint    `json:"-"`
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Visibility  *string `json:"visibility"`
+}
+
+type AddMovieRequest struct {
+	Movie string `json:"movie"`
+}
+
+// ReorderRequest lists movie codes in the new order. Movies left out keep
+// their order after the listed ones.
+type ReorderRequest struct {
+	Movies []string `json:"movies"`
+}
+
+type GetResponse struct {
+	ID          uint                    `json:"id"`
+	CreatedAt   time.Time               `json:"createdAt"`
+	UpdatedAt   time.Time               `json:"updatedAt"`
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Visibility  string                  `json:"visibility"`
+	Type        string                  `json:"type"`
+	MoviesCount int64                   `json:"moviesCount"`
+	User        *user.GetPublicResponse `json:"user"`
+}
+
+func NewGetResponse(playlist *Playlist, moviesCount int64) *GetResponse {
+	return &GetResponse{
+		ID:          playlist.ID,
+		CreatedAt:   playlist.CreatedAt,
+		UpdatedAt:   playlist.UpdatedAt,
+		Name:        playlist.Name,
+		Description: playlist.Description,
+		Visibility:  playlist.Visibility,
+		Type:        playlist.Type,
+		MoviesCount: moviesCount,
+		User:        user.NewGetPublicResponse(&playlist.User),
+	}
+}
+
+type PageResponse struct {
+	Playlist *GetResponse   `json:"playlist"`
+	Movies   *response.List `json:"movies"`
+}
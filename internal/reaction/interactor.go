@@ -0,0 +1,14 @@
+package reaction
+
+import (
+	"gorm.io/gorm"
+	"nine-dubz/internal/pagination"
+)
+
+type Interactor interface {
+	Set(reaction *Reaction) error
+	Delete(userId, movieId uint) error
+	Get(userId, movieId uint) (*Reaction, error)
+	GetMultiple(where interface{}, movieScope func(db *gorm.DB) *gorm.DB, pagination *pagination.Pagination, order string) ([]Reaction, error)
+	Count(where interface{}, movieScope func(db *gorm.DB) *gorm.DB) (int64, error)
+}
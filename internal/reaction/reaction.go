@@ -0,0 +1,89 @@
+package reaction
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"nine-dubz/internal/pagination"
+	"slices"
+)
+
+// Liked movies go from the latest like
+var likedSortKey = pagination.Key{SortBy: "updated_at", Column: "reactions.updated_at", IdColumn: "reactions.id", Desc: true}
+
+type UseCase struct {
+	ReactionInteractor Interactor
+}
+
+func New(db *gorm.DB) *UseCase {
+	return &UseCase{
+		ReactionInteractor: &Repository{
+			DB: db,
+		},
+	}
+}
+
+func (uc *UseCase) Set(userId, movieId uint, reactionType string) error {
+	if !slices.Contains(Types, reactionType) {
+		return errors.New("invalid reaction type")
+	}
+
+	err := uc.ReactionInteractor.Set(&Reaction{
+		UserID:  userId,
+		MovieID: movieId,
+		Type:    reactionType,
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Another request created the first reaction of the user meanwhile,
+		// now it's found and updated
+		err = uc.ReactionInteractor.Set(&Reaction{
+			UserID:  userId,
+			MovieID: movieId,
+			Type:    reactionType,
+		})
+	}
+
+	return err
+}
+
+func (uc *UseCase) Delete(userId, movieId uint) error {
+	return uc.ReactionInteractor.Delete(userId, movieId)
+}
+
+// GetType returns the user's reaction to the movie or an empty string.
+func (uc *UseCase) GetType(userId, movieId uint) string {
+	reaction, err := uc.ReactionInteractor.Get(userId, movieId)
+	if err != nil {
+		return ""
+	}
+
+	return reaction.Type
+}
+
+// GetMultipleLiked returns IDs of movies the user liked, latest first.
+// movieScope leaves out the movies the user can't watch anymore, so pages
+// and the total only count the listed movies.
+func (uc *UseCase) GetMultipleLiked(userId uint, movieScope func(db *gorm.DB) *gorm.DB, pagination *pagination.Pagination) ([]uint, error) {
+	where := map[string]interface{}{"reactions.user_id": userId, "reactions.type": TypeLike}
+	reactions, err := uc.ReactionInteractor.GetMultiple(where, movieScope, pagination, pagination.OrderBy(likedSortKey))
+	if err != nil {
+		return nil, err
+	}
+	reactions = reactions[:pagination.Trim(reactions, func(i int) (interface{}, uint) {
+		return reactions[i].UpdatedAt, reactions[i].ID
+	})]
+
+	if pagination.WithTotal {
+		total, err := uc.ReactionInteractor.Count(where, movieScope)
+		if err != nil {
+			return nil, err
+		}
+		pagination.Total = &total
+	}
+
+	var movieIds []uint
+	for _, reaction := range reactions {
+		movieIds = append(movieIds, reaction.MovieID)
+	}
+
+	return movieIds, nil
+}
@@ -0,0 +1,118 @@
+package reaction
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"nine-dubz/internal/pagination"
+)
+
+type Repository struct {
+	DB *gorm.DB
+}
+
+// Set creates or changes the user's reaction and keeps the like and dislike
+// counters on the movie in the same transaction. Setting the same reaction
+// again changes nothing.
+func (r *Repository) Set(reaction *Reaction) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		current := &Reaction{}
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND movie_id = ?", reaction.UserID, reaction.MovieID).
+			First(current).
+			Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err = tx.Create(reaction).Error; err != nil {
+				return err
+			}
+
+			return updateCounter(tx, reaction.MovieID, reaction.Type, 1)
+		} else if err != nil {
+			return err
+		}
+
+		if current.Type == reaction.Type {
+			*reaction = *current
+			return nil
+		}
+
+		previousType := current.Type
+		if err = tx.Model(current).Update("type", reaction.Type).Error; err != nil {
+			return err
+		}
+		*reaction = *current
+
+		if err = updateCounter(tx, reaction.MovieID, previousType, -1); err != nil {
+			return err
+		}
+
+		return updateCounter(tx, reaction.MovieID, reaction.Type, 1)
+	})
+}
+
+func (r *Repository) Delete(userId, movieId uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		current := &Reaction{}
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND movie_id = ?", userId, movieId).
+			First(current).
+			Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err = tx.Delete(current).Error; err != nil {
+			return err
+		}
+
+		return updateCounter(tx, movieId, current.Type, -1)
+	})
+}
+
+func (r *Repository) Get(userId, movieId uint) (*Reaction, error) {
+	reaction := &Reaction{}
+	result := r.DB.Where("user_id = ? AND movie_id = ?", userId, movieId).First(reaction)
+
+	return reaction, result.Error
+}
+
+// GetMultiple lists reactions to the movies matched by movieScope
+func (r *Repository) GetMultiple(where interface{}, movieScope func(db *gorm.DB) *gorm.DB, pagination *pagination.Pagination, order string) ([]Reaction, error) {
+	var reactions []Reaction
+	result := r.DB.
+		Scopes(withMovie, movieScope).
+		Where(where).
+		Scopes(pagination.Scope).
+		Order(order).
+		Find(&reactions)
+
+	return reactions, result.Error
+}
+
+func (r *Repository) Count(where interface{}, movieScope func(db *gorm.DB) *gorm.DB) (int64, error) {
+	var count int64
+	result := r.DB.Model(&Reaction{}).Scopes(withMovie, movieScope).Where(where).Count(&count)
+
+	return count, result.Error
+}
+
+func withMovie(db *gorm.DB) *gorm.DB {
+	return db.Joins("JOIN movies ON movies.id = reactions.movie_id AND movies.deleted_at IS NULL")
+}
+
+func updateCounter(tx *gorm.DB, movieId uint, reactionType string, delta int) error {
+	column := "likes_count"
+	if reactionType == TypeDislike {
+		column = "dislikes_count"
+	}
+
+	return tx.
+		Table("movies").
+		Where("id = ?", movieId).
+		UpdateColumn(column, gorm.Expr(column+" + ?", delta)).
+		Error
+}
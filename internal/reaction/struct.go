@@ -0,0 +1,27 @@
+package reaction
+
+import "time"
+
+const (
+	TypeLike    = "like"
+	TypeDislike = "dislike"
+)
+
+var Types = []string{TypeLike, TypeDislike}
+
+// Reaction is the user's like or dislike of a movie, a user has at most
+// one reaction per movie.
+type Reaction struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_reactions_user_movie"`
+	MovieID   uint   `gorm:"not null;uniqueIndex:idx_reactions_user_movie;index"`
+	Type      string `gorm:"size:10;not null"`
+}
+
+type GetResponse struct {
+	Likes    int64  `json:"likes"`
+	Dislikes int64  `json:"dislikes"`
+	Reaction string `json:"reaction"`
+}
@@ -36,3 +36,12 @@ func RenderSuccess(w http.ResponseWriter, r *http.Request, status int, message s
 		Message: messageLang,
 	})
 }
+
+// List is the envelope of paginated lists. Total is counted only on request,
+// Next and Prev are cursors of the neighbour pages.
+type List struct {
+	Items interface{} `json:"items"`
+	Total *int64      `json:"total,omitempty"`
+	Next  string      `json:"next,omitempty"`
+	Prev  string      `json:"prev,omitempty"`
+}
@@ -0,0 +1,103 @@
+package search
+
+import (
+	"errors"
+	"github.com/go-chi/render"
+	"log"
+	"net/http"
+	"nine-dubz/internal/pagination"
+	"nine-dubz/internal/response"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Handler struct {
+	SearchUseCase *UseCase
+}
+
+func NewHandler(uc *UseCase) *Handler {
+	return &Handler{
+		SearchUseCase: uc,
+	}
+}
+
+func (h *Handler) SuggestHandler(w http.ResponseWriter, r *http.Request) {
+	suggestions, err := h.SearchUseCase.Suggest(r.URL.Query().Get("q"))
+	if isRequestError(err) {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't get suggestions: "+err.Error())
+		return
+	} else if err != nil {
+		log.Println("search: suggestions:", err)
+		response.RenderError(w, r, http.StatusInternalServerError, "Can't get suggestions")
+		return
+	}
+
+	render.JSON(w, r, suggestions)
+}
+
+func (h *Handler) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	pagination := r.Context().Value("pagination").(*pagination.Pagination)
+	query := r.URL.Query()
+
+	searchRequest := &SearchRequest{
+		Query: query.Get("q"),
+	}
+
+	if types := query.Get("type"); types != "" {
+		searchRequest.Types = strings.Split(types, ",")
+	}
+
+	if category := query.Get("category"); category != "" {
+		categoryId, err := strconv.ParseUint(category, 10, 32)
+		if err != nil {
+			response.RenderError(w, r, http.StatusBadRequest, "Invalid category")
+			return
+		}
+		searchRequest.Category = uint(categoryId)
+	}
+
+	if dateFrom := query.Get("date-from"); dateFrom != "" {
+		date, err := time.Parse(time.DateOnly, dateFrom)
+		if err != nil {
+			response.RenderError(w, r, http.StatusBadRequest, "Invalid date from")
+			return
+		}
+		searchRequest.DateFrom = &date
+	}
+
+	if dateTo := query.Get("date-to"); dateTo != "" {
+		date, err := time.Parse(time.DateOnly, dateTo)
+		if err != nil {
+			response.RenderError(w, r, http.StatusBadRequest, "Invalid date to")
+			return
+		}
+		// Include the whole last day
+		date = date.Add(24*time.Hour - time.Nanosecond)
+		searchRequest.DateTo = &date
+	}
+
+	searchResponse, err := h.SearchUseCase.Search(searchRequest, pagination)
+	if isRequestError(err) {
+		response.RenderError(w, r, http.StatusBadRequest, "Can't search: "+err.Error())
+		return
+	} else if err != nil {
+		log.Println("search:", err)
+		response.RenderError(w, r, http.StatusInternalServerError, "Can't search")
+		return
+	}
+
+	render.JSON(w, r, searchResponse)
+}
+
+// isRequestError tells the errors of an invalid request, which are safe to
+// show, from the failures of the index
+func isRequestError(err error) bool {
+	for _, requestErr := range []error{ErrQueryEmpty, ErrQueryTooLong, ErrInvalidType, ErrInvalidDates} {
+		if errors.Is(err, requestErr) {
+			return true
+		}
+	}
+
+	return false
+}
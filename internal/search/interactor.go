@@ -0,0 +1,8 @@
+package search
+
+type Interactor interface {
+	Search(query *Query) ([]Hit, error)
+	GetVocabulary() ([]string, error)
+	Suggest(prefix string, limit int) ([]Suggestion, error)
+	GetChannelsMovies(channelIds []uint) (map[uint][]uint, error)
+}
@@ -0,0 +1,192 @@
+package search
+
+import (
+	"nine-dubz/pkg/textsearch"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	titleWeight = 2
+	bodyWeight  = 1
+)
+
+// MemoryIndex is an inverted index kept in memory. It doesn't need
+// MySQL, so it can be used for development and embedded in tests.
+type MemoryIndex struct {
+	Documents []Document
+	Terms     map[string]map[*Document]float64
+	Mutex     *sync.RWMutex
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		Terms: make(map[string]map[*Document]float64),
+		Mutex: &sync.RWMutex{},
+	}
+}
+
+// Replace rebuilds the whole index from the given documents.
+func (mi *MemoryIndex) Replace(documents []Document) {
+	terms := make(map[string]map[*Document]float64)
+	for i := range documents {
+		document := &documents[i]
+		for _, term := range textsearch.Tokenize(document.Title) {
+			addTerm(terms, term, document, titleWeight)
+		}
+		for _, term := range textsearch.Tokenize(document.Body) {
+			addTerm(terms, term, document, bodyWeight)
+		}
+	}
+
+	mi.Mutex.Lock()
+	defer mi.Mutex.Unlock()
+
+	mi.Documents = documents
+	mi.Terms = terms
+}
+
+func addTerm(terms map[string]map[*Document]float64, term string, document *Document, weight float64) {
+	if terms[term] == nil {
+		terms[term] = make(map[*Document]float64)
+	}
+	terms[term][document] += weight
+}
+
+// Search scores documents by the sum of matched terms weights. Exact
+// matches count fully, prefix matches and matches with typos count less.
+func (mi *MemoryIndex) Search(query *Query) ([]Hit, error) {
+	mi.Mutex.RLock()
+	defer mi.Mutex.RUnlock()
+
+	scores := make(map[*Document]float64)
+	for _, queryTerm := range query.Terms {
+		maxDistance := textsearch.MaxDistance(queryTerm)
+		for term, documents := range mi.Terms {
+			var factor float64
+			switch {
+			case term == queryTerm:
+				factor = 1
+			case strings.HasPrefix(term, queryTerm):
+				factor = 0.75
+			case maxDistance > 0 && textsearch.Distance(term, queryTerm) <= maxDistance:
+				factor = 0.5
+			default:
+				continue
+			}
+
+			for document, weight := range documents {
+				if !mi.matches(document, query) {
+					continue
+				}
+				scores[document] += weight * factor
+			}
+		}
+	}
+
+	hits := make(map[string][]Hit)
+	for document, score := range scores {
+		hits[document.Type] = append(hits[document.Type], Hit{
+			Type:  document.Type,
+			ID:    document.ID,
+			Score: score,
+		})
+	}
+
+	var result []Hit
+	for _, typeHits := range hits {
+		sort.Slice(typeHits, func(i, j int) bool {
+			return typeHits[i].Score > typeHits[j].Score
+		})
+		if query.Limit > 0 && len(typeHits) > query.Limit {
+			typeHits = typeHits[:query.Limit]
+		}
+		result = append(result, typeHits...)
+	}
+
+	return result, nil
+}
+
+func (mi *MemoryIndex) matches(document *Document, query *Query) bool {
+	if !slices.Contains(query.Types, document.Type) {
+		return false
+	}
+	if query.CategoryId > 0 && document.CategoryId != query.CategoryId {
+		return false
+	}
+	if query.DateFrom != nil && document.CreatedAt.Before(*query.DateFrom) {
+		return false
+	}
+	if query.DateTo != nil && document.CreatedAt.After(*query.DateTo) {
+		return false
+	}
+
+	return true
+}
+
+// Suggest takes the first matches, the documents come from GetDocuments
+// with the most popular ones first
+func (mi *MemoryIndex) Suggest(prefix string, limit int) ([]Suggestion, error) {
+	mi.Mutex.RLock()
+	defer mi.Mutex.RUnlock()
+
+	prefix = textsearch.Normalize(prefix)
+
+	var suggestions []Suggestion
+	count := make(map[string]int)
+	for _, document := range mi.Documents {
+		if document.Type != TypeMovie && document.Type != TypeChannel {
+			continue
+		}
+		if count[document.Type] >= limit {
+			continue
+		}
+
+		title := textsearch.Normalize(document.Title)
+		if !strings.HasPrefix(title, prefix) &&
+			(document.Type != TypeMovie || !strings.Contains(title, " "+prefix)) {
+			continue
+		}
+
+		suggestions = append(suggestions, Suggestion{
+			Type:      document.Type,
+			ID:        document.ID,
+			Code:      document.Code,
+			ChannelId: document.ChannelId,
+			Title:     document.Title,
+		})
+		count[document.Type]++
+	}
+
+	return suggestions, nil
+}
+
+func (mi *MemoryIndex) GetChannelsMovies(channelIds []uint) (map[uint][]uint, error) {
+	mi.Mutex.RLock()
+	defer mi.Mutex.RUnlock()
+
+	channelsMovies := make(map[uint][]uint)
+	for _, document := range mi.Documents {
+		if document.Type == TypeMovie && slices.Contains(channelIds, document.ChannelId) {
+			channelsMovies[document.ChannelId] = append(channelsMovies[document.ChannelId], document.ID)
+		}
+	}
+
+	return channelsMovies, nil
+}
+
+func (mi *MemoryIndex) GetVocabulary() ([]string, error) {
+	mi.Mutex.RLock()
+	defer mi.Mutex.RUnlock()
+
+	var vocabulary []string
+	for _, document := range mi.Documents {
+		if document.Title != "" {
+			vocabulary = append(vocabulary, document.Title)
+		}
+	}
+
+	return vocabulary, nil
+}
@@ -0,0 +1,169 @@
+package search
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newMemoryIndex() *MemoryIndex {
+	index := NewMemoryIndex()
+	index.Replace([]Document{
+		{Type: TypeMovie, ID: 1, Title: "Dragon in the mountains", Body: "A long walk", CategoryId: 1, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Type: TypeMovie, ID: 2, Title: "Mountain walks", Body: "Dragons and cats", CategoryId: 2, CreatedAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{Type: TypeMovie, ID: 3, Title: "Кошки и собаки", Body: "Фильм про кошек", CategoryId: 1, CreatedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Type: TypeChannel, ID: 4, Title: "Dragonfly"},
+		{Type: TypeComment, ID: 5, Body: "Best dragon ever"},
+	})
+
+	return index
+}
+
+func hitIds(hits []Hit) []uint {
+	var ids []uint
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+
+	return ids
+}
+
+func TestMemorySearchRanking(t *testing.T) {
+	index := newMemoryIndex()
+
+	hits, err := index.Search(&Query{Terms: []string{"dragon"}, Types: []string{TypeMovie}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A title match outweighs a body match
+	if ids := hitIds(hits); !slices.Equal(ids, []uint{1, 2}) {
+		t.Fatalf("ids = %v, want [1 2]", ids)
+	}
+	if hits[0].Score != titleWeight || hits[1].Score != bodyWeight {
+		t.Errorf("scores = %v and %v", hits[0].Score, hits[1].Score)
+	}
+
+	// An exact match outweighs a prefix match, which outweighs a typo
+	hits, err = index.Search(&Query{Terms: []string{"dragon"}, Types: []string{TypeChannel, TypeComment}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores := make(map[uint]float64)
+	for _, hit := range hits {
+		scores[hit.ID] = hit.Score
+	}
+	if scores[5] != bodyWeight || scores[4] != titleWeight*0.75 {
+		t.Errorf("scores = %v", scores)
+	}
+
+	hits, err = index.Search(&Query{Terms: []string{"dragn"}, Types: []string{TypeMovie}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := hitIds(hits); !slices.Equal(ids, []uint{1, 2}) || hits[0].Score != titleWeight*0.5 {
+		t.Errorf("typo hits = %+v", hits)
+	}
+
+	// Short terms tolerate no typos
+	if hits, _ = index.Search(&Query{Terms: []string{"cst"}, Types: []string{TypeMovie}}); len(hits) != 0 {
+		t.Errorf("short typo hits = %+v", hits)
+	}
+}
+
+func TestMemorySearchRussian(t *testing.T) {
+	index := newMemoryIndex()
+
+	// Every form of the word has the same stem
+	for _, term := range []string{"кошк", "фильм"} {
+		hits, err := index.Search(&Query{Terms: []string{term}, Types: []string{TypeMovie}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := hitIds(hits); !slices.Equal(ids, []uint{3}) {
+			t.Errorf("%s: ids = %v, want [3]", term, ids)
+		}
+	}
+}
+
+func TestMemorySearchFilters(t *testing.T) {
+	index := newMemoryIndex()
+
+	dateFrom := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		name  string
+		query *Query
+		want  []uint
+	}{
+		{"category", &Query{Terms: []string{"dragon"}, Types: []string{TypeMovie}, CategoryId: 2}, []uint{2}},
+		{"date", &Query{Terms: []string{"dragon"}, Types: []string{TypeMovie}, DateFrom: &dateFrom}, []uint{2}},
+		{"limit", &Query{Terms: []string{"dragon"}, Types: []string{TypeMovie}, Limit: 1}, []uint{1}},
+		{"type", &Query{Terms: []string{"dragon"}, Types: []string{TypeComment}}, []uint{5}},
+	} {
+		hits, err := index.Search(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := hitIds(hits); !slices.Equal(ids, test.want) {
+			t.Errorf("%s: ids = %v, want %v", test.name, ids, test.want)
+		}
+	}
+}
+
+func TestMemorySuggest(t *testing.T) {
+	index := newMemoryIndex()
+
+	suggestions, err := index.Suggest("Drag", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Documents keep their order, comments are never suggested
+	var ids []uint
+	for _, suggestion := range suggestions {
+		ids = append(ids, suggestion.ID)
+	}
+	if !slices.Equal(ids, []uint{1, 4}) {
+		t.Errorf("ids = %v, want [1 4]", ids)
+	}
+
+	// Movies also match from the start of any word, never from the middle
+	if suggestions, _ = index.Suggest("alks", 10); len(suggestions) != 0 {
+		t.Errorf("mid-word suggestions = %+v", suggestions)
+	}
+	if suggestions, _ = index.Suggest("собаки", 10); len(suggestions) != 1 || suggestions[0].ID != 3 {
+		t.Errorf("second word suggestions = %+v", suggestions)
+	}
+
+	// The first matches are taken
+	if suggestions, _ = index.Suggest("mountain", 1); len(suggestions) != 1 || suggestions[0].ID != 1 {
+		t.Errorf("limited suggestions = %+v", suggestions)
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	uc := &UseCase{
+		SearchInteractor: newMemoryIndex(),
+		Mutex:            &sync.RWMutex{},
+	}
+
+	for _, test := range []struct {
+		words     []string
+		terms     []string
+		corrected string
+	}{
+		// Known words are left as they are
+		{[]string{"dragon", "кошки"}, []string{"dragon", "кошк"}, "dragon кошки"},
+		// A typo adds the closest vocabulary term
+		{[]string{"dragn"}, []string{"dragn", "dragon"}, "dragon"},
+		{[]string{"монтаин", "собакки"}, []string{"монтаин", "собакк", "собак"}, "монтаин собаки"},
+		// A prefix of a known term is not a typo
+		{[]string{"drag"}, []string{"drag"}, "drag"},
+		// Short words tolerate no typos
+		{[]string{"cst"}, []string{"cst"}, "cst"},
+	} {
+		terms, corrected := uc.Correct(test.words)
+		if !slices.Equal(terms, test.terms) || corrected != test.corrected {
+			t.Errorf("Correct(%q) = %q, %q, want %q, %q", test.words, terms, corrected, test.terms, test.corrected)
+		}
+	}
+}
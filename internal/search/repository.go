@@ -0,0 +1,259 @@
+package search
+
+import (
+	"gorm.io/gorm"
+	"nine-dubz/internal/comment"
+	"nine-dubz/internal/movie"
+	"nine-dubz/internal/user"
+	"slices"
+	"strings"
+)
+
+// Popular channels go first in suggestions
+const channelsBySubscribers = "(SELECT COUNT(*) FROM subscriptions WHERE subscriptions.channel_id = users.id AND subscriptions.deleted_at IS NULL) DESC"
+
+// Repository searches with MySQL FULLTEXT indexes in boolean mode,
+// every stemmed term is matched as a prefix.
+type Repository struct {
+	DB *gorm.DB
+}
+
+func (r *Repository) Search(query *Query) ([]Hit, error) {
+	var against []string
+	for _, term := range query.Terms {
+		against = append(against, term+"*")
+	}
+	if len(against) == 0 {
+		return nil, nil
+	}
+	match := strings.Join(against, " ")
+
+	var hits []Hit
+	if slices.Contains(query.Types, TypeMovie) {
+		var movieHits []Hit
+		result := r.filter(r.DB.Model(&movie.Movie{}), query, "movies").
+			Select("movies.id AS id, MATCH(movies.name, movies.description) AGAINST (? IN BOOLEAN MODE) AS score", match).
+			Where("MATCH(movies.name, movies.description) AGAINST (? IN BOOLEAN MODE)", match).
+			Where("movies.is_published = 1 AND movies.visibility = ?", movie.VisibilityPublic).
+			Order("score desc").
+			Limit(query.Limit).
+			Scan(&movieHits)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+
+		for _, hit := range movieHits {
+			hit.Type = TypeMovie
+			hits = append(hits, hit)
+		}
+	}
+
+	if slices.Contains(query.Types, TypeChannel) {
+		var channelHits []Hit
+		result := r.DB.Model(&user.User{}).
+			Select("users.id AS id, MATCH(users.name) AGAINST (? IN BOOLEAN MODE) AS score", match).
+			Where("MATCH(users.name) AGAINST (? IN BOOLEAN MODE)", match).
+			Where("users.active = 1").
+			Order("score desc").
+			Limit(query.Limit).
+			Scan(&channelHits)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+
+		for _, hit := range channelHits {
+			hit.Type = TypeChannel
+			hits = append(hits, hit)
+		}
+	}
+
+	if slices.Contains(query.Types, TypeComment) {
+		var commentHits []Hit
+		result := r.filter(r.DB.Model(&comment.Comment{}), query, "comments").
+			Select("comments.id AS id, MATCH(comments.text) AGAINST (? IN BOOLEAN MODE) AS score", match).
+			Joins("JOIN movies ON movies.id = comments.movie_id AND movies.deleted_at IS NULL").
+			Where("MATCH(comments.text) AGAINST (? IN BOOLEAN MODE)", match).
+			Where("movies.is_published = 1 AND movies.visibility = ?", movie.VisibilityPublic).
+			Order("score desc").
+			Limit(query.Limit).
+			Scan(&commentHits)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+
+		for _, hit := range commentHits {
+			hit.Type = TypeComment
+			hits = append(hits, hit)
+		}
+	}
+
+	return hits, nil
+}
+
+func (r *Repository) filter(db *gorm.DB, query *Query, table string) *gorm.DB {
+	if query.CategoryId > 0 {
+		db = db.Where("movies.category = ?", query.CategoryId)
+	}
+	if query.DateFrom != nil {
+		db = db.Where(table+".created_at >= ?", *query.DateFrom)
+	}
+	if query.DateTo != nil {
+		db = db.Where(table+".created_at <= ?", *query.DateTo)
+	}
+
+	return db
+}
+
+func (r *Repository) GetVocabulary() ([]string, error) {
+	var movieNames []string
+	result := r.DB.Model(&movie.Movie{}).
+		Where("is_published = 1 AND visibility = ?", movie.VisibilityPublic).
+		Pluck("name", &movieNames)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var userNames []string
+	result = r.DB.Model(&user.User{}).
+		Where("active = 1").
+		Pluck("name", &userNames)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return append(movieNames, userNames...), nil
+}
+
+// Suggest returns public movies and active channels with a name or any
+// word of it starting with the prefix, the most popular ones first.
+func (r *Repository) Suggest(prefix string, limit int) ([]Suggestion, error) {
+	prefix = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+
+	var movies []movie.Movie
+	result := r.DB.
+		Select("id", "code", "name", "user_id").
+		Where("name LIKE ? OR name LIKE ?", prefix+"%", "% "+prefix+"%").
+		Where("is_published = 1 AND visibility = ?", movie.VisibilityPublic).
+		Order("views_count DESC").
+		Limit(limit).
+		Find(&movies)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var users []user.User
+	result = r.DB.
+		Select("id", "name").
+		Where("name LIKE ?", prefix+"%").
+		Where("active = 1").
+		Order(channelsBySubscribers).
+		Limit(limit).
+		Find(&users)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var suggestions []Suggestion
+	for _, item := range movies {
+		suggestions = append(suggestions, Suggestion{
+			Type:      TypeMovie,
+			ID:        item.ID,
+			Code:      item.Code,
+			ChannelId: item.UserId,
+			Title:     item.Name,
+		})
+	}
+	for _, item := range users {
+		suggestions = append(suggestions, Suggestion{
+			Type:  TypeChannel,
+			ID:    item.ID,
+			Title: item.Name,
+		})
+	}
+
+	return suggestions, nil
+}
+
+func (r *Repository) GetChannelsMovies(channelIds []uint) (map[uint][]uint, error) {
+	var movies []movie.Movie
+	result := r.DB.
+		Select("id", "user_id").
+		Where("user_id IN ?", channelIds).
+		Where("is_published = 1 AND visibility = ?", movie.VisibilityPublic).
+		Find(&movies)
+
+	channelsMovies := make(map[uint][]uint)
+	for _, item := range movies {
+		channelsMovies[item.UserId] = append(channelsMovies[item.UserId], item.ID)
+	}
+
+	return channelsMovies, result.Error
+}
+
+func (r *Repository) GetDocuments() ([]Document, error) {
+	var documents []Document
+
+	var movies []movie.Movie
+	result := r.DB.
+		Select("id", "code", "name", "description", "category", "user_id", "created_at").
+		Where("is_published = 1 AND visibility = ?", movie.VisibilityPublic).
+		Order("views_count DESC").
+		Find(&movies)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	for _, item := range movies {
+		documents = append(documents, Document{
+			Type:       TypeMovie,
+			ID:         item.ID,
+			Code:       item.Code,
+			ChannelId:  item.UserId,
+			Title:      item.Name,
+			Body:       item.Description,
+			CategoryId: item.CategoryId,
+			CreatedAt:  item.CreatedAt,
+		})
+	}
+
+	var users []user.User
+	result = r.DB.
+		Select("id", "name", "created_at").
+		Where("active = 1").
+		Order(channelsBySubscribers).
+		Find(&users)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	for _, item := range users {
+		documents = append(documents, Document{
+			Type:      TypeChannel,
+			ID:        item.ID,
+			Title:     item.Name,
+			CreatedAt: item.CreatedAt,
+		})
+	}
+
+	var comments []comment.Comment
+	result = r.DB.
+		Preload("Movie", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "category")
+		}).
+		Select("comments.id", "comments.text", "comments.movie_id", "comments.created_at").
+		Joins("JOIN movies ON movies.id = comments.movie_id AND movies.deleted_at IS NULL").
+		Where("movies.is_published = 1 AND movies.visibility = ?", movie.VisibilityPublic).
+		Find(&comments)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	for _, item := range comments {
+		documents = append(documents, Document{
+			Type:       TypeComment,
+			ID:         item.ID,
+			Body:       item.Text,
+			CategoryId: item.Movie.CategoryId,
+			CreatedAt:  item.CreatedAt,
+		})
+	}
+
+	return documents, nil
+}
@@ -0,0 +1,16 @@
+package search
+
+import (
+	"github.com/go-chi/chi/v5"
+	"nine-dubz/internal/pagination"
+)
+
+func (h *Handler) Routes(r chi.Router) {
+	r.Route("/search", func(r chi.Router) {
+		r.
+			With(pagination.SetPaginationContextMiddleware).
+			Get("/", h.SearchHandler)
+
+		r.Get("/suggestions", h.SuggestHandler)
+	})
+}
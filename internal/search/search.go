@@ -0,0 +1,467 @@
+package search
+
+import (
+	"gorm.io/gorm"
+	"log"
+	"nine-dubz/internal/comment"
+	"nine-dubz/internal/movie"
+	"nine-dubz/internal/pagination"
+	"nine-dubz/internal/user"
+	"nine-dubz/internal/view"
+	"nine-dubz/pkg/textsearch"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxQueryLength = 200
+	maxQueryWords  = 10
+	maxTypeHits    = 100
+	vocabularyTTL  = 10 * time.Minute
+
+	maxSuggestionPrefixLength = 100
+	maxSuggestionCandidates   = 50
+	maxMovieSuggestions       = 7
+	maxChannelSuggestions     = 3
+	maxCachedSuggestions      = 1000
+	suggestionsTTL            = time.Minute
+)
+
+type cachedSuggestions struct {
+	Suggestions []*SuggestionResponse
+	ExpiresAt   time.Time
+}
+
+type UseCase struct {
+	SearchInteractor    Interactor
+	Repository          *Repository
+	MemoryIndex         *MemoryIndex
+	MovieUseCase        *movie.UseCase
+	UserUseCase         *user.UseCase
+	CommentUseCase      *comment.UseCase
+	ViewUseCase         *view.UseCase
+	Suggestions         map[string]cachedSuggestions
+	SuggestionsMutex    *sync.RWMutex
+	Vocabulary          map[string]string
+	VocabularyUpdatedAt time.Time
+	Mutex               *sync.RWMutex
+}
+
+// New uses MySQL FULLTEXT search by default, SEARCH_INDEX=memory
+// switches to the in-memory index which is rebuilt by RunReindex.
+func New(db *gorm.DB, movuc *movie.UseCase, uuc *user.UseCase, cuc *comment.UseCase, vuc *view.UseCase) *UseCase {
+	repository := &Repository{
+		DB: db,
+	}
+
+	uc := &UseCase{
+		SearchInteractor: repository,
+		Repository:       repository,
+		MovieUseCase:     movuc,
+		UserUseCase:      uuc,
+		CommentUseCase:   cuc,
+		ViewUseCase:      vuc,
+		Suggestions:      make(map[string]cachedSuggestions),
+		SuggestionsMutex: &sync.RWMutex{},
+		Mutex:            &sync.RWMutex{},
+	}
+
+	if indexType, _ := os.LookupEnv("SEARCH_INDEX"); indexType == "memory" {
+		uc.MemoryIndex = NewMemoryIndex()
+		uc.SearchInteractor = uc.MemoryIndex
+	}
+
+	return uc
+}
+
+func (uc *UseCase) RunReindex(interval time.Duration) {
+	if uc.MemoryIndex == nil {
+		return
+	}
+
+	if err := uc.Reindex(); err != nil {
+		log.Println(err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := uc.Reindex(); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (uc *UseCase) Reindex() error {
+	documents, err := uc.Repository.GetDocuments()
+	if err != nil {
+		return err
+	}
+
+	uc.MemoryIndex.Replace(documents)
+
+	return nil
+}
+
+func (uc *UseCase) Search(request *SearchRequest, pagination *pagination.Pagination) (*GetResponse, error) {
+	if pagination.Offset < 0 {
+		pagination.Offset = 0
+	}
+
+	if utf8.RuneCountInString(request.Query) > maxQueryLength {
+		return nil, ErrQueryTooLong
+	}
+	if request.DateFrom != nil && request.DateTo != nil && request.DateFrom.After(*request.DateTo) {
+		return nil, ErrInvalidDates
+	}
+
+	words := textsearch.Words(request.Query)
+	if len(words) == 0 {
+		return nil, ErrQueryEmpty
+	}
+	if len(words) > maxQueryWords {
+		words = words[:maxQueryWords]
+	}
+
+	types := []string{TypeMovie, TypeChannel}
+	if len(request.Types) > 0 {
+		types = nil
+		for _, searchType := range request.Types {
+			if !slices.Contains(Types, searchType) {
+				return nil, ErrInvalidType
+			}
+			if !slices.Contains(types, searchType) {
+				types = append(types, searchType)
+			}
+		}
+	}
+
+	// Channels have neither category nor upload date
+	if request.Category > 0 || request.DateFrom != nil || request.DateTo != nil {
+		types = slices.DeleteFunc(types, func(searchType string) bool {
+			return searchType == TypeChannel
+		})
+	}
+
+	terms, corrected := uc.Correct(words)
+
+	response := &GetResponse{
+		Query: request.Query,
+	}
+	if corrected != strings.Join(words, " ") {
+		response.Corrected = corrected
+	}
+
+	if len(types) == 0 {
+		pagination.SetOffsetCursors(0)
+		response.List = pagination.NewList(nil)
+		return response, nil
+	}
+
+	hits, err := uc.SearchInteractor.Search(&Query{
+		Terms:      terms,
+		Types:      types,
+		CategoryId: request.Category,
+		DateFrom:   request.DateFrom,
+		DateTo:     request.DateTo,
+		Limit:      maxTypeHits,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+
+	if pagination.WithTotal {
+		total := int64(len(hits))
+		pagination.Total = &total
+	}
+	pagination.SetOffsetCursors(len(hits))
+	if pagination.Offset >= len(hits) {
+		response.List = pagination.NewList(nil)
+		return response, nil
+	}
+	hits = hits[pagination.Offset:min(pagination.Offset+pagination.Limit, len(hits))]
+
+	results, err := uc.GetResults(hits)
+	if err != nil {
+		return nil, err
+	}
+	response.List = pagination.NewList(results)
+
+	return response, nil
+}
+
+// Correct appends the closest vocabulary word to every search term that
+// isn't found in the vocabulary and returns the corrected query.
+func (uc *UseCase) Correct(words []string) ([]string, string) {
+	vocabulary := uc.GetVocabulary()
+
+	var terms []string
+	var correctedWords []string
+	for _, word := range words {
+		term := textsearch.Stem(word)
+		terms = append(terms, term)
+
+		if _, ok := vocabulary[term]; ok {
+			correctedWords = append(correctedWords, word)
+			continue
+		}
+
+		maxDistance := textsearch.MaxDistance(term)
+		bestTerm := ""
+		bestDistance := maxDistance + 1
+		for vocabularyTerm := range vocabulary {
+			if strings.HasPrefix(vocabularyTerm, term) {
+				bestTerm = ""
+				break
+			}
+
+			distance := textsearch.Distance(vocabularyTerm, term)
+			if distance < bestDistance || (distance == bestDistance && vocabularyTerm < bestTerm) {
+				bestTerm = vocabularyTerm
+				bestDistance = distance
+			}
+		}
+
+		if bestTerm != "" && bestDistance <= maxDistance {
+			terms = append(terms, bestTerm)
+			correctedWords = append(correctedWords, vocabulary[bestTerm])
+		} else {
+			correctedWords = append(correctedWords, word)
+		}
+	}
+
+	return terms, strings.Join(correctedWords, " ")
+}
+
+// GetVocabulary returns stemmed words of movie and channel names mapped
+// to their original form. It is cached for vocabularyTTL.
+func (uc *UseCase) GetVocabulary() map[string]string {
+	uc.Mutex.RLock()
+	if uc.Vocabulary != nil && time.Since(uc.VocabularyUpdatedAt) < vocabularyTTL {
+		defer uc.Mutex.RUnlock()
+		return uc.Vocabulary
+	}
+	uc.Mutex.RUnlock()
+
+	uc.Mutex.Lock()
+	defer uc.Mutex.Unlock()
+
+	titles, err := uc.SearchInteractor.GetVocabulary()
+	if err != nil {
+		log.Println(err)
+		return uc.Vocabulary
+	}
+
+	vocabulary := make(map[string]string)
+	for _, title := range titles {
+		for _, word := range textsearch.Words(title) {
+			term := textsearch.Stem(word)
+			if _, ok := vocabulary[term]; !ok {
+				vocabulary[term] = word
+			}
+		}
+	}
+
+	uc.Vocabulary = vocabulary
+	uc.VocabularyUpdatedAt = time.Now()
+
+	return uc.Vocabulary
+}
+
+func (uc *UseCase) GetResults(hits []Hit) ([]*Result, error) {
+	var movieIds, channelIds, commentIds []uint
+	for _, hit := range hits {
+		switch hit.Type {
+		case TypeMovie:
+			movieIds = append(movieIds, hit.ID)
+		case TypeChannel:
+			channelIds = append(channelIds, hit.ID)
+		case TypeComment:
+			commentIds = append(commentIds, hit.ID)
+		}
+	}
+
+	comments := make(map[uint]comment.Comment)
+	if len(commentIds) > 0 {
+		commentsList, err := uc.CommentUseCase.GetMultipleByIds(commentIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range commentsList {
+			comments[item.ID] = item
+			if !slices.Contains(movieIds, item.MovieID) {
+				movieIds = append(movieIds, item.MovieID)
+			}
+		}
+	}
+
+	movies := make(map[uint]*movie.GetResponse)
+	if len(movieIds) > 0 {
+		moviesList, err := uc.MovieUseCase.GetMultiplePublicByIds(movieIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range moviesList {
+			movies[item.ID] = item
+		}
+	}
+
+	channels := make(map[uint]*user.GetPublicResponse)
+	if len(channelIds) > 0 {
+		channelsList, err := uc.UserUseCase.GetMultiple(map[string]interface{}{"id": channelIds, "active": true})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range channelsList {
+			channels[item.ID] = user.NewGetPublicResponse(&item)
+		}
+	}
+
+	results := []*Result{}
+	for _, hit := range hits {
+		result := &Result{
+			Type:  hit.Type,
+			Score: hit.Score,
+		}
+
+		switch hit.Type {
+		case TypeMovie:
+			if result.Movie = movies[hit.ID]; result.Movie == nil {
+				continue
+			}
+		case TypeChannel:
+			if result.Channel = channels[hit.ID]; result.Channel == nil {
+				continue
+			}
+		case TypeComment:
+			item, ok := comments[hit.ID]
+			if !ok || movies[item.MovieID] == nil {
+				continue
+			}
+			result.Comment = &CommentResult{
+				ID:        item.ID,
+				CreatedAt: item.CreatedAt,
+				Text:      item.Text,
+				Mentions:  item.Mentions,
+				User:      user.NewGetPublicResponse(&item.User),
+				Movie:     movies[item.MovieID],
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// Suggest returns movie titles and channel names starting with the prefix,
+// the most viewed first. Channels are ranked by the views of their movies.
+func (uc *UseCase) Suggest(prefix string) ([]*SuggestionResponse, error) {
+	prefix = strings.Join(strings.Fields(textsearch.Normalize(prefix)), " ")
+	if prefix == "" {
+		return nil, ErrQueryEmpty
+	}
+	if utf8.RuneCountInString(prefix) > maxSuggestionPrefixLength {
+		return nil, ErrQueryTooLong
+	}
+
+	uc.SuggestionsMutex.RLock()
+	cached, ok := uc.Suggestions[prefix]
+	uc.SuggestionsMutex.RUnlock()
+	if ok && time.Now().Before(cached.ExpiresAt) {
+		return cached.Suggestions, nil
+	}
+
+	suggestions, err := uc.SearchInteractor.Suggest(prefix, maxSuggestionCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	var movieIds, channelIds []uint
+	for _, suggestion := range suggestions {
+		switch suggestion.Type {
+		case TypeMovie:
+			movieIds = append(movieIds, suggestion.ID)
+		case TypeChannel:
+			channelIds = append(channelIds, suggestion.ID)
+		}
+	}
+
+	channelsMovies := make(map[uint][]uint)
+	if len(channelIds) > 0 {
+		channelsMovies, err = uc.SearchInteractor.GetChannelsMovies(channelIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, channelMovies := range channelsMovies {
+			movieIds = append(movieIds, channelMovies...)
+		}
+	}
+
+	viewsCounts := make(map[uint]int64)
+	if len(movieIds) > 0 {
+		viewsCounts, err = uc.ViewUseCase.GetMultipleCount(movieIds)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var movies, channels []*SuggestionResponse
+	for _, suggestion := range suggestions {
+		response := &SuggestionResponse{
+			Type:  suggestion.Type,
+			ID:    suggestion.ID,
+			Code:  suggestion.Code,
+			Title: suggestion.Title,
+		}
+
+		switch suggestion.Type {
+		case TypeMovie:
+			response.Views = viewsCounts[suggestion.ID]
+			movies = append(movies, response)
+		case TypeChannel:
+			for _, movieId := range channelsMovies[suggestion.ID] {
+				response.Views += viewsCounts[movieId]
+			}
+			channels = append(channels, response)
+		}
+	}
+
+	byViews := func(items []*SuggestionResponse) {
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Views > items[j].Views
+		})
+	}
+	byViews(movies)
+	byViews(channels)
+
+	result := append(
+		movies[:min(len(movies), maxMovieSuggestions)],
+		channels[:min(len(channels), maxChannelSuggestions)]...,
+	)
+	if result == nil {
+		result = []*SuggestionResponse{}
+	}
+
+	uc.SuggestionsMutex.Lock()
+	if len(uc.Suggestions) >= maxCachedSuggestions {
+		uc.Suggestions = make(map[string]cachedSuggestions)
+	}
+	uc.Suggestions[prefix] = cachedSuggestions{
+		Suggestions: result,
+		ExpiresAt:   time.Now().Add(suggestionsTTL),
+	}
+	uc.SuggestionsMutex.Unlock()
+
+	return result, nil
+}
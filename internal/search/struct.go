@@ -0,0 +1,102 @@
+package search
+
+import (
+	"errors"
+	"nine-dubz/internal/comment"
+	"nine-dubz/internal/movie"
+	"nine-dubz/internal/response"
+	"nine-dubz/internal/user"
+	"time"
+)
+
+const (
+	TypeMovie   = "movie"
+	TypeChannel = "channel"
+	TypeComment = "comment"
+)
+
+var Types = []string{TypeMovie, TypeChannel, TypeComment}
+
+var (
+	ErrQueryEmpty   = errors.New("search query is empty")
+	ErrQueryTooLong = errors.New("search query too long")
+	ErrInvalidType  = errors.New("invalid search type")
+	ErrInvalidDates = errors.New("date from is after date to")
+)
+
+// Document is a searchable entity as it is stored in the memory index.
+type Document struct {
+	Type       string
+	ID         uint
+	Code       string
+	ChannelId  uint
+	Title      string
+	Body       string
+	CategoryId uint
+	CreatedAt  time.Time
+}
+
+// Query is a tokenized search request passed to the index.
+type Query struct {
+	Terms      []string
+	Types      []string
+	CategoryId uint
+	DateFrom   *time.Time
+	DateTo     *time.Time
+	Limit      int
+}
+
+type Hit struct {
+	Type  string
+	ID    uint
+	Score float64
+}
+
+type Suggestion struct {
+	Type      string
+	ID        uint
+	Code      string
+	ChannelId uint
+	Title     string
+}
+
+type SuggestionResponse struct {
+	Type  string `json:"type"`
+	ID    uint   `json:"id"`
+	Code  string `json:"code,omitempty"`
+	Title string `json:"title"`
+	Views int64  `json:"views"`
+}
+
+type SearchRequest struct {
+	Query    string     `json:"query"`
+	Types    []string   `json:"types"`
+	Category uint       `json:"category"`
+	DateFrom *time.Time `json:"dateFrom"`
+	DateTo   *time.Time `json:"dateTo"`
+}
+
+type CommentResult struct {
+	ID        uint                    `json:"id"`
+	CreatedAt time.Time               `json:"createdAt"`
+	Text      string                  `json:"text"`
+	Mentions  []comment.Mention       `json:"mentions,omitempty"`
+	User      *user.GetPublicResponse `json:"user"`
+	Movie     *movie.GetResponse      `json:"movie"`
+}
+
+type Result struct {
+	Type    string                  `json:"type"`
+	Score   float64                 `json:"score"`
+	Movie   *movie.GetResponse      `json:"movie,omitempty"`
+	Channel *user.GetPublicResponse `json:"channel,omitempty"`
+	Comment *CommentResult          `json:"comment,omitempty"`
+}
+
+// GetResponse is the list envelope of the results with the query as it was
+// searched.
+type GetResponse struct {
+	*response.List
+	Query     string `json:"query"`
+	Corrected string `json:"corrected,omitempty"`
+}
@@ -62,14 +62,16 @@ func (h *Handler) GetMultipleHandler(w http.ResponseWriter, r *http.Request) {
 
 	subscriptions, err := h.SubscriptionUseCase.GetMultiple(userId, pagination)
 	if err != nil {
-		response.RenderError(w, r, http.StatusInternalServerError, err.Error())
+		switch status := pagination.ErrorStatus(err); status {
+		case http.StatusBadRequest:
+			response.RenderError(w, r, status, err.Error())
+		case http.StatusNotFound:
+			response.RenderError(w, r, status, "SUBSCRIPTION_NO_SUBSCRIPTIONS")
+		default:
+			response.RenderError(w, r, status, "INTERNAL_ERROR")
+		}
 		return
 	}
 
-	if len(subscriptions) > 0 {
-		render.JSON(w, r, subscriptions)
-	} else {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, make([]struct{}, 0))
-	}
+	render.JSON(w, r, pagination.NewList(subscriptions))
 }
@@ -6,5 +6,7 @@ type Interactor interface {
 	Create(sub *Subscription) error
 	Delete(userId, channelId uint) (int64, error)
 	Get(userId, channelId uint) (*Subscription, error)
-	GetWhereMultiple(where interface{}, pagination *pagination.Pagination) ([]Subscription, error)
+	GetWhereMultiple(where interface{}, pagination *pagination.Pagination, order string) ([]Subscription, error)
+	GetSubscribers(channelId uint) ([]Subscription, error)
+	Count(where interface{}) (int64, error)
 }
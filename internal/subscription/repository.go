@@ -26,15 +26,35 @@ func (r *Repository) Get(userId, channelId uint) (*Subscription, error) {
 	return &subscription, result.Error
 }
 
-func (r *Repository) GetWhereMultiple(where interface{}, pagination *pagination.Pagination) ([]Subscription, error) {
+func (r *Repository) GetWhereMultiple(where interface{}, pagination *pagination.Pagination, order string) ([]Subscription, error) {
 	var subscriptions []Subscription
 	result := r.DB.
 		Preload("Channel").
 		Preload("Channel.Picture").
 		Where(where).
-		Limit(pagination.Limit).
-		Offset(pagination.Offset).
+		Scopes(pagination.Scope).
+		Order(order).
 		Find(&subscriptions)
 
 	return subscriptions, result.Error
 }
+
+func (r *Repository) GetSubscribers(channelId uint) ([]Subscription, error) {
+	var subscriptions []Subscription
+	result := r.DB.
+		Preload("User").
+		Where("channel_id = ?", channelId).
+		Find(&subscriptions)
+
+	return subscriptions, result.Error
+}
+
+func (r *Repository) Count(where interface{}) (int64, error) {
+	var count int64
+	result := r.DB.
+		Model(&Subscription{}).
+		Where(where).
+		Count(&count)
+
+	return count, result.Error
+}
@@ -3,19 +3,26 @@ package subscription
 import (
 	"errors"
 	"gorm.io/gorm"
+	"log"
+	"nine-dubz/internal/notification"
 	"nine-dubz/internal/pagination"
 	"nine-dubz/internal/user"
 )
 
+// Latest subscriptions go first
+var sortKey = pagination.Key{SortBy: "created_at", Column: "subscriptions.created_at", IdColumn: "subscriptions.id", Desc: true}
+
 type UseCase struct {
 	SubscriptionInteractor Interactor
+	NotificationUseCase    *notification.UseCase
 }
 
-func New(db *gorm.DB) *UseCase {
+func New(db *gorm.DB, nuc *notification.UseCase) *UseCase {
 	return &UseCase{
 		SubscriptionInteractor: &Repository{
 			DB: db,
 		},
+		NotificationUseCase: nuc,
 	}
 }
 
@@ -33,6 +40,14 @@ func (uc *UseCase) Subscribe(userId, channelId uint) error {
 		return errors.New("SUBSCRIPTION_FAILED_TO_SUBSCRIBE")
 	}
 
+	err = uc.NotificationUseCase.Notify(
+		notification.Notification{Type: notification.TypeSubscriber, ActorId: &userId},
+		[]uint{channelId},
+	)
+	if err != nil {
+		log.Println("subscription: notify:", err)
+	}
+
 	return nil
 }
 
@@ -59,6 +74,7 @@ func (uc *UseCase) GetAll(userId uint) ([]Subscription, error) {
 			Limit:  -1,
 			Offset: -1,
 		},
+		"",
 	)
 	if err != nil {
 		return nil, errors.New("SUBSCRIPTION_NO_SUBSCRIPTIONS")
@@ -67,17 +83,39 @@ func (uc *UseCase) GetAll(userId uint) ([]Subscription, error) {
 	return subscriptions, nil
 }
 
-func (uc *UseCase) GetMultiple(userId uint, pagination *pagination.Pagination) ([]*user.GetPublicResponse, error) {
-	if pagination.Limit > 20 || pagination.Limit == -1 {
-		pagination.Limit = 20
+func (uc *UseCase) GetSubscribers(channelId uint) ([]user.User, error) {
+	subscriptions, err := uc.SubscriptionInteractor.GetSubscribers(channelId)
+	if err != nil {
+		return nil, err
 	}
 
+	var users []user.User
+	for _, subscription := range subscriptions {
+		users = append(users, subscription.User)
+	}
+
+	return users, nil
+}
+
+func (uc *UseCase) GetMultiple(userId uint, pagination *pagination.Pagination) ([]*user.GetPublicResponse, error) {
 	subscriptions, err := uc.SubscriptionInteractor.GetWhereMultiple(
 		map[string]interface{}{"user_id": userId},
 		pagination,
+		pagination.OrderBy(sortKey),
 	)
 	if err != nil {
-		return nil, errors.New("SUBSCRIPTION_NO_SUBSCRIPTIONS")
+		return nil, err
+	}
+	subscriptions = subscriptions[:pagination.Trim(subscriptions, func(i int) (interface{}, uint) {
+		return subscriptions[i].CreatedAt, subscriptions[i].ID
+	})]
+
+	if pagination.WithTotal {
+		total, err := uc.SubscriptionInteractor.Count(map[string]interface{}{"user_id": userId})
+		if err != nil {
+			return nil, err
+		}
+		pagination.Total = &total
 	}
 
 	var users []user.User
@@ -0,0 +1,6 @@
+package tag
+
+type Interactor interface {
+	FirstOrCreate(tag *Tag) error
+	GetByName(name string) (*Tag, error)
+}
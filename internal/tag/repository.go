@@ -0,0 +1,18 @@
+package tag
+
+import "gorm.io/gorm"
+
+type Repository struct {
+	DB *gorm.DB
+}
+
+func (r *Repository) FirstOrCreate(tag *Tag) error {
+	return r.DB.Where(Tag{Name: tag.Name}).FirstOrCreate(tag).Error
+}
+
+func (r *Repository) GetByName(name string) (*Tag, error) {
+	tag := &Tag{}
+	result := r.DB.Where("name = ?", name).First(tag)
+
+	return tag, result.Error
+}
@@ -0,0 +1,15 @@
+package tag
+
+import "time"
+
+// Tag name is always stored normalized, see Normalize.
+type Tag struct {
+	ID        uint      `json:"-" gorm:"primarykey"`
+	CreatedAt time.Time `json:"-"`
+	Name      string    `json:"name" gorm:"size:50;not null;unique"`
+}
+
+type GetResponse struct {
+	Name        string `json:"name"`
+	MoviesCount int64  `json:"moviesCount"`
+}
@@ -0,0 +1,124 @@
+package tag
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"nine-dubz/pkg/textsearch"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const MaxTags = 15
+
+// Latin and Cyrillic letters that look the same, tags typed with a mixed
+// keyboard layout are folded into the script used by most of the letters.
+var latinToCyrillic = map[rune]rune{
+	'a': 'а', 'e': 'е', 'o': 'о', 'p': 'р', 'c': 'с', 'x': 'х', 'y': 'у', 'k': 'к',
+}
+
+var cyrillicToLatin = map[rune]rune{
+	'а': 'a', 'е': 'e', 'о': 'o', 'р': 'p', 'с': 'c', 'х': 'x', 'у': 'y', 'к': 'k',
+}
+
+type UseCase struct {
+	TagInteractor Interactor
+}
+
+func New(db *gorm.DB) *UseCase {
+	return &UseCase{
+		TagInteractor: &Repository{
+			DB: db,
+		},
+	}
+}
+
+// GetOrCreateMultiple normalizes tag names and returns tags for them,
+// creating missing ones. Duplicates after normalization are dropped.
+func (uc *UseCase) GetOrCreateMultiple(names []string) ([]Tag, error) {
+	normalizedNames, err := NormalizeMultiple(names)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []Tag{}
+	for _, name := range normalizedNames {
+		tag := &Tag{Name: name}
+		if err = uc.TagInteractor.FirstOrCreate(tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, *tag)
+	}
+
+	return tags, nil
+}
+
+func (uc *UseCase) GetByName(name string) (*Tag, error) {
+	name, err := Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.TagInteractor.GetByName(name)
+}
+
+func NormalizeMultiple(names []string) ([]string, error) {
+	var normalizedNames []string
+	for _, name := range names {
+		normalizedName, err := Normalize(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(normalizedNames, normalizedName) {
+			normalizedNames = append(normalizedNames, normalizedName)
+		}
+	}
+
+	if len(normalizedNames) > MaxTags {
+		return nil, errors.New("too many tags")
+	}
+
+	return normalizedNames, nil
+}
+
+// Normalize lowercases the tag, strips a leading "#", joins words with
+// a single space and folds look-alike Latin and Cyrillic letters.
+func Normalize(name string) (string, error) {
+	name = strings.TrimLeft(strings.TrimSpace(name), "#")
+	name = strings.Join(strings.FieldsFunc(textsearch.Normalize(name), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '_'
+	}), " ")
+
+	var latinCount, cyrillicCount int
+	for _, r := range name {
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latinCount++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillicCount++
+		case unicode.IsDigit(r) || r == ' ' || r == '-':
+		default:
+			return "", errors.New("tag can contain only letters, digits, spaces and hyphens")
+		}
+	}
+
+	replacements := latinToCyrillic
+	if latinCount > cyrillicCount {
+		replacements = cyrillicToLatin
+	}
+	name = strings.Map(func(r rune) rune {
+		if replacement, ok := replacements[r]; ok {
+			return replacement
+		}
+		return r
+	}, name)
+
+	length := utf8.RuneCountInString(name)
+	if length < 2 || length > 30 {
+		return "", errors.New("tag length must be from 2 to 30 characters")
+	}
+
+	return name, nil
+}
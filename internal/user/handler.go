@@ -95,7 +95,11 @@ func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		response.RenderError(w, r, http.StatusBadRequest, err.Error())
 		return
 	} else if userId > 0 {
-		h.SendRegistrationEmail(r, registrationPayload)
+		// The user already exists, failing here would leave the account
+		// inactive for good since registering again is refused
+		if err = h.SendRegistrationEmail(r, registrationPayload); err != nil {
+			log.Println("user: registration email:", err)
+		}
 
 		response.RenderSuccess(w, r, http.StatusOK, "")
 		return
@@ -104,15 +108,12 @@ func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	response.RenderError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR")
 }
 
-func (h *Handler) SendRegistrationEmail(r *http.Request, user *User) {
-	languageCode := language.GetLanguageCode(r)
-
-	subject, _ := language.GetMessage("EMAIL_REGISTRATION_CONFIRMATION", languageCode)
+// SendRegistrationEmail queues the confirmation email, it's sent by the mail
+// outbox
+func (h *Handler) SendRegistrationEmail(r *http.Request, user *User) error {
 	link := fmt.Sprintf("%s/api/authorize/inner/confirm/?email=%s&hash=%s", h.SiteUrl, user.Email, user.Hash)
-	contentValues := map[string]string{"userName": user.Name, "link": link}
-	content, _ := language.GetFormattedMessage("EMAIL_REGISTRATION_CONFIRMATION_CONTENT", contentValues, languageCode)
 
-	h.UserUseCase.SendRegistrationEmail(user.Email, subject, content)
+	return h.UserUseCase.SendRegistrationEmail(user, link, language.GetLanguageCode(r))
 }
 
 func (h *Handler) CheckUserWithNameExistsHandler(w http.ResponseWriter, r *http.Request) {
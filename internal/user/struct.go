@@ -10,7 +10,7 @@ type User struct {
 	gorm.Model
 	Active    bool `gorm:"default:false"`
 	ID        uint
-	Name      string      `json:"name" gorm:"unique;not null"`
+	Name      string      `json:"name" gorm:"unique;not null;index:idx_users_search,class:FULLTEXT"`
 	Email     string      `json:"email" gorm:"unique;not null"`
 	Password  string      `json:"password"`
 	Hash      string      `json:"-"`
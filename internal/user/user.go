@@ -88,8 +88,16 @@ func (uc *UseCase) CheckUserWithNameExists(userName string) bool {
 	return isUserExists
 }
 
-func (uc *UseCase) SendRegistrationEmail(to, subject, content string) error {
-	return uc.MailUseCase.SendMail(to, subject, content)
+func (uc *UseCase) SendRegistrationEmail(user *User, link, languageCode string) error {
+	message, err := uc.MailUseCase.Render("registration", languageCode, map[string]string{
+		"UserName": user.Name,
+		"Link":     link,
+	})
+	if err != nil {
+		return err
+	}
+
+	return uc.MailUseCase.Queue(user.Email, message)
 }
 
 func (uc *UseCase) ConfirmRegistration(email, hash string) (uint, bool) {
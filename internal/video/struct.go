@@ -68,7 +68,15 @@ var QualityTypeResize = QualityType{"Resize"}
 var QualityTypeConvert = QualityType{"Convert"}
 var QualityTypeSkip = QualityType{"Skip"}
 
-func (q *Quality) Process(ctx context.Context, pathFrom, pathTo string) error {
+// Process makes the quality from the source. Loudness is measured once per
+// source by the caller, without it the audio isn't normalised.
+func (q *Quality) Process(ctx context.Context, pathFrom, pathTo string, loudnessTarget ffmpegthumbs.LoudnessTarget, loudness *ffmpegthumbs.Loudness) error {
+	if q.Type == QualityTypeSkip {
+		return nil
+	}
+
+	audioFilter := ffmpegthumbs.GetLoudnormFilter(loudnessTarget, loudness)
+
 	switch q.Type {
 	case QualityTypeResize:
 		audioBitrate := q.Settings.AudioBitrate
@@ -84,6 +92,7 @@ func (q *Quality) Process(ctx context.Context, pathFrom, pathTo string) error {
 			q.Settings.Speed,
 			q.Settings.VideoBitrate,
 			audioBitrate,
+			audioFilter,
 			pathFrom,
 			pathTo,
 			q.Code,
@@ -100,6 +109,7 @@ func (q *Quality) Process(ctx context.Context, pathFrom, pathTo string) error {
 			q.Settings.CRF,
 			q.Settings.Speed,
 			origVideoBitrate,
+			audioFilter,
 			pathTo,
 			q.Code,
 		)
@@ -127,6 +137,16 @@ func GetQuality(id uint) *Quality {
 	return nil
 }
 
+func GetQualityByCode(code string) *Quality {
+	for _, quality := range SupportedQualities {
+		if quality.Code == code {
+			return &quality
+		}
+	}
+
+	return nil
+}
+
 func (q *Quality) Scan(value interface{}) error {
 	qualityId, ok := value.(int64)
 	if !ok {
@@ -2,8 +2,11 @@ package video
 
 import (
 	"context"
+	"log"
 	"nine-dubz/internal/file"
 	"nine-dubz/pkg/ffmpegthumbs"
+	"os"
+	"strconv"
 
 	"gorm.io/gorm"
 )
@@ -11,6 +14,7 @@ import (
 type UseCase struct {
 	VideoInteractor Interactor
 	FileUseCase     *file.UseCase
+	LoudnessTarget  ffmpegthumbs.LoudnessTarget
 }
 
 func New(db *gorm.DB, fuc *file.UseCase) *UseCase {
@@ -19,7 +23,27 @@ func New(db *gorm.DB, fuc *file.UseCase) *UseCase {
 			DB: db,
 		},
 		FileUseCase: fuc,
+		LoudnessTarget: ffmpegthumbs.LoudnessTarget{
+			Integrated: lookupFloatEnv("LOUDNESS_TARGET_I", -16),
+			TruePeak:   lookupFloatEnv("LOUDNESS_TARGET_TP", -1.5),
+			Range:      lookupFloatEnv("LOUDNESS_TARGET_LRA", 11),
+		},
+	}
+}
+
+func lookupFloatEnv(key string, defaultValue float64) float64 {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+
+	parsedValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("video: invalid %s environment variable, using %.1f", key, defaultValue)
+		return defaultValue
 	}
+
+	return parsedValue
 }
 
 func (uc *UseCase) Save(ctx context.Context, filePath, name, path string, qualityId uint) (*Video, error) {
@@ -44,6 +68,14 @@ func (uc *UseCase) Save(ctx context.Context, filePath, name, path string, qualit
 	return video, nil
 }
 
+func (uc *UseCase) MeasureLoudness(ctx context.Context, filePath string) (*ffmpegthumbs.Loudness, error) {
+	return ffmpegthumbs.MeasureLoudness(ctx, filePath, uc.LoudnessTarget)
+}
+
+func (uc *UseCase) Process(ctx context.Context, quality Quality, pathFrom, pathTo string, loudness *ffmpegthumbs.Loudness) error {
+	return quality.Process(ctx, pathFrom, pathTo, uc.LoudnessTarget, loudness)
+}
+
 func (uc *UseCase) Delete(video *Video) error {
 	err := uc.VideoInteractor.Delete(video.ID)
 	if err != nil {
@@ -6,5 +6,10 @@ type Interactor interface {
 	Create(view *View) error
 	GetLast(movieId uint, userId *uint, ip string, time time.Time) (View, error)
 	GetCount(movieId uint) (int64, error)
+	GetCountByIp(ip string, since time.Time) (int64, error)
 	GetCountMultiple(movieIds []uint) (map[uint]int64, error)
+	GetPending(movieId uint, clientKey string) (Pending, error)
+	SavePending(pending *Pending) error
+	DeletePending(pending *Pending) (int64, error)
+	DeletePendingBefore(time time.Time) error
 }
@@ -2,6 +2,7 @@ package view
 
 import (
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"time"
 )
 
@@ -9,8 +10,20 @@ type Repository struct {
 	DB *gorm.DB
 }
 
+// Create stores the view and bumps the denormalized counter on the movie
+// in the same transaction, so list sorting by views stays in sync.
 func (r *Repository) Create(view *View) error {
-	return r.DB.Create(&view).Error
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&view).Error; err != nil {
+			return err
+		}
+
+		return tx.
+			Table("movies").
+			Where("id = ?", view.MovieID).
+			UpdateColumn("views_count", gorm.Expr("views_count + ?", 1)).
+			Error
+	})
 }
 
 func (r *Repository) GetLast(movieId uint, userId *uint, ip string, time time.Time) (View, error) {
@@ -30,6 +43,13 @@ func (r *Repository) GetCount(movieId uint) (int64, error) {
 	return count, result.Error
 }
 
+func (r *Repository) GetCountByIp(ip string, since time.Time) (int64, error) {
+	var count int64
+	result := r.DB.Model(&View{}).Where("ip = ? AND created_at > ?", ip, since).Count(&count)
+
+	return count, result.Error
+}
+
 func (r *Repository) GetCountMultiple(movieIds []uint) (map[uint]int64, error) {
 	var views []View
 	result := r.DB.Select("movie_id").Where("movie_id IN ?", movieIds).Find(&views)
@@ -45,3 +65,36 @@ func (r *Repository) GetCountMultiple(movieIds []uint) (map[uint]int64, error) {
 
 	return counts, result.Error
 }
+
+func (r *Repository) GetPending(movieId uint, clientKey string) (Pending, error) {
+	pending := Pending{}
+	result := r.DB.Where("movie_id = ? AND client_key = ?", movieId, clientKey).First(&pending)
+
+	return pending, result.Error
+}
+
+// SavePending starts the clock again when the client had an expired
+// pending view of the movie
+func (r *Repository) SavePending(pending *Pending) error {
+	return r.DB.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "movie_id"}, {Name: "client_key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"source", "started_at"}),
+		}).
+		Create(pending).
+		Error
+}
+
+// DeletePending deletes the pending view only if it wasn't restarted, the
+// instance which deletes it is the one to add the view
+func (r *Repository) DeletePending(pending *Pending) (int64, error) {
+	result := r.DB.
+		Where("movie_id = ? AND client_key = ? AND started_at = ?", pending.MovieID, pending.ClientKey, pending.StartedAt).
+		Delete(&Pending{})
+
+	return result.RowsAffected, result.Error
+}
+
+func (r *Repository) DeletePendingBefore(time time.Time) error {
+	return r.DB.Where("started_at < ?", time).Delete(&Pending{}).Error
+}
@@ -2,13 +2,87 @@ package view
 
 import (
 	"gorm.io/gorm"
+	"net"
+	"net/url"
 	"nine-dubz/internal/user"
+	"slices"
+	"time"
 )
 
+// Sources tell where viewers came to a movie from. Pages of the site pass
+// one of them, other visits are external or direct.
+const (
+	SourceHome          = "home"
+	SourceSearch        = "search"
+	SourceChannel       = "channel"
+	SourcePlaylist      = "playlist"
+	SourceFeed          = "feed"
+	SourceSubscriptions = "subscriptions"
+	SourceLibrary       = "library"
+	SourceRelated       = "related"
+	SourceExternal      = "external"
+	SourceDirect        = "direct"
+)
+
+var Sources = []string{
+	SourceHome,
+	SourceSearch,
+	SourceChannel,
+	SourcePlaylist,
+	SourceFeed,
+	SourceSubscriptions,
+	SourceLibrary,
+	SourceRelated,
+	SourceExternal,
+	SourceDirect,
+}
+
 type View struct {
 	gorm.Model
 	MovieID uint
 	UserID  *uint
 	User    user.User
-	IP      string
+	IP      string `gorm:"size:45;index"`
+	Source  string `gorm:"size:20;not null;default:'direct'"`
+}
+
+// Client is where a movie is opened or watched from
+type Client struct {
+	IP        net.IP
+	UserAgent string
+	Source    string
+}
+
+// NewSource takes the source the page passed, a referer from another site
+// makes the view external.
+func NewSource(source, referer, siteUrl string) string {
+	if slices.Contains(Sources, source) {
+		return source
+	}
+
+	refererUrl, err := url.Parse(referer)
+	if err != nil || refererUrl.Host == "" {
+		return SourceDirect
+	}
+
+	site, err := url.Parse(siteUrl)
+	if err == nil && site.Host == refererUrl.Host {
+		return SourceDirect
+	}
+
+	return SourceExternal
+}
+
+// Pending is a movie opened by a viewer who hasn't watched enough of it
+// yet. It's kept in the database, so the view counts whichever instance
+// gets the heartbeat and after a restart.
+type Pending struct {
+	MovieID   uint      `gorm:"primarykey;autoIncrement:false"`
+	ClientKey string    `gorm:"primarykey;size:45"`
+	Source    string    `gorm:"size:20"`
+	StartedAt time.Time `gorm:"not null;index"`
+}
+
+func (Pending) TableName() string {
+	return "view_pendings"
 }
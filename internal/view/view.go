@@ -2,11 +2,28 @@ package view
 
 import (
 	"errors"
+	"fmt"
 	"gorm.io/gorm"
-	"net"
+	"log"
+	"regexp"
 	"time"
 )
 
+const (
+	// A view counts after this much of the movie is watched, short movies
+	// need half of their duration
+	viewWatchTime = 30 * time.Second
+	// The same user or IP adds one view of a movie in this window
+	viewWindow = 24 * time.Hour
+	// No IP adds more views than this an hour
+	maxViewsPerIp = 60
+	// Movies opened and not watched long enough are forgotten after this
+	pendingTTL = 6 * time.Hour
+)
+
+// Crawlers, link previews and HTTP libraries don't watch movies
+var botRegexp = regexp.MustCompile(`(?i)bot|crawl|spider|slurp|archiver|facebookexternalhit|embedly|preview|headless|lighthouse|curl|wget|python|go-http-client|java/|okhttp|axios|node-fetch|scrapy`)
+
 type UseCase struct {
 	ViewInteractor Interactor
 }
@@ -19,24 +36,115 @@ func New(db *gorm.DB) *UseCase {
 	}
 }
 
-func (uc *UseCase) Add(movieId uint, userId *uint, ip net.IP) (*View, error) {
-	if userId == nil && ip == nil {
-		return nil, errors.New("view: user ip and ip is nil")
+func IsBot(userAgent string) bool {
+	return userAgent == "" || botRegexp.MatchString(userAgent)
+}
+
+// Start remembers when the movie was opened. The view itself is added by
+// Add once the player reports enough watch time, reopening the movie
+// doesn't restart the clock.
+func (uc *UseCase) Start(movieId uint, userId *uint, client Client) {
+	if IsBot(client.UserAgent) || (userId == nil && client.IP == nil) {
+		return
 	}
 
-	lastViewMinTime := time.Now().Add(-24 * time.Hour)
-	_, err := uc.ViewInteractor.GetLast(movieId, userId, ip.String(), lastViewMinTime)
+	clientKey := ClientKey(userId, client)
+	pending, err := uc.ViewInteractor.GetPending(movieId, clientKey)
+	if err == nil && time.Since(pending.StartedAt) < pendingTTL {
+		return
+	}
+
+	err = uc.ViewInteractor.SavePending(&Pending{
+		MovieID:   movieId,
+		ClientKey: clientKey,
+		Source:    client.Source,
+		StartedAt: time.Now(),
+	})
+	if err != nil {
+		log.Println("view: start:", err)
+	}
+}
+
+func (uc *UseCase) RunPendingCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := uc.ViewInteractor.DeletePendingBefore(time.Now().Add(-pendingTTL)); err != nil {
+			log.Println("view: pending cleanup:", err)
+		}
+	}
+}
+
+// Add counts a view of the movie. watched is the watch time in seconds the
+// player reports, at least as much time must have passed since the movie
+// was opened. A user or IP adds one view of a movie a day and an IP adds a
+// limited number of views an hour.
+func (uc *UseCase) Add(movieId uint, userId *uint, client Client, watched, duration int) (*View, error) {
+	if userId == nil && client.IP == nil {
+		return nil, errors.New("view: user id and ip is nil")
+	}
+
+	if IsBot(client.UserAgent) {
+		return nil, errors.New("view: bot user agent")
+	}
+
+	threshold := viewWatchTime
+	if duration > 0 {
+		threshold = min(threshold, time.Duration(duration)*time.Second/2)
+	}
+	if time.Duration(watched)*time.Second < threshold {
+		return nil, errors.New("view: not watched long enough")
+	}
+
+	pending, err := uc.ViewInteractor.GetPending(movieId, ClientKey(userId, client))
+	if err != nil || time.Since(pending.StartedAt) < threshold {
+		return nil, errors.New("view: not watched long enough")
+	}
+	// Heartbeats can come to several instances at once, only one of them
+	// takes the pending view
+	deleted, err := uc.ViewInteractor.DeletePending(&pending)
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, errors.New("view: view already added")
+	}
+
+	ip := client.IP.String()
+	_, err = uc.ViewInteractor.GetLast(movieId, userId, ip, time.Now().Add(-viewWindow))
 	if err == nil {
 		return nil, errors.New("view: too early to add a view")
 	}
 
+	count, err := uc.ViewInteractor.GetCountByIp(ip, time.Now().Add(-time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxViewsPerIp {
+		return nil, errors.New("view: too many views from the ip")
+	}
+
 	view := &View{MovieID: movieId}
 	view.UserID = userId
-	view.IP = ip.String()
+	view.IP = ip
+	view.Source = pending.Source
+	if view.Source == "" {
+		view.Source = SourceDirect
+	}
 
 	return view, uc.ViewInteractor.Create(view)
 }
 
+// ClientKey tells viewers apart: users by ID, guests by IP
+func ClientKey(userId *uint, client Client) string {
+	if userId != nil {
+		return fmt.Sprintf("u%d", *userId)
+	}
+
+	return client.IP.String()
+}
+
 func (uc *UseCase) GetCount(movieId uint) (int64, error) {
 	return uc.ViewInteractor.GetCount(movieId)
 }
@@ -0,0 +1,208 @@
+package view
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryViews keeps views and pending views the way Repository does
+type memoryViews struct {
+	views   []View
+	pending map[string]Pending
+}
+
+func newMemoryViews() *memoryViews {
+	return &memoryViews{pending: make(map[string]Pending)}
+}
+
+func (m *memoryViews) Create(view *View) error {
+	view.CreatedAt = time.Now()
+	m.views = append(m.views, *view)
+
+	return nil
+}
+
+func (m *memoryViews) GetLast(movieId uint, userId *uint, ip string, time time.Time) (View, error) {
+	for _, view := range m.views {
+		sameViewer := view.IP == ip || (userId != nil && view.UserID != nil && *view.UserID == *userId)
+		if view.MovieID == movieId && view.CreatedAt.After(time) && sameViewer {
+			return view, nil
+		}
+	}
+
+	return View{}, gorm.ErrRecordNotFound
+}
+
+func (m *memoryViews) GetCount(movieId uint) (int64, error) {
+	return 0, nil
+}
+
+func (m *memoryViews) GetCountByIp(ip string, since time.Time) (int64, error) {
+	var count int64
+	for _, view := range m.views {
+		if view.IP == ip && view.CreatedAt.After(since) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (m *memoryViews) GetCountMultiple(movieIds []uint) (map[uint]int64, error) {
+	return nil, nil
+}
+
+func (m *memoryViews) GetPending(movieId uint, clientKey string) (Pending, error) {
+	pending, ok := m.pending[clientKey]
+	if !ok || pending.MovieID != movieId {
+		return Pending{}, gorm.ErrRecordNotFound
+	}
+
+	return pending, nil
+}
+
+func (m *memoryViews) SavePending(pending *Pending) error {
+	m.pending[pending.ClientKey] = *pending
+
+	return nil
+}
+
+func (m *memoryViews) DeletePending(pending *Pending) (int64, error) {
+	stored, ok := m.pending[pending.ClientKey]
+	if !ok || !stored.StartedAt.Equal(pending.StartedAt) {
+		return 0, nil
+	}
+	delete(m.pending, pending.ClientKey)
+
+	return 1, nil
+}
+
+func (m *memoryViews) DeletePendingBefore(time time.Time) error {
+	return nil
+}
+
+// openedAgo moves the start of the pending view back in time
+func (m *memoryViews) openedAgo(t *testing.T, clientKey string, ago time.Duration) {
+	t.Helper()
+
+	pending, ok := m.pending[clientKey]
+	if !ok {
+		t.Fatalf("no pending view of %s", clientKey)
+	}
+	pending.StartedAt = time.Now().Add(-ago)
+	m.pending[clientKey] = pending
+}
+
+func TestIsBot(t *testing.T) {
+	for _, userAgent := range []string{
+		"",
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		"facebookexternalhit/1.1",
+		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0.0.0",
+		"curl/8.4.0",
+		"python-requests/2.31.0",
+		"Go-http-client/1.1",
+	} {
+		if !IsBot(userAgent) {
+			t.Errorf("IsBot(%q) = false", userAgent)
+		}
+	}
+
+	for _, userAgent := range []string{
+		"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+	} {
+		if IsBot(userAgent) {
+			t.Errorf("IsBot(%q) = true", userAgent)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	views := newMemoryViews()
+	uc := &UseCase{ViewInteractor: views}
+	client := Client{IP: net.ParseIP("203.0.113.5"), UserAgent: "Mozilla/5.0 Firefox/121.0", Source: SourceSearch}
+	clientKey := ClientKey(nil, client)
+
+	// Nothing is counted without opening the movie
+	if _, err := uc.Add(1, nil, client, 60, 600); err == nil {
+		t.Fatal("view of a movie that wasn't opened was added")
+	}
+
+	uc.Start(1, nil, client)
+	if _, err := uc.Add(1, nil, client, 60, 600); err == nil {
+		t.Fatal("view was added before the watch time passed since opening")
+	}
+
+	// The player can't report more watch time than has passed
+	views.openedAgo(t, clientKey, 10*time.Second)
+	if _, err := uc.Add(1, nil, client, 60, 600); err == nil {
+		t.Fatal("view was added after 10 seconds")
+	}
+
+	// Nor is enough time without the player reporting it
+	views.openedAgo(t, clientKey, time.Minute)
+	if _, err := uc.Add(1, nil, client, 10, 600); err == nil {
+		t.Fatal("view was added with 10 seconds watched")
+	}
+
+	view, err := uc.Add(1, nil, client, int(viewWatchTime/time.Second), 600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.IP != "203.0.113.5" || view.Source != SourceSearch {
+		t.Errorf("view = %+v", view)
+	}
+
+	// The pending view is taken, the next heartbeat adds nothing
+	if _, err = uc.Add(1, nil, client, 60, 600); err == nil {
+		t.Error("view was added twice")
+	}
+
+	// Reopening within the window adds no view either
+	uc.Start(1, nil, client)
+	views.openedAgo(t, clientKey, time.Minute)
+	if _, err = uc.Add(1, nil, client, 60, 600); err == nil {
+		t.Error("view was added twice a day")
+	}
+}
+
+func TestAddShortMovie(t *testing.T) {
+	views := newMemoryViews()
+	uc := &UseCase{ViewInteractor: views}
+	userId := uint(7)
+	client := Client{IP: net.ParseIP("203.0.113.5"), UserAgent: "Mozilla/5.0 Firefox/121.0"}
+
+	// A 20 second movie needs 10 seconds
+	uc.Start(1, &userId, client)
+	views.openedAgo(t, ClientKey(&userId, client), 11*time.Second)
+	if _, err := uc.Add(1, &userId, client, 9, 20); err == nil {
+		t.Fatal("view was added with 9 seconds watched")
+	}
+
+	view, err := uc.Add(1, &userId, client, 10, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.UserID == nil || *view.UserID != userId || view.Source != SourceDirect {
+		t.Errorf("view = %+v", view)
+	}
+}
+
+func TestAddBot(t *testing.T) {
+	views := newMemoryViews()
+	uc := &UseCase{ViewInteractor: views}
+	client := Client{IP: net.ParseIP("203.0.113.5"), UserAgent: "curl/8.4.0"}
+
+	uc.Start(1, nil, client)
+	if len(views.pending) != 0 {
+		t.Error("bot opened a pending view")
+	}
+
+	if _, err := uc.Add(1, nil, client, 60, 600); err == nil {
+		t.Error("bot view was added")
+	}
+}
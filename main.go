@@ -1,12 +1,27 @@
 package main
 
 import (
+	"flag"
 	"nine-dubz/app"
 	gormDb "nine-dubz/db"
 )
 
 func main() {
+	backfillAnimatedPreviews := flag.Bool("backfill-animated-previews", false, "create animated previews for already processed movies and exit")
+	backfillDurations := flag.Bool("backfill-durations", false, "probe durations of movies processed before they were saved and exit")
+	flag.Parse()
+
 	db := gormDb.NewGormDb()
 	app := app.NewApp(*db)
+
+	if *backfillAnimatedPreviews {
+		app.BackfillAnimatedPreviews()
+		return
+	}
+	if *backfillDurations {
+		app.BackfillDurations()
+		return
+	}
+
 	app.Start()
 }
@@ -1,10 +1,12 @@
 package ffmpegthumbs
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	ffmpeg "github.com/u2takey/ffmpeg-go"
 	"golang.org/x/net/context"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -20,6 +22,25 @@ type Format struct {
 	Bitrate string `json:"bit_rate"`
 }
 
+type LoudnessTarget struct {
+	Integrated float64
+	TruePeak   float64
+	Range      float64
+}
+
+type Loudness struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	TargetOffset string `json:"target_offset"`
+}
+
+type Segment struct {
+	Start float64
+	End   float64
+}
+
 type Stream struct {
 	CodecType  string `json:"codec_type"`
 	DurationTs int    `json:"duration_ts"`
@@ -147,24 +168,29 @@ func GetBitrate(filePath, codecType string) (string, error) {
 	return bitrate, nil
 }
 
-func Resize(ctx context.Context, height int, crf, speed, videoBitrate, audioBitrate, filePath, outputPath, fileName string) error {
+func Resize(ctx context.Context, height int, crf, speed, videoBitrate, audioBitrate, audioFilter, filePath, outputPath, fileName string) error {
 	err := os.MkdirAll(outputPath, os.ModePerm)
 	if err != nil {
 		return err
 	}
 
+	outputArgs := ffmpeg.KwArgs{
+		"map":   "0:a:0",
+		"c:v":   "libx264",
+		"crf":   crf,
+		"speed": speed,
+		"b:v":   videoBitrate,
+		"c:a":   "libopus",
+		"b:a":   audioBitrate,
+	}
+	if audioFilter != "" {
+		outputArgs["af"] = audioFilter
+	}
+
 	stream := ffmpeg.
 		Input(filePath).
 		Filter("scale", ffmpeg.Args{fmt.Sprintf("-2:%d", height)}).
-		Output(filepath.Join(outputPath, fileName+".mp4"), ffmpeg.KwArgs{
-			"map":   "0:a:0",
-			"c:v":   "libx264",
-			"crf":   crf,
-			"speed": speed,
-			"b:v":   videoBitrate,
-			"c:a":   "libopus",
-			"b:a":   audioBitrate,
-		}).
+		Output(filepath.Join(outputPath, fileName+".mp4"), outputArgs).
 		Silent(true).
 		OverWriteOutput()
 
@@ -177,21 +203,26 @@ func Resize(ctx context.Context, height int, crf, speed, videoBitrate, audioBitr
 	return nil
 }
 
-func ToWebm(ctx context.Context, filePath, crf, speed, bitrate, outputPath, fileName string) error {
+func ToWebm(ctx context.Context, filePath, crf, speed, bitrate, audioFilter, outputPath, fileName string) error {
 	err := os.MkdirAll(outputPath, os.ModePerm)
 	if err != nil {
 		return err
 	}
 
+	outputArgs := ffmpeg.KwArgs{
+		"c:v":   "libx264",
+		"crf":   crf,
+		"speed": speed,
+		"b:v":   bitrate,
+		"c:a":   "libopus",
+	}
+	if audioFilter != "" {
+		outputArgs["af"] = audioFilter
+	}
+
 	stream := ffmpeg.
 		Input(filePath).
-		Output(filepath.Join(outputPath, fileName+".mp4"), ffmpeg.KwArgs{
-			"c:v":   "libx264",
-			"crf":   crf,
-			"speed": speed,
-			"b:v":   bitrate,
-			"c:a":   "libopus",
-		}).
+		Output(filepath.Join(outputPath, fileName+".mp4"), outputArgs).
 		Silent(true).
 		OverWriteOutput()
 
@@ -204,6 +235,106 @@ func ToWebm(ctx context.Context, filePath, crf, speed, bitrate, outputPath, file
 	return nil
 }
 
+func MeasureLoudness(ctx context.Context, filePath string, target LoudnessTarget) (*Loudness, error) {
+	if _, err := GetAudioBitrate(filePath); err != nil {
+		return nil, fmt.Errorf("ffmpeg: no audio stream")
+	}
+
+	buff := &bytes.Buffer{}
+	stream := ffmpeg.
+		Input(filePath).
+		Output("-", ffmpeg.KwArgs{
+			"map": "0:a:0",
+			"af": fmt.Sprintf(
+				"loudnorm=I=%.1f:TP=%.1f:LRA=%.1f:print_format=json",
+				target.Integrated, target.TruePeak, target.Range,
+			),
+			"f": "null",
+		}).
+		WithErrorOutput(buff)
+
+	stream.Context = ctx
+	err := stream.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	output := buff.String()
+	jsonStart := strings.LastIndex(output, "{")
+	jsonEnd := strings.LastIndex(output, "}")
+	if jsonStart == -1 || jsonEnd < jsonStart {
+		return nil, fmt.Errorf("ffmpeg: loudnorm output not found")
+	}
+
+	loudness := &Loudness{}
+	err = json.Unmarshal([]byte(output[jsonStart:jsonEnd+1]), loudness)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range []string{loudness.InputI, loudness.InputTP, loudness.InputLRA, loudness.InputThresh} {
+		parsedValue, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(parsedValue, 0) {
+			return nil, fmt.Errorf("ffmpeg: invalid loudness value %q", value)
+		}
+	}
+
+	return loudness, nil
+}
+
+func GetLoudnormFilter(target LoudnessTarget, measured *Loudness) string {
+	if measured == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		"loudnorm=I=%.1f:TP=%.1f:LRA=%.1f:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true,aresample=48000",
+		target.Integrated, target.TruePeak, target.Range,
+		measured.InputI, measured.InputTP, measured.InputLRA, measured.InputThresh, measured.TargetOffset,
+	)
+}
+
+func Trim(ctx context.Context, filePath, outputPath, fileName string, segments []Segment) error {
+	err := os.MkdirAll(outputPath, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	var selectExpressions []string
+	for _, segment := range segments {
+		selectExpressions = append(selectExpressions, fmt.Sprintf("between(t,%.3f,%.3f)", segment.Start, segment.End))
+	}
+	selectExpression := strings.Join(selectExpressions, "+")
+
+	outputArgs := ffmpeg.KwArgs{
+		"vf":     fmt.Sprintf("select='%s',setpts=N/FRAME_RATE/TB", selectExpression),
+		"c:v":    "libx264",
+		"crf":    "18",
+		"preset": "fast",
+	}
+	if _, err = GetAudioBitrate(filePath); err == nil {
+		outputArgs["af"] = fmt.Sprintf("aselect='%s',asetpts=N/SR/TB", selectExpression)
+		outputArgs["c:a"] = "libopus"
+		outputArgs["b:a"] = "192k"
+	} else {
+		outputArgs["an"] = ""
+	}
+
+	stream := ffmpeg.
+		Input(filePath).
+		Output(filepath.Join(outputPath, fileName+".mp4"), outputArgs).
+		Silent(true).
+		OverWriteOutput()
+
+	stream.Context = ctx
+	err = stream.Run()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func ToWebp(filePath, outputPath, fileName string) error {
 	err := os.MkdirAll(outputPath, os.ModePerm)
 	if err != nil {
@@ -224,3 +355,44 @@ func ToWebp(filePath, outputPath, fileName string) error {
 
 	return nil
 }
+
+func CreateAnimatedPreview(ctx context.Context, filePath, outputPath, fileName string, clipsCount, clipDuration int) error {
+	err := os.MkdirAll(outputPath, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	duration, err := GetVideoDuration(filePath)
+	if err != nil {
+		return err
+	}
+
+	clipsInterval := clipDuration
+	if duration > clipsCount*clipDuration {
+		clipsInterval = duration / clipsCount
+	}
+
+	stream := ffmpeg.
+		Input(filePath).
+		Filter("select", ffmpeg.Args{fmt.Sprintf("lt(mod(t,%d),%d)", clipsInterval, clipDuration)}).
+		Filter("setpts", ffmpeg.Args{"N/FRAME_RATE/TB"}).
+		Filter("fps", ffmpeg.Args{"12"}).
+		Filter("scale", ffmpeg.Args{"320:-2"}).
+		Output(filepath.Join(outputPath, fileName+".webp"), ffmpeg.KwArgs{
+			"c:v":     "libwebp",
+			"an":      "",
+			"loop":    "0",
+			"quality": "60",
+			"t":       clipsCount * clipDuration,
+		}).
+		Silent(true).
+		OverWriteOutput()
+
+	stream.Context = ctx
+	err = stream.Run()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -12,6 +12,18 @@ func Set(node *html.Node, meta map[string]string) {
 			continue
 		}
 
+		if key == "robots" {
+			node.AppendChild(&html.Node{
+				Type: html.ElementNode,
+				Data: "meta",
+				Attr: []html.Attribute{
+					{Key: "name", Val: "robots"},
+					{Key: "content", Val: val},
+				},
+			})
+			continue
+		}
+
 		metaNode := &html.Node{
 			Type: html.ElementNode,
 			Data: "meta",
@@ -0,0 +1,132 @@
+package textsearch
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var stopWords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "are": {}, "as": {}, "at": {}, "be": {}, "by": {}, "for": {}, "from": {},
+	"in": {}, "is": {}, "it": {}, "of": {}, "on": {}, "or": {}, "the": {}, "to": {}, "with": {},
+	"а": {}, "без": {}, "в": {}, "во": {}, "для": {}, "до": {}, "и": {}, "из": {}, "или": {}, "к": {},
+	"как": {}, "на": {}, "не": {}, "но": {}, "о": {}, "об": {}, "от": {}, "по": {}, "при": {}, "с": {},
+	"со": {}, "то": {}, "у": {}, "что": {},
+}
+
+var russianSuffixes = sortByLength([]string{
+	"иями", "ями", "ами", "иях", "ием", "ией", "ого", "его", "ому", "ему", "ими", "ыми",
+	"ость", "ости", "ешь", "ете", "ите", "ишь", "ает", "яет", "ует", "ать", "ять", "ить", "еть", "уть",
+	"ется", "ится", "ах", "ях", "ов", "ев", "ей", "ий", "ый", "ой", "ая", "яя", "ое", "ее", "ые", "ие",
+	"ам", "ям", "ом", "ем", "ую", "юю", "ию", "ии", "ия", "ью",
+	"а", "я", "о", "е", "ы", "и", "у", "ю", "ь", "й",
+})
+
+var englishSuffixes = sortByLength([]string{
+	"ational", "ations", "ation", "ments", "ment", "ness", "ings", "ing", "edly", "ies", "ied",
+	"ed", "es", "ly", "er", "s",
+})
+
+// Normalize lowercases text and treats "ё" as "е" so both spellings match.
+func Normalize(text string) string {
+	return strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+}
+
+// Words splits normalized text into words, dropping stop words.
+func Words(text string) []string {
+	text = Normalize(text)
+
+	var words []string
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if _, ok := stopWords[word]; ok {
+			continue
+		}
+		words = append(words, word)
+	}
+
+	return words
+}
+
+// Tokenize splits text into stemmed search terms.
+func Tokenize(text string) []string {
+	var terms []string
+	for _, word := range Words(text) {
+		terms = append(terms, Stem(word))
+	}
+
+	return terms
+}
+
+// Stem strips the most common inflection endings of a Russian or English
+// word, the language is picked by the script of the word.
+// Stems are kept at least 3 letters long.
+func Stem(word string) string {
+	suffixes := englishSuffixes
+	for _, r := range word {
+		if unicode.Is(unicode.Cyrillic, r) {
+			suffixes = russianSuffixes
+			break
+		}
+	}
+
+	for _, suffix := range suffixes {
+		if !strings.HasSuffix(word, suffix) {
+			continue
+		}
+
+		stem := strings.TrimSuffix(word, suffix)
+		if utf8.RuneCountInString(stem) >= 3 {
+			return stem
+		}
+	}
+
+	return word
+}
+
+// Distance returns the Levenshtein distance between two words.
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+// MaxDistance returns how many typos are tolerated in the term.
+func MaxDistance(term string) int {
+	length := utf8.RuneCountInString(term)
+	switch {
+	case length < 4:
+		return 0
+	case length < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func sortByLength(suffixes []string) []string {
+	sort.SliceStable(suffixes, func(i, j int) bool {
+		return utf8.RuneCountInString(suffixes[i]) > utf8.RuneCountInString(suffixes[j])
+	})
+
+	return suffixes
+}
@@ -0,0 +1,91 @@
+package textsearch
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for text, want := range map[string]string{
+		"Ёлка":        "елка",
+		"ЁЖИК":        "ежик",
+		"Hello World": "hello world",
+	} {
+		if got := Normalize(text); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	words := Words("The Cat and the Ёжик, в тумане! 2024")
+	if want := []string{"cat", "ежик", "тумане", "2024"}; !slices.Equal(words, want) {
+		t.Errorf("words = %q, want %q", words, want)
+	}
+
+	if words = Words("the, and - в и"); len(words) != 0 {
+		t.Errorf("stop words = %q, want none", words)
+	}
+}
+
+func TestStem(t *testing.T) {
+	for word, want := range map[string]string{
+		// Russian
+		"фильм":    "фильм",
+		"фильмы":   "фильм",
+		"фильмов":  "фильм",
+		"фильмами": "фильм",
+		"кошка":    "кошк",
+		"кошками":  "кошк",
+		// English
+		"cats":    "cat",
+		"walked":  "walk",
+		"walking": "walk",
+		"dragons": "dragon",
+		// Stems are kept at least 3 letters long
+		"она": "она",
+		"bed": "bed",
+	} {
+		if got := Stem(word); got != want {
+			t.Errorf("Stem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	// Both spellings and all forms end up with the same terms
+	if a, b := Tokenize("Ёлки и фильмы"), Tokenize("елка фильмами"); !slices.Equal(a, b) {
+		t.Errorf("terms %q and %q differ", a, b)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want int
+	}{
+		{"dragon", "dragon", 0},
+		{"dragon", "dragn", 1},
+		{"dragon", "drgaon", 2},
+		{"фильм", "филм", 1},
+		{"", "cat", 3},
+	} {
+		if got := Distance(test.a, test.b); got != test.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestMaxDistance(t *testing.T) {
+	for term, want := range map[string]int{
+		"cat":      0,
+		"кот":      0,
+		"dragon":   1,
+		"фильм":    1,
+		"universe": 2,
+	} {
+		if got := MaxDistance(term); got != want {
+			t.Errorf("MaxDistance(%q) = %d, want %d", term, got, want)
+		}
+	}
+}
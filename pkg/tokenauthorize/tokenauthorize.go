@@ -1,6 +1,9 @@
 package tokenauthorize
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"net/http"
@@ -89,3 +92,16 @@ func (ta *TokenAuthorize) GetEmptyTokenCookie() *http.Cookie {
 
 	return &tokenCookie
 }
+
+// Sign returns a signature of the value made with the secret key, for
+// links that have to work without the token cookie
+func (ta *TokenAuthorize) Sign(value string) string {
+	mac := hmac.New(sha256.New, []byte(ta.SecretKey))
+	mac.Write([]byte(ta.Issuer + ":" + value))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (ta *TokenAuthorize) VerifySignature(value, signature string) bool {
+	return hmac.Equal([]byte(ta.Sign(value)), []byte(signature))
+}
@@ -7,21 +7,105 @@ import (
 	"strings"
 )
 
-func GetIP(r *http.Request) (net.IP, error) {
-	realIp := r.Header.Get("X-Real-Ip")
-	userIP := net.ParseIP(realIp)
-	if userIP != nil {
-		return userIP, nil
-	}
-
-	forwardedFor := r.Header.Get("X-Forwarded-For")
-	ips := strings.Split(forwardedFor, ", ")
-	for _, ip := range ips {
-		userIP = net.ParseIP(ip)
-		if userIP != nil {
-			return userIP, nil
+// DefaultTrustedProxies are loopback and private networks, where a reverse
+// proxy in front of the app usually is
+var DefaultTrustedProxies = []string{
+	"127.0.0.0/8",
+	"::1/128",
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"fc00::/7",
+}
+
+// Resolver finds the client IP of a request. Forwarding headers are only
+// believed when the request comes from a trusted proxy, otherwise anyone
+// could set them.
+type Resolver struct {
+	trustedProxies []*net.IPNet
+}
+
+// New accepts trusted proxies as CIDRs or single IPs
+func New(trustedProxies []string) (*Resolver, error) {
+	resolver := &Resolver{}
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, errors.New("userip: invalid trusted proxy " + proxy)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			resolver.trustedProxies = append(resolver.trustedProxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, errors.New("userip: invalid trusted proxy " + proxy)
+		}
+		resolver.trustedProxies = append(resolver.trustedProxies, network)
+	}
+
+	return resolver, nil
+}
+
+// GetIP returns the address the request came from. Behind trusted proxies
+// it's the last address in X-Forwarded-For that is not a trusted proxy
+// itself, or X-Real-Ip when there is no X-Forwarded-For.
+func (r *Resolver) GetIP(req *http.Request) (net.IP, error) {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	remoteIP := net.ParseIP(host)
+	if remoteIP == nil {
+		return nil, errors.New("userip: no ip found")
+	}
+
+	if !r.isTrusted(remoteIP) {
+		return remoteIP, nil
+	}
+
+	var forwardedFor []string
+	for _, header := range req.Header.Values("X-Forwarded-For") {
+		forwardedFor = append(forwardedFor, strings.Split(header, ",")...)
+	}
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwardedFor[i]))
+		if ip == nil {
+			break
+		}
+		if !r.isTrusted(ip) {
+			return ip, nil
+		}
+		remoteIP = ip
+	}
+
+	if len(forwardedFor) == 0 {
+		if realIP := net.ParseIP(strings.TrimSpace(req.Header.Get("X-Real-Ip"))); realIP != nil {
+			return realIP, nil
+		}
+	}
+
+	return remoteIP, nil
+}
+
+func (r *Resolver) isTrusted(ip net.IP) bool {
+	for _, network := range r.trustedProxies {
+		if network.Contains(ip) {
+			return true
 		}
 	}
 
-	return nil, errors.New("userip: no ip found")
+	return false
 }
@@ -0,0 +1,83 @@
+package userip
+
+import (
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	resolver, err := New([]string{"10.0.0.0/8", " 192.168.1.1 ", "::1", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for ip, want := range map[string]bool{
+		"10.1.2.3":    true,
+		"192.168.1.1": true,
+		"192.168.1.2": false,
+		"::1":         true,
+		"8.8.8.8":     false,
+	} {
+		if got := resolver.isTrusted(net.ParseIP(ip)); got != want {
+			t.Errorf("isTrusted(%s) = %t, want %t", ip, got, want)
+		}
+	}
+
+	for _, proxy := range []string{"10.0.0.0/33", "localhost", "1.2.3"} {
+		if _, err = New([]string{proxy}); err == nil {
+			t.Errorf("New(%q) error = nil", proxy)
+		}
+	}
+}
+
+func TestGetIP(t *testing.T) {
+	resolver, err := New(DefaultTrustedProxies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		want         string
+	}{
+		{"direct", "203.0.113.5:1234", nil, "", "203.0.113.5"},
+		{"untrusted remote", "203.0.113.5:1234", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.5"},
+		{"trusted proxy", "10.0.0.1:1234", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"trusted hops", "10.0.0.1:1234", []string{"198.51.100.1, 192.168.0.2", "10.0.0.3"}, "", "198.51.100.1"},
+		// The client can put anything at the start of the chain, only the
+		// address the proxy appended counts
+		{"spoofed chain", "10.0.0.1:1234", []string{"127.0.0.1, 1.1.1.1, 198.51.100.1"}, "", "198.51.100.1"},
+		{"spoofed after garbage", "10.0.0.1:1234", []string{"1.1.1.1, garbage, 10.0.0.2"}, "", "10.0.0.2"},
+		{"all trusted", "10.0.0.1:1234", []string{"192.168.0.2"}, "", "192.168.0.2"},
+		{"real ip", "10.0.0.1:1234", nil, "198.51.100.1", "198.51.100.1"},
+		{"forwarded for over real ip", "10.0.0.1:1234", []string{"198.51.100.1"}, "198.51.100.2", "198.51.100.1"},
+		{"invalid real ip", "10.0.0.1:1234", nil, "garbage", "10.0.0.1"},
+		{"ipv6", "[::1]:1234", []string{"2001:db8::1"}, "", "2001:db8::1"},
+		{"no port", "203.0.113.5", nil, "", "203.0.113.5"},
+	} {
+		req := &http.Request{RemoteAddr: test.remoteAddr, Header: http.Header{}}
+		for _, header := range test.forwardedFor {
+			req.Header.Add("X-Forwarded-For", header)
+		}
+		if test.realIP != "" {
+			req.Header.Set("X-Real-Ip", test.realIP)
+		}
+
+		ip, err := resolver.GetIP(req)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !ip.Equal(net.ParseIP(test.want)) {
+			t.Errorf("%s: ip = %s, want %s", test.name, ip, test.want)
+		}
+	}
+
+	if _, err = resolver.GetIP(&http.Request{RemoteAddr: "garbage"}); err == nil {
+		t.Error("GetIP of an invalid remote address error = nil")
+	}
+}
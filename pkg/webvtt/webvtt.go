@@ -1,8 +1,10 @@
 package webvtt
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -74,3 +76,27 @@ func CreateFromFilePaths(filePaths []string, outputPath string, videoDuration, f
 
 	return file, nil
 }
+
+type Chapter struct {
+	Title string
+	Start int
+	End   int
+}
+
+func CreateChapters(chapters []Chapter) []byte {
+	buff := bytes.NewBufferString("WEBVTT\n\n")
+
+	timeFormat := "15:04:05.000"
+	for i, chapter := range chapters {
+		chapterStart := time.Time{}.Add(time.Duration(chapter.Start) * time.Second)
+		chapterEnd := time.Time{}.Add(time.Duration(chapter.End) * time.Second)
+
+		buff.WriteString(strconv.Itoa(i+1) + "\n")
+		buff.WriteString(chapterStart.Format(timeFormat) + " --> " + chapterEnd.Format(timeFormat) + "\n")
+		// A line break would end the cue and start a new one
+		title := strings.Join(strings.Fields(chapter.Title), " ")
+		buff.WriteString(strings.ReplaceAll(title, "-->", "->") + "\n\n")
+	}
+
+	return buff.Bytes()
+}